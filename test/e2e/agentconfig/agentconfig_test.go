@@ -0,0 +1,244 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agentconfig
+
+import (
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const systemProbeYAML = `# system_probe_config:
+  # enabled: false
+runtime_security_config:
+  enabled: true
+privileged_logs:
+  enabled: false
+# discovery:
+  # enabled: false
+`
+
+func TestParse(t *testing.T) {
+	var config SystemProbe
+	require.NoError(t, Parse([]byte(systemProbeYAML), &config, "runtime_security_config.enabled", "privileged_logs.enabled"))
+	assert.True(t, config.RuntimeSecurityConfig.Enabled)
+	assert.False(t, config.PrivilegedLogs.Enabled)
+	assert.True(t, config.Has("privileged_logs"))
+	assert.True(t, config.Has("privileged_logs.enabled"), "false is set")
+	assert.False(t, config.Has("discovery"), "commented sections are absent")
+	assert.False(t, config.Has("privileged_logs.enabled.value"))
+}
+
+func TestDocumentValue(t *testing.T) {
+	var document Document
+	require.NoError(t, Parse([]byte("agent_ipc:\n  port: 5009\ntags: ['a', 'b']\nsite:\n"), &document, "agent_ipc.port"))
+	value, ok := document.Value("agent_ipc.port")
+	assert.True(t, ok)
+	assert.Equal(t, 5009, value)
+	value, _ = document.Value("tags")
+	assert.Equal(t, []any{"a", "b"}, value)
+	_, ok = document.Value("site")
+	assert.True(t, ok, "null values are present")
+	assert.False(t, document.Has("site"))
+	_, ok = document.Value("agent_ipc.port.number")
+	assert.False(t, ok)
+}
+
+func TestParseMissing(t *testing.T) {
+	var config SystemProbe
+	err := Parse([]byte(systemProbeYAML+"service_monitoring_config:\n"), &config, "discovery.enabled", "runtime_security_config.enabled", "service_monitoring_config.enabled")
+	assert.EqualError(t, err, "missing fields: discovery.enabled, service_monitoring_config.enabled")
+}
+
+func TestParseMistyped(t *testing.T) {
+	var config Datadog
+	err := Parse([]byte("fips:\n  enabled: true\n  port_range_start: first\nagent_ipc:\n  port: 5009\n"), &config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mistyped fields")
+	assert.Contains(t, err.Error(), "line 3")
+
+	err = Parse([]byte("tags: ['a', 'b'\n"), &config)
+	assert.ErrorContains(t, err, "invalid YAML")
+}
+
+func TestParseEnvironment(t *testing.T) {
+	env, err := ParseEnvironment([]byte("PATH=/usr/bin\nDD_APM_ERROR_TRACKING_STANDALONE_ENABLED=true\nDD_CORE_AGENT_ENABLED=false\n"), "DD_CORE_AGENT_ENABLED")
+	require.NoError(t, err)
+	assert.True(t, env.APMErrorTrackingStandaloneEnabled)
+	assert.False(t, env.CoreAgentEnabled)
+	assert.True(t, env.Has("DD_CORE_AGENT_ENABLED"))
+	assert.False(t, env.Has("DD_SBOM_ENABLED"))
+	assert.Equal(t, "/usr/bin", env.Values["PATH"])
+
+	_, err = ParseEnvironment([]byte("DD_SBOM_ENABLED=yes\n"))
+	assert.EqualError(t, err, `mistyped fields: DD_SBOM_ENABLED="yes" is not a boolean`)
+	_, err = ParseEnvironment(nil, "DD_SBOM_ENABLED", "DD_SBOM_HOST_ENABLED")
+	assert.EqualError(t, err, "missing fields: DD_SBOM_ENABLED, DD_SBOM_HOST_ENABLED")
+}
+
+func TestParseFIPSProxy(t *testing.T) {
+	config, err := ParseFIPSProxy([]byte(`# datadog-fips-proxy
+global
+    presetenv DD_FIPS_LOCAL_ADDRESS 127.0.0.1
+    ssl-default-server-ciphers "ECDHE-RSA-AES128-GCM-SHA256 ECDHE-RSA-AES256-GCM-SHA384" # FIPS ciphers
+
+defaults
+    mode http
+
+frontend metrics-forwarder
+    bind "${DD_FIPS_LOCAL_ADDRESS}:9804"
+    default_backend datadog-metrics
+
+backend datadog-metrics
+    server mothership haproxy-app.agent.datadoghq.com:443 check ssl verify required
+`))
+	require.NoError(t, err)
+	global, ok := config.Section("global", "")
+	require.True(t, ok)
+	assert.Equal(t, [][]string{{"DD_FIPS_LOCAL_ADDRESS", "127.0.0.1"}}, global.Values("presetenv"))
+	assert.Equal(t, [][]string{{"ECDHE-RSA-AES128-GCM-SHA256 ECDHE-RSA-AES256-GCM-SHA384"}}, global.Values("ssl-default-server-ciphers"))
+	assert.Len(t, config.SectionsOf("backend"), 1)
+	ports, err := config.BindPorts()
+	require.NoError(t, err)
+	assert.Equal(t, []int{9804}, ports)
+
+	_, err = ParseFIPSProxy([]byte("    maxconn 2048\nglobal\n"))
+	assert.EqualError(t, err, "line 1: maxconn is outside of any section")
+	_, err = ParseFIPSProxy([]byte("global\n    log \"stdout\n"))
+	assert.EqualError(t, err, "line 2: unterminated \" quote")
+}
+
+// TestScriptConfigurations parses what the update_* and manage_* functions of the script write
+func TestScriptConfigurations(t *testing.T) {
+	h := hermetic.New(t, hermetic.WithOS(hermetic.Ubuntu("22.04")))
+	result := h.Run(map[string]string{
+		"DD_API_KEY":                         "0123456789abcdef0123456789abcdef",
+		"DD_APP_KEY":                         "fedcba9876543210fedcba9876543210fedcba98",
+		"DD_SITE":                            "datadoghq.eu",
+		"DD_HOSTNAME":                        "totoro",
+		"DD_HOST_TAGS":                       "foo:bar,baz:toto",
+		"DD_ENV":                             "kiki",
+		"DD_INFRASTRUCTURE_MODE":             "basic",
+		"DD_REMOTE_UPDATES":                  "true",
+		"DD_LOGS_CONFIG_PROCESS_COLLECT_ALL": "true",
+		"DD_OTELCOLLECTOR_ENABLED":           "true",
+		"DD_PRIVATE_ACTION_RUNNER_ENABLED":   "true",
+		"DD_PRIVATE_ACTION_RUNNER_ACTIONS_ALLOWLIST":       "com.datadoghq.script.runPredefinedScript,com.datadoghq.http.request",
+		"DD_PRIVATE_ACTION_RUNNER_API_KEY_ONLY_ENROLLMENT": "true",
+		"DD_RUNTIME_SECURITY_CONFIG_ENABLED":               "true",
+		"DD_COMPLIANCE_CONFIG_ENABLED":                     "true",
+		"DD_SYSTEM_PROBE_SERVICE_MONITORING_ENABLED":       "true",
+		"DD_PRIVILEGED_LOGS_ENABLED":                       "false",
+		"DD_SBOM_CONTAINER_IMAGE_ENABLED":                  "true",
+		"DD_SBOM_HOST_ENABLED":                             "false",
+		"DD_APM_ERROR_TRACKING_STANDALONE":                 "false",
+	})
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	var datadog Datadog
+	require.NoError(t, Parse([]byte(h.ReadFile("/etc/datadog-agent/datadog.yaml")), &datadog,
+		"api_key", "app_key", "site", "hostname", "tags", "env", "infrastructure_mode", "remote_updates",
+		"logs_enabled", "logs_config.process_exclude_agent", "logs_config.auto_multi_line_detection",
+		"process_config.process_collection.use_wlm", "extra_config_providers", "otelcollector.enabled",
+		"agent_ipc.port", "agent_ipc.config_refresh_interval", "private_action_runner.enabled",
+		"private_action_runner.actions_allowlist", "private_action_runner.api_key_only_enrollment"))
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", datadog.APIKey)
+	assert.Equal(t, "fedcba9876543210fedcba9876543210fedcba98", datadog.AppKey)
+	assert.Equal(t, "datadoghq.eu", datadog.Site)
+	assert.Equal(t, "totoro", datadog.Hostname)
+	assert.Equal(t, []string{"foo:bar", "baz:toto"}, datadog.Tags)
+	assert.Equal(t, "kiki", datadog.Env)
+	assert.Equal(t, "basic", datadog.InfrastructureMode)
+	assert.True(t, datadog.RemoteUpdates)
+	assert.True(t, datadog.LogsEnabled)
+	assert.Equal(t, LogsConfig{ProcessExcludeAgent: true, AutoMultiLineDetection: true}, datadog.LogsConfig)
+	assert.True(t, datadog.ProcessConfig.ProcessCollection.UseWLM)
+	assert.Equal(t, []string{"process_log"}, datadog.ExtraConfigProviders)
+	assert.True(t, datadog.OTelCollector.Enabled)
+	assert.Equal(t, AgentIPC{Port: 5009, ConfigRefreshInterval: 60}, datadog.AgentIPC)
+	assert.Equal(t, PrivateActionRunner{
+		Enabled:              true,
+		ActionsAllowlist:     []string{"com.datadoghq.script.runPredefinedScript", "com.datadoghq.http.request"},
+		APIKeyOnlyEnrollment: true,
+	}, datadog.PrivateActionRunner)
+	assert.False(t, datadog.Has("dd_url"))
+	assert.False(t, datadog.Has("fips"))
+
+	var systemProbe SystemProbe
+	require.NoError(t, Parse([]byte(h.ReadFile("/etc/datadog-agent/system-probe.yaml")), &systemProbe,
+		"runtime_security_config.enabled", "discovery.enabled", "privileged_logs.enabled", "service_monitoring_config.enabled"))
+	assert.True(t, systemProbe.RuntimeSecurityConfig.Enabled)
+	assert.True(t, systemProbe.Discovery.Enabled)
+	assert.False(t, systemProbe.PrivilegedLogs.Enabled)
+	assert.True(t, systemProbe.ServiceMonitoringConfig.Enabled)
+
+	var securityAgent SecurityAgent
+	require.NoError(t, Parse([]byte(h.ReadFile("/etc/datadog-agent/security-agent.yaml")), &securityAgent,
+		"runtime_security_config.enabled", "compliance_config.enabled"))
+	assert.True(t, securityAgent.RuntimeSecurityConfig.Enabled)
+	assert.True(t, securityAgent.ComplianceConfig.Enabled)
+
+	var otel OTel
+	require.NoError(t, Parse([]byte(h.ReadFile("/etc/datadog-agent/otel-config.yaml")), &otel, "exporters.datadog.api.key", "exporters.datadog.api.site"))
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", otel.Exporters.Datadog.API.Key)
+	assert.Equal(t, "datadoghq.eu", otel.Exporters.Datadog.API.Site)
+
+	sbom, err := ParseEnvironment([]byte(h.ReadFile("/etc/datadog-agent/environment")), "DD_SBOM_ENABLED", "DD_SBOM_CONTAINER_IMAGE_ENABLED", "DD_SBOM_HOST_ENABLED")
+	require.NoError(t, err)
+	assert.True(t, sbom.SBOMEnabled)
+	assert.True(t, sbom.SBOMContainerImageEnabled)
+	assert.False(t, sbom.SBOMHostEnabled)
+	env, err := ParseEnvironment([]byte(h.ReadFile("/etc/environment")), "DD_APM_ERROR_TRACKING_STANDALONE_ENABLED", "DD_CORE_AGENT_ENABLED")
+	require.NoError(t, err)
+	assert.False(t, env.APMErrorTrackingStandaloneEnabled)
+	assert.True(t, env.CoreAgentEnabled)
+}
+
+// TestScriptFIPSConfiguration parses datadog.yaml with the fips section update_fips prepends
+func TestScriptFIPSConfiguration(t *testing.T) {
+	h := hermetic.New(t, hermetic.WithOS(hermetic.RedHat("9.4")))
+	result := h.Run(map[string]string{
+		"DD_API_KEY":   "0123456789abcdef0123456789abcdef",
+		"DD_FIPS_MODE": "true",
+	})
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	var datadog Datadog
+	require.NoError(t, Parse([]byte(h.ReadFile("/etc/datadog-agent/datadog.yaml")), &datadog, "fips.enabled", "fips.port_range_start", "fips.https"))
+	assert.Equal(t, FIPS{Enabled: true, PortRangeStart: 9803, HTTPS: false}, datadog.FIPS)
+	assert.False(t, datadog.Has("site"))
+	assert.False(t, datadog.Has("dd_url"))
+
+	proxy, err := ParseFIPSProxy([]byte(h.ReadFile("/etc/datadog-fips-proxy/datadog-fips-proxy.cfg")))
+	require.NoError(t, err)
+	_, ok := proxy.Section("global", "")
+	assert.True(t, ok)
+}
+
+// TestScriptErrorTrackingStandalone parses the sections update_error_tracking_standalone prepends
+func TestScriptErrorTrackingStandalone(t *testing.T) {
+	h := hermetic.New(t, hermetic.WithOS(hermetic.Ubuntu("22.04")))
+	result := h.Run(map[string]string{
+		"DD_API_KEY":                       "0123456789abcdef0123456789abcdef",
+		"DD_APM_ERROR_TRACKING_STANDALONE": "true",
+	})
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	var datadog Datadog
+	require.NoError(t, Parse([]byte(h.ReadFile("/etc/datadog-agent/datadog.yaml")), &datadog,
+		"enable_payloads.series", "enable_payloads.events", "enable_payloads.service_checks", "enable_payloads.sketches",
+		"apm_config.enabled", "apm_config.error_tracking_standalone.enabled"))
+	assert.Equal(t, EnablePayloads{}, datadog.EnablePayloads)
+	assert.True(t, datadog.APMConfig.Enabled)
+	assert.True(t, datadog.APMConfig.ErrorTrackingStandalone.Enabled)
+
+	env, err := ParseEnvironment([]byte(h.ReadFile("/etc/environment")), "DD_APM_ERROR_TRACKING_STANDALONE_ENABLED", "DD_CORE_AGENT_ENABLED")
+	require.NoError(t, err)
+	assert.True(t, env.APMErrorTrackingStandaloneEnabled)
+	assert.False(t, env.CoreAgentEnabled)
+}
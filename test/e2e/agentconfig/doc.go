@@ -0,0 +1,10 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package agentconfig models the configuration files the install script writes: datadog.yaml (and dogstatsd.yaml),
+// system-probe.yaml, security-agent.yaml, otel-config.yaml, the FIPS proxy configuration and the system environment
+// file. Parsing reports missing and mistyped fields as errors, so that suites assert on fields rather than on
+// map[any]any chains that panic when a section is absent.
+package agentconfig
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agentconfig
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	envparse "github.com/hashicorp/go-envparse"
+)
+
+// Environment is an environment file, /etc/datadog-agent/environment where
+// manage_infrastructure_vulnerabilities_config sets the SBOM variables, or /etc/environment where
+// manage_error_tracking_standalone_config sets the Error Tracking ones
+type Environment struct {
+	SBOMEnabled                       bool
+	SBOMContainerImageEnabled         bool
+	SBOMHostEnabled                   bool
+	APMErrorTrackingStandaloneEnabled bool
+	CoreAgentEnabled                  bool
+
+	// Values holds every variable of the file, including the ones the script doesn't manage
+	Values map[string]string
+}
+
+func (e *Environment) fields() map[string]*bool {
+	return map[string]*bool{
+		"DD_SBOM_ENABLED":                          &e.SBOMEnabled,
+		"DD_SBOM_CONTAINER_IMAGE_ENABLED":          &e.SBOMContainerImageEnabled,
+		"DD_SBOM_HOST_ENABLED":                     &e.SBOMHostEnabled,
+		"DD_APM_ERROR_TRACKING_STANDALONE_ENABLED": &e.APMErrorTrackingStandaloneEnabled,
+		"DD_CORE_AGENT_ENABLED":                    &e.CoreAgentEnabled,
+	}
+}
+
+// Has reports whether the variable is set
+func (e Environment) Has(name string) bool {
+	_, ok := e.Values[name]
+	return ok
+}
+
+// ParseEnvironment parses an environment file. It fails when a variable of the model isn't a boolean, and lists the
+// required variables that are not set.
+func ParseEnvironment(content []byte, required ...string) (Environment, error) {
+	values, err := envparse.Parse(bytes.NewReader(content))
+	if err != nil {
+		return Environment{}, fmt.Errorf("invalid environment file: %w", err)
+	}
+	env := Environment{Values: values}
+
+	var mistyped []string
+	for name, field := range env.fields() {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		if *field, err = strconv.ParseBool(value); err != nil {
+			mistyped = append(mistyped, fmt.Sprintf("%s=%q is not a boolean", name, value))
+		}
+	}
+	if len(mistyped) > 0 {
+		sort.Strings(mistyped)
+		return env, fmt.Errorf("mistyped fields: %s", strings.Join(mistyped, ", "))
+	}
+
+	var missing []string
+	for _, name := range required {
+		if !env.Has(name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return env, fmt.Errorf("missing fields: %s", strings.Join(missing, ", "))
+	}
+	return env, nil
+}
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agentconfig
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// proxySectionKinds are the HAProxy keywords starting a section
+var proxySectionKinds = map[string]bool{
+	"global":      true,
+	"defaults":    true,
+	"frontend":    true,
+	"backend":     true,
+	"listen":      true,
+	"resolvers":   true,
+	"peers":       true,
+	"userlist":    true,
+	"mailers":     true,
+	"program":     true,
+	"http-errors": true,
+	"ring":        true,
+	"cache":       true,
+}
+
+// FIPSProxy is the HAProxy configuration shipped by datadog-fips-proxy
+type FIPSProxy struct {
+	Sections []ProxySection
+}
+
+// ProxySection is a section of an HAProxy configuration, e.g. `frontend metrics-forwarder`
+type ProxySection struct {
+	Kind string
+	Name string
+	// Directives are the lines of the section, split into a keyword and its arguments
+	Directives [][]string
+}
+
+// Section returns the first section of the kind and name, name is empty for global and unnamed defaults
+func (c FIPSProxy) Section(kind, name string) (ProxySection, bool) {
+	for _, section := range c.Sections {
+		if section.Kind == kind && section.Name == name {
+			return section, true
+		}
+	}
+	return ProxySection{}, false
+}
+
+// SectionsOf returns the sections of a kind, in order
+func (c FIPSProxy) SectionsOf(kind string) []ProxySection {
+	var sections []ProxySection
+	for _, section := range c.Sections {
+		if section.Kind == kind {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// BindPorts returns the ports the frontends and listen sections bind to
+func (c FIPSProxy) BindPorts() ([]int, error) {
+	var ports []int
+	for _, section := range c.Sections {
+		if section.Kind != "frontend" && section.Kind != "listen" {
+			continue
+		}
+		for _, bind := range section.Values("bind") {
+			if len(bind) == 0 {
+				return nil, fmt.Errorf("%s %s: bind without address", section.Kind, section.Name)
+			}
+			port, err := strconv.Atoi(bind[0][strings.LastIndex(bind[0], ":")+1:])
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: no port in bind %s", section.Kind, section.Name, bind[0])
+			}
+			ports = append(ports, port)
+		}
+	}
+	return ports, nil
+}
+
+// Values returns the arguments of every directive of the keyword
+func (s ProxySection) Values(keyword string) [][]string {
+	var values [][]string
+	for _, directive := range s.Directives {
+		if directive[0] == keyword {
+			values = append(values, directive[1:])
+		}
+	}
+	return values
+}
+
+// ParseFIPSProxy parses an HAProxy configuration. It fails on a directive outside of any section and on an
+// unterminated quote.
+func ParseFIPSProxy(content []byte) (FIPSProxy, error) {
+	var config FIPSProxy
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		fields, err := splitProxyLine(scanner.Text())
+		if err != nil {
+			return config, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if proxySectionKinds[fields[0]] {
+			section := ProxySection{Kind: fields[0]}
+			if len(fields) > 1 {
+				section.Name = fields[1]
+			}
+			config.Sections = append(config.Sections, section)
+			continue
+		}
+		if len(config.Sections) == 0 {
+			return config, fmt.Errorf("line %d: %s is outside of any section", line, fields[0])
+		}
+		current := &config.Sections[len(config.Sections)-1]
+		current.Directives = append(current.Directives, fields)
+	}
+	return config, scanner.Err()
+}
+
+// splitProxyLine splits a line into words, dropping the comment and the quotes
+func splitProxyLine(line string) ([]string, error) {
+	var fields []string
+	var word strings.Builder
+	inWord, quote, escaped := false, rune(0), false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			inWord, escaped = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			inWord, quote = true, r
+		case r == '#':
+			if inWord {
+				fields = append(fields, word.String())
+			}
+			return fields, nil
+		case r == ' ' || r == '\t':
+			if inWord {
+				fields = append(fields, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			inWord = true
+			word.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		fields = append(fields, word.String())
+	}
+	return fields, nil
+}
@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agentconfig
+
+import (
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Document keeps the raw content of a YAML model, to tell a key set to its zero value from an absent one
+type Document struct {
+	raw map[any]any
+}
+
+func (d *Document) setRaw(raw map[any]any) {
+	d.raw = raw
+}
+
+// Has reports whether the dotted path, e.g. "privileged_logs.enabled", is set to a non null value
+func (d Document) Has(path string) bool {
+	value, ok := d.Value(path)
+	return ok && value != nil
+}
+
+// Value returns the raw value at the dotted path, as yaml.v2 decodes it: sections are map[any]any and lists []any
+func (d Document) Value(path string) (any, bool) {
+	var node any = d.raw
+	for _, key := range strings.Split(path, ".") {
+		section, ok := node.(map[any]any)
+		if !ok {
+			return nil, false
+		}
+		if node, ok = section[key]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// Datadog is datadog.yaml, and dogstatsd.yaml for the dogstatsd flavor, with the keys written by the update_*
+// functions of the script
+type Datadog struct {
+	Document `yaml:"-"`
+
+	APIKey             string   `yaml:"api_key"`
+	AppKey             string   `yaml:"app_key"`
+	Site               string   `yaml:"site"`
+	DDURL              string   `yaml:"dd_url"`
+	Hostname           string   `yaml:"hostname"`
+	Tags               []string `yaml:"tags"`
+	Env                string   `yaml:"env"`
+	InfrastructureMode string   `yaml:"infrastructure_mode"`
+	RemoteUpdates      bool     `yaml:"remote_updates"`
+
+	FIPS           FIPS           `yaml:"fips"`
+	EnablePayloads EnablePayloads `yaml:"enable_payloads"`
+	APMConfig      APMConfig      `yaml:"apm_config"`
+
+	LogsEnabled          bool          `yaml:"logs_enabled"`
+	LogsConfig           LogsConfig    `yaml:"logs_config"`
+	ProcessConfig        ProcessConfig `yaml:"process_config"`
+	ExtraConfigProviders []string      `yaml:"extra_config_providers"`
+
+	OTelCollector       Toggle              `yaml:"otelcollector"`
+	AgentIPC            AgentIPC            `yaml:"agent_ipc"`
+	PrivateActionRunner PrivateActionRunner `yaml:"private_action_runner"`
+}
+
+// Toggle is a section holding only an enabled flag, such as discovery or otelcollector
+type Toggle struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// FIPS points the Agent to the local FIPS proxy
+type FIPS struct {
+	Enabled        bool `yaml:"enabled"`
+	PortRangeStart int  `yaml:"port_range_start"`
+	HTTPS          bool `yaml:"https"`
+}
+
+// EnablePayloads selects the payloads the Agent sends, all are disabled with Error Tracking standalone
+type EnablePayloads struct {
+	Series        bool `yaml:"series"`
+	Events        bool `yaml:"events"`
+	ServiceChecks bool `yaml:"service_checks"`
+	Sketches      bool `yaml:"sketches"`
+}
+
+// APMConfig is the apm_config section
+type APMConfig struct {
+	Enabled                 bool   `yaml:"enabled"`
+	ErrorTrackingStandalone Toggle `yaml:"error_tracking_standalone"`
+}
+
+// LogsConfig is the logs_config section set for process log collection
+type LogsConfig struct {
+	ProcessExcludeAgent    bool `yaml:"process_exclude_agent"`
+	AutoMultiLineDetection bool `yaml:"auto_multi_line_detection"`
+}
+
+// ProcessConfig is the process_config section set for process log collection
+type ProcessConfig struct {
+	ProcessCollection struct {
+		UseWLM bool `yaml:"use_wlm"`
+	} `yaml:"process_collection"`
+}
+
+// AgentIPC is the agent_ipc section the DDOT collector fetches its configuration from
+type AgentIPC struct {
+	Port                  int `yaml:"port"`
+	ConfigRefreshInterval int `yaml:"config_refresh_interval"`
+}
+
+// PrivateActionRunner is the private_action_runner section
+type PrivateActionRunner struct {
+	Enabled              bool     `yaml:"enabled"`
+	ActionsAllowlist     []string `yaml:"actions_allowlist"`
+	APIKeyOnlyEnrollment bool     `yaml:"api_key_only_enrollment"`
+}
+
+// SystemProbe is system-probe.yaml, written by manage_system_probe_config
+type SystemProbe struct {
+	Document `yaml:"-"`
+
+	RuntimeSecurityConfig   Toggle `yaml:"runtime_security_config"`
+	Discovery               Toggle `yaml:"discovery"`
+	PrivilegedLogs          Toggle `yaml:"privileged_logs"`
+	ServiceMonitoringConfig Toggle `yaml:"service_monitoring_config"`
+}
+
+// SecurityAgent is security-agent.yaml, written by manage_security_config
+type SecurityAgent struct {
+	Document `yaml:"-"`
+
+	RuntimeSecurityConfig Toggle `yaml:"runtime_security_config"`
+	ComplianceConfig      Toggle `yaml:"compliance_config"`
+}
+
+// OTel is otel-config.yaml, where manage_otel_config substitutes the API key and the site
+type OTel struct {
+	Document `yaml:"-"`
+
+	Exporters struct {
+		Datadog struct {
+			API struct {
+				Key  string `yaml:"key"`
+				Site string `yaml:"site"`
+			} `yaml:"api"`
+		} `yaml:"datadog"`
+	} `yaml:"exporters"`
+}
+
+// Parse decodes content into model, a pointer to one of the YAML models of this package, or to a Document to only
+// look at raw values. It fails when a value doesn't match the type of its field, and lists the required dotted paths
+// that are absent or null.
+func Parse(content []byte, model any, required ...string) error {
+	raw := map[any]any{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+	if err := yaml.Unmarshal(content, model); err != nil {
+		return fmt.Errorf("mistyped fields: %w", err)
+	}
+	document, ok := model.(interface{ setRaw(map[any]any) })
+	if !ok {
+		return fmt.Errorf("%T is not a configuration model", model)
+	}
+	document.setRaw(raw)
+
+	var missing []string
+	for _, path := range required {
+		if !(Document{raw: raw}).Has(path) {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing fields: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package classifier
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Class is the cause of a failed install. The values are stable, tickets and dashboards refer to them.
+type Class string
+
+// Classes of failures, from the most specific to the least
+const (
+	// ClassNone is an install that succeeded
+	ClassNone Class = "none"
+	// ClassDiskFull is a package manager running out of space
+	ClassDiskFull Class = "disk-full"
+	// ClassAPTLock is another process holding the dpkg lock
+	ClassAPTLock Class = "apt-lock"
+	// ClassGPGKeyDownload is a repository key that could not be downloaded
+	ClassGPGKeyDownload Class = "gpg-key-download"
+	// ClassSourcesUpdate is apt-get update failing once the Datadog repository is added
+	ClassSourcesUpdate Class = "sources-update"
+	// ClassVersionNotFound is a pinned version missing from the repository
+	ClassVersionNotFound Class = "version-not-found"
+	// ClassUnsupportedPlatform is a distribution, version or architecture the requested install does not support
+	ClassUnsupportedPlatform Class = "unsupported-platform"
+	// ClassMissingAPIKey is an install without DD_API_KEY
+	ClassMissingAPIKey Class = "missing-api-key"
+	// ClassInvalidParameters is a DD_* variable with an invalid value, or an invalid combination of them
+	ClassInvalidParameters Class = "invalid-parameters"
+	// ClassPackageInstall is the package manager failing to install the packages
+	ClassPackageInstall Class = "package-install"
+	// ClassServiceStart is the Agent failing to start once installed
+	ClassServiceStart Class = "service-start"
+	// ClassUnknown is a failure matching none of the other classes
+	ClassUnknown Class = "unknown"
+)
+
+// Classification is the class of a log, the line it was recognized from and what to do about it
+type Classification struct {
+	Class Class `json:"class"`
+	// Evidence is the line of the log the class was recognized from, empty for ClassUnknown
+	Evidence string `json:"evidence,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+// rule recognizes a class from any of its patterns
+type rule struct {
+	class    Class
+	patterns []*regexp.Regexp
+	hint     string
+}
+
+// rules are tried in order, the first matching one wins: a failure often cascades, e.g. a full disk makes the
+// package install fail, so the root causes come first
+var rules = []rule{
+	{
+		class:    ClassDiskFull,
+		patterns: patterns(`No space left on device`, `Write error`),
+		hint:     "Free some space on the host, in /var and /opt in particular, and run the install script again.",
+	},
+	{
+		class:    ClassAPTLock,
+		patterns: patterns(`Could not get lock`, `Unable to get lock`),
+		hint:     "Another process, such as unattended-upgrades, holds the dpkg lock. Wait for it to finish, then run the install script again.",
+	},
+	{
+		class:    ClassGPGKeyDownload,
+		patterns: patterns(`Failed to download one or more (APT|RPM) GPG keys`),
+		hint:     "The host could not download the Datadog signing keys. Check its access to keys.datadoghq.com, or its proxy settings.",
+	},
+	{
+		class:    ClassSourcesUpdate,
+		patterns: patterns(`Failed to update the sources after adding the Datadog repository`),
+		hint:     "One of the APT sources of the host fails to update. Run apt-get update to find which one, fix or disable it, then run the install script again.",
+	},
+	{
+		class:    ClassVersionNotFound,
+		patterns: patterns(`Specified version not found: `),
+		hint:     "The repository has no such version. Check DD_AGENT_MINOR_VERSION against the changelog of the Agent.",
+	},
+	{
+		class: ClassUnsupportedPlatform,
+		patterns: patterns(
+			`Your OS or distribution are not supported by this install script`,
+			`This script does not support installing on the Mac`,
+			`isn't available for your architecture`,
+			`is only available since version [0-9.]+ for your architecture`,
+			`is only available for 64 bit SUSE Enterprise machines`,
+			`A future version of .* will support `,
+			`only supports .* up to [0-9]+\.[0-9]+\.`,
+		),
+		hint: "The host is not supported by this install, see the supported platforms of the Agent. An older version may be available with DD_AGENT_MINOR_VERSION.",
+	},
+	{
+		class:    ClassMissingAPIKey,
+		patterns: patterns(`API key not available in DD_API_KEY environment variable`),
+		hint:     "Set DD_API_KEY to an API key of your organization.",
+	},
+	{
+		class: ClassInvalidParameters,
+		patterns: patterns(
+			`Unknown DD_AGENT_FLAVOR `,
+			`DD_[A-Z_]+ must be `,
+			`is not supported with Agent version 6`,
+			`is only available since version .* and requested minor version is `,
+			`cannot be used with the fips-proxy installed`,
+		),
+		hint: "Fix the DD_* variable reported by the script, see the documentation of the install script for the accepted values.",
+	},
+	{
+		class:    ClassPackageInstall,
+		patterns: patterns(`Failed to install one or more packages`, `Failed to install [a-z-]+\.`),
+		hint:     "The package manager could not install the packages. Check its output in the log, then run the install script again.",
+	},
+	{
+		class:    ClassServiceStart,
+		patterns: patterns(`Error starting `),
+		hint:     "The Agent is installed but does not start. Check its status and logs, e.g. with journalctl -u datadog-agent.",
+	},
+}
+
+var (
+	// success is the banner of each service started, failure the banner of on_error: with the FIPS proxy, a log can
+	// have both
+	success    = regexp.MustCompile(`is running and functioning properly`)
+	failure    = regexp.MustCompile(`It looks like you hit an issue when trying to install`)
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+const unknownHint = "The failure is not a known one. Send the log to Datadog support."
+
+func patterns(expressions ...string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(expressions))
+	for _, expression := range expressions {
+		compiled = append(compiled, regexp.MustCompile(expression))
+	}
+	return compiled
+}
+
+// Classify returns the class of an install log, such as the content of ddagent-install.log or the message of the
+// telemetry logs payload
+func Classify(log string) Classification {
+	lines := strings.Split(ansiEscape.ReplaceAllString(log, ""), "\n")
+	// the script retries on some errors, such as the dpkg lock, they only matter when the install failed
+	if line, ok := find(lines, []*regexp.Regexp{success}); ok {
+		if _, failed := find(lines, []*regexp.Regexp{failure}); !failed {
+			return Classification{Class: ClassNone, Evidence: line}
+		}
+	}
+	for _, rule := range rules {
+		if line, ok := find(lines, rule.patterns); ok {
+			return Classification{Class: rule.class, Evidence: line, Hint: rule.hint}
+		}
+	}
+	return Classification{Class: ClassUnknown, Hint: unknownHint}
+}
+
+// find returns the first line matching any of the patterns, trimmed
+func find(lines []string, patterns []*regexp.Regexp) (string, bool) {
+	for _, line := range lines {
+		for _, pattern := range patterns {
+			if pattern.MatchString(line) {
+				return strings.TrimSpace(line), true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package classifier
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// classes are all the classes, a corpus log has to cover each of them
+var classes = []Class{
+	ClassNone, ClassDiskFull, ClassAPTLock, ClassGPGKeyDownload, ClassSourcesUpdate, ClassVersionNotFound,
+	ClassUnsupportedPlatform, ClassMissingAPIKey, ClassInvalidParameters, ClassPackageInstall, ClassServiceStart,
+	ClassUnknown,
+}
+
+// expectedClass returns the class of a corpus file, the prefix of its name: <class>.<variant>.log
+func expectedClass(path string) Class {
+	return Class(strings.SplitN(filepath.Base(path), ".", 2)[0])
+}
+
+func TestCorpus(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.log")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	covered := map[Class]bool{}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			log, err := os.ReadFile(file)
+			require.NoError(t, err)
+			expected := expectedClass(file)
+			require.Contains(t, classes, expected, "unknown class in the file name")
+			covered[expected] = true
+
+			classification := Classify(string(log))
+			assert.Equal(t, expected, classification.Class)
+			switch expected {
+			case ClassNone:
+				assert.Empty(t, classification.Hint)
+			case ClassUnknown:
+				assert.Empty(t, classification.Evidence)
+				assert.Equal(t, unknownHint, classification.Hint)
+			default:
+				assert.NotEmpty(t, classification.Hint)
+				assert.Contains(t, ansiEscape.ReplaceAllString(string(log), ""), classification.Evidence)
+			}
+		})
+	}
+	for _, class := range classes {
+		assert.True(t, covered[class], "no corpus log for %s", class)
+	}
+}
+
+func TestTelemetryCorpus(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.telemetry.json")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+			envelope, err := telemetry.ParseEnvelope(data)
+			require.NoError(t, err)
+			require.Equal(t, telemetry.RequestTypeLogs, envelope.RequestType)
+			require.Len(t, envelope.Payload.Logs, 1)
+
+			// the payload holds the same log as the file next to it
+			log, err := os.ReadFile(strings.TrimSuffix(file, ".telemetry.json") + ".log")
+			require.NoError(t, err)
+			assert.Equal(t, Classify(string(log)), Classify(envelope.Payload.Logs[0].Message))
+			assert.Equal(t, expectedClass(file), Classify(envelope.Payload.Logs[0].Message).Class)
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		class    Class
+		evidence string
+	}{
+		{
+			name:  "empty",
+			class: ClassUnknown,
+		},
+		{
+			// the root cause wins over the failure it causes
+			name:     "disk full before package install",
+			log:      "\x1b[31mFailed to install one or more packages\x1b[0m\nE: Write error - write (28: No space left on device)\n",
+			class:    ClassDiskFull,
+			evidence: "E: Write error - write (28: No space left on device)",
+		},
+		{
+			// a retried lock does not fail the install
+			name:     "lock retried",
+			log:      "E: Could not get lock /var/lib/dpkg/lock-frontend\n  Your Datadog Agent is running and functioning properly.\n",
+			class:    ClassNone,
+			evidence: "Your Datadog Agent is running and functioning properly.",
+		},
+		{
+			name: "service failing after another started",
+			log: "Your Datadog Agent is running and functioning properly.\n\x1b[31mError starting Datadog FIPS Proxy\x1b[0m\n" +
+				"\x1b[31mIt looks like you hit an issue when trying to install the Datadog Agent.\x1b[0m\n",
+			class:    ClassServiceStart,
+			evidence: "Error starting Datadog FIPS Proxy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classification := Classify(tt.log)
+			assert.Equal(t, tt.class, classification.Class)
+			assert.Equal(t, tt.evidence, classification.Evidence)
+		})
+	}
+}
+
+func TestClassValues(t *testing.T) {
+	// the values are reported in tickets and dashboards, they must not change
+	assert.Equal(t, []string{
+		"none", "disk-full", "apt-lock", "gpg-key-download", "sources-update", "version-not-found",
+		"unsupported-platform", "missing-api-key", "invalid-parameters", "package-install", "service-start", "unknown",
+	}, func() []string {
+		values := make([]string, 0, len(classes))
+		for _, class := range classes {
+			values = append(values, string(class))
+		}
+		return values
+	}())
+	for _, rule := range rules {
+		assert.Contains(t, classes, rule.class)
+	}
+}
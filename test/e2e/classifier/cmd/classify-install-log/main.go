@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Command classify-install-log prints the class of failure of install logs, given as files or on stdin. An input is
+// either a ddagent-install.log or a telemetry logs payload, such as /tmp/datadog-installer-log.json.
+//
+//	classify-install-log [-json] [file...]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/classifier"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/telemetry"
+)
+
+// result is the output for an input, in -json mode
+type result struct {
+	File string `json:"file,omitempty"`
+	classifier.Classification
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "print one JSON object per input")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-json] [file...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	failed := false
+	for _, file := range files {
+		classification, err := classifyFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			failed = true
+			continue
+		}
+		name := file
+		if name == "-" {
+			name = ""
+		}
+		if *asJSON {
+			out, _ := json.Marshal(result{File: name, Classification: classification})
+			fmt.Println(string(out))
+			continue
+		}
+		if len(files) > 1 {
+			fmt.Printf("%s:\n", file)
+		}
+		fmt.Printf("class: %s\n", classification.Class)
+		if classification.Evidence != "" {
+			fmt.Printf("evidence: %s\n", classification.Evidence)
+		}
+		if classification.Hint != "" {
+			fmt.Printf("hint: %s\n", classification.Hint)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func classifyFile(file string) (classifier.Classification, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return classifier.Classification{}, err
+	}
+	log, err := installLog(data)
+	if err != nil {
+		return classifier.Classification{}, err
+	}
+	return classifier.Classify(log), nil
+}
+
+// installLog returns the log in data, the message of the entries of a telemetry logs payload or data itself
+func installLog(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return string(data), nil
+	}
+	envelope, err := telemetry.ParseEnvelope(data)
+	if err != nil {
+		return "", err
+	}
+	if envelope.RequestType != telemetry.RequestTypeLogs {
+		return "", fmt.Errorf("%q telemetry payload, expected %q", envelope.RequestType, telemetry.RequestTypeLogs)
+	}
+	messages := make([]string, 0, len(envelope.Payload.Logs))
+	for _, entry := range envelope.Payload.Logs {
+		messages = append(messages, entry.Message)
+	}
+	return strings.Join(messages, "\n"), nil
+}
@@ -0,0 +1,9 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package classifier sorts the ddagent-install.log of a failed install, or the message of the telemetry logs payload,
+// into a stable taxonomy of causes with a remediation hint. The cmd/classify-install-log command runs it on support
+// tickets, testdata holds the recorded logs it is tested against.
+package classifier
@@ -6,24 +6,26 @@
 package e2e
 
 import (
+	"encoding/base64"
 	"flag"
 	"fmt"
 	"os"
+	"path"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/components"
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/environments"
+	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 	componentsos "github.com/DataDog/test-infra-definitions/components/os"
 	"github.com/DataDog/test-infra-definitions/scenarios/aws/ec2"
 
-	envparse "github.com/hashicorp/go-envparse"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/inventory"
 	version "github.com/hashicorp/go-version"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	yaml "gopkg.in/yaml.v2"
 )
 
 type osConfig struct {
@@ -35,6 +37,7 @@ const (
 	defaultAgentFlavor          agentFlavor = agentFlavorDatadogAgent
 	defaultPlatform                         = "Ubuntu_22_04"
 	defaultMode                             = "install"
+	defaultProvisioner                      = provisionerAWS
 	fipsConfigFilepath                      = "/etc/datadog-fips-proxy/datadog-fips-proxy.cfg"
 	otelConfigFileName                      = "otel-config.yaml"
 	systemProbeConfigFileName               = "system-probe.yaml"
@@ -48,6 +51,12 @@ var (
 	scriptPath string      // Absolute path to the generated install scripts
 	noFlush    bool        // To prevent eventual cleanup, to test install_script won't override existing configuration
 	platform   string      // Platform under test
+	// Provisioner used to create the host under test
+	provisioner    string
+	containerImage string // Image used by the container provisioner, overrides containerImageByPlatform
+	// serviceTimeout is how long services have to reach the expected state after install
+	serviceTimeout time.Duration
+	diagnosticsDir string // Where the diagnostics of failed tests are written, see AfterTest
 
 	baseNameByFlavor = map[agentFlavor]string{
 		agentFlavorDatadogAgent:     "datadog-agent",
@@ -77,6 +86,10 @@ func init() {
 	flag.StringVar(&apiKey, "apiKey", os.Getenv("DD_API_KEY"), "Datadog API key")
 	flag.StringVar(&scriptPath, "scriptPath", "", "Absolute path to the generated install scripts")
 	flag.StringVar(&platform, "platform", defaultPlatform, fmt.Sprintf("Defines the target platform, default %s", defaultPlatform))
+	flag.StringVar(&provisioner, "provisioner", defaultProvisioner, fmt.Sprintf("Defines where the platform runs, supported values are [%s, %s], default %s", provisionerAWS, provisionerContainer, defaultProvisioner))
+	flag.StringVar(&containerImage, "containerImage", "", "Image used by the container provisioner, defaults to the image of the platform")
+	flag.StringVar(&diagnosticsDir, "diagnosticsDir", "diagnostics", "Directory where the diagnostics of the host are written when a test fails")
+	flag.DurationVar(&serviceTimeout, "serviceTimeout", 30*time.Second, "How long services have to reach the expected state after install")
 }
 
 func getenv(key, fallback string) string {
@@ -92,52 +105,70 @@ type linuxInstallerTestSuite struct {
 	baseName        string
 	optPathOverride string
 	configFile      string
+	// container is set when the suite runs against a local container instead of an EC2 instance. s.Env().RemoteHost
+	// is nil then, use host.
+	container *containerProvisioner
+	// pkgManager is detected on first use, see packageManager
+	pkgManager packageManager
+	// svcManager is detected on first use, see serviceManager
+	svcManager serviceManager
+	// services lists the features adding services to the ones of the flavor, see expectedServices
+	services serviceFeatures
+	// secrets and installOutputs are recorded by trackInstall for auditSecrets, after each test
+	secrets        map[string]string
+	installOutputs []string
+	// inventories are the file trees of the host recorded at each stage of the test, see recordInventory
+	inventories map[inventoryStage]inventory.Inventory
 }
 
-func (s *linuxInstallerTestSuite) InstallAgent(agentVersion int, extraParam ...string) string {
-	t := s.T()
-	vm := s.Env().RemoteHost
-
-	installationScriptPath := "scripts/install_agent.sh"
-	scriptEnvVariable := fmt.Sprintf("DD_API_KEY=%s", apiKey)
-	if agentVersion != 5 {
-		scriptEnvVariable = scriptEnvVariable + fmt.Sprintf(" DD_AGENT_MAJOR_VERSION=%d DD_AGENT_FLAVOR=%s", agentVersion, flavor)
-		installationScriptPath = "scripts/install_script_agent7.sh"
-		if agentVersion == 6 {
-			installationScriptPath = "scripts/install_script_agent6.sh"
-		}
+// provisionerOption returns the suite option creating the host selected by the -provisioner flag
+func (s *linuxInstallerTestSuite) provisionerOption(t *testing.T) e2e.SuiteOption {
+	t.Helper()
+	switch provisioner {
+	case provisionerAWS:
+		return e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...)))
+	case provisionerContainer:
+		s.container = newContainerProvisioner(getContainerImage(t))
+		return e2e.WithProvisioner(s.container)
 	}
+	t.Fatalf("unknown provisioner %s", provisioner)
+	return nil
+}
 
-	extraParamLength := len(extraParam)
-	if agentVersion == 7 && extraParamLength == 0 {
-		if val, ok := os.LookupEnv("TESTING_YUM_VERSION_PATH"); ok {
-			scriptEnvVariable = scriptEnvVariable + fmt.Sprintf(" TESTING_YUM_VERSION_PATH='%s'", val)
-		}
-		if val, ok := os.LookupEnv("TESTING_APT_REPO_VERSION"); ok {
-			scriptEnvVariable = scriptEnvVariable + fmt.Sprintf(" TESTING_APT_REPO_VERSION='%s'", val)
-		}
-		if val, ok := os.LookupEnv("TESTING_YUM_URL"); ok {
-			scriptEnvVariable = scriptEnvVariable + fmt.Sprintf(" TESTING_YUM_URL='%s'", val)
-		}
-		if val, ok := os.LookupEnv("TESTING_APT_URL"); ok {
-			scriptEnvVariable = scriptEnvVariable + fmt.Sprintf(" TESTING_APT_URL='%s'", val)
-		}
-		if val, ok := os.LookupEnv("TESTING_KEYS_URL"); ok {
-			scriptEnvVariable = scriptEnvVariable + fmt.Sprintf(" TESTING_KEYS_URL='%s'", val)
-		}
+// host returns the machine under test, either the EC2 instance or the local container
+func (s *linuxInstallerTestSuite) host() installerHost {
+	if s.container != nil {
+		return s.container.host(s.T())
 	}
+	require.NotNil(s.T(), s.Env().RemoteHost, "no remote host provisioned")
+	return s.Env().RemoteHost
+}
 
-	if extraParamLength == 0 {
-		t.Logf("Install latest Agent %d", agentVersion)
-	} else {
-		scriptEnvVariable = scriptEnvVariable + " " + strings.Join(extraParam[:extraParamLength-1], " ")
-		t.Log(extraParam[extraParamLength-1])
+// runScriptAsRoot runs a bash script as root on the host and returns its output, failing the test when it fails. The
+// script is passed encoded, so that its quotes survive the command line.
+func (s *linuxInstallerTestSuite) runScriptAsRoot(script string) string {
+	encoded := base64.StdEncoding.EncodeToString([]byte(script))
+	return s.host().MustExecute(fmt.Sprintf("echo %s | base64 -d | sudo bash", encoded))
+}
+
+// packageManager returns the package manager of the host, failing the test when there is none
+func (s *linuxInstallerTestSuite) packageManager() packageManager {
+	if s.pkgManager == nil {
+		pkgManager, err := detectPackageManager(s.host())
+		require.NoError(s.T(), err)
+		s.pkgManager = pkgManager
 	}
-	cmd := fmt.Sprintf("%s bash -c \"$(cat %s)\"", scriptEnvVariable, installationScriptPath)
-	output := vm.MustExecute(cmd)
-	t.Log(output)
+	return s.pkgManager
+}
 
-	return output
+// serviceManager returns the service manager of the host, failing the test when there is none
+func (s *linuxInstallerTestSuite) serviceManager() serviceManager {
+	if s.svcManager == nil {
+		svcManager, err := detectServiceManager(s.host())
+		require.NoError(s.T(), err)
+		s.svcManager = svcManager
+	}
+	return s.svcManager
 }
 
 // SetupSuite is called at suite initialisation, once before all tests
@@ -152,8 +183,12 @@ func (s *linuxInstallerTestSuite) SetupSuite() {
 	s.baseName = baseNameByFlavor[flavor]
 	s.configFile = configFileByFlavor[flavor]
 	fmt.Println("SetupSuite2")
-	fmt.Printf("Copying scripts from %s to %s\n", scriptPath, s.Env().RemoteHost.Address)
-	err := s.Env().RemoteHost.CopyFolder(scriptPath, "scripts")
+	if s.container != nil {
+		fmt.Printf("Copying scripts from %s to container %s\n", scriptPath, s.container.containerID)
+	} else {
+		fmt.Printf("Copying scripts from %s to %s\n", scriptPath, s.Env().RemoteHost.Address)
+	}
+	err := s.host().CopyFolder(scriptPath, "scripts")
 	require.NoError(s.T(), err, "failed to copy scripts")
 	fmt.Println("SetupSuite3")
 }
@@ -179,7 +214,7 @@ func getEC2Options(t *testing.T) []ec2.VMOption {
 
 func (s *linuxInstallerTestSuite) getLatestEmbeddedPythonPath(baseName string) string {
 	s.T().Helper()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	cmd := fmt.Sprintf("echo /opt/%s/embedded/lib/python*", baseName)
 	result, err := vm.Execute(cmd)
 	require.NoError(s.T(), err, fmt.Sprintf("Python embedded libraries not found: %s", err))
@@ -209,7 +244,7 @@ func (s *linuxInstallerTestSuite) getLatestEmbeddedPythonPath(baseName string) s
 
 func (s *linuxInstallerTestSuite) assertInstallScript(active bool) {
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Helper()
 	t.Log("Check user, config file and service")
 	// check presence of the dd-agent user
@@ -218,54 +253,59 @@ func (s *linuxInstallerTestSuite) assertInstallScript(active bool) {
 	// Check presence of the config file - the file is added by the install script, so this should always be okay
 	// if the install succeeds
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, s.configFile))
+	// Check the package and the repository the script sets up
+	s.assertPackageInstalled(string(flavor))
+	repoFiles, err := s.packageManager().repoFiles()
+	assert.NoError(t, err)
+	assert.True(t, slices.ContainsFunc(repoFiles, func(file string) bool {
+		return strings.HasPrefix(path.Base(file), "datadog.")
+	}), "no datadog repository in %v", repoFiles)
+	// the script rewrites datadog.repo without exclude line, upgrades must reach the package
+	held, err := s.packageManager().heldPackages()
+	assert.NoError(t, err)
+	assert.NotContains(t, held, string(flavor), "%s is held by %s", flavor, s.packageManager().name())
 	// Check presence and ownership of the config and main directories
 	owner := strings.TrimSuffix(vm.MustExecute(fmt.Sprintf("stat -c \"%%U\" /etc/%s/", s.baseName)), "\n")
 	assert.Equal(t, "dd-agent", owner, fmt.Sprintf("dd-agent does not own /etc/%s", s.baseName))
 
 	owner = strings.TrimSuffix(vm.MustExecute(fmt.Sprintf("stat -c \"%%U\" /opt/%s/", s.baseName)), "\n")
 	assert.Equal(t, "dd-agent", owner, fmt.Sprintf("dd-agent does not own /opt/%s", s.baseName))
-	serviceNames := []string{s.baseName}
-	if flavor == agentFlavorDatadogAgent {
-		serviceNames = append(serviceNames, "datadog-agent-trace")
-		// Cannot assert process-agent because it may be running or dead based on timing
+	s.assertServices(active, expectedServices(flavor, s.services)...)
+	if active {
+		// the services of an active install start on boot too
+		enabled, err := s.serviceManager().isEnabled(s.baseName)
+		assert.NoError(t, err)
+		assert.True(t, enabled, "%s not enabled after Agent install", s.baseName)
 	}
-	if strings.Contains(t.Name(), "install-ddot") {
-		serviceNames = append(serviceNames, "datadog-agent-ddot")
-	}
-	// Check that the services are active
-	if _, err = vm.Execute("command -v systemctl"); err == nil {
-		for _, serviceName := range serviceNames {
-			_, err = vm.Execute(fmt.Sprintf("systemctl is-active %s", serviceName))
-			if active {
-				assert.NoError(t, err, fmt.Sprintf("%s not running after Agent install", serviceName))
-			} else {
-				assert.Error(t, err, fmt.Sprintf("%s running after Agent install", serviceName))
+}
+
+// assertServices waits for the services to be active, or inactive, for at most serviceTimeout, and logs their
+// status when they don't. Their logs are part of the diagnostics, see AfterTest.
+func (s *linuxInstallerTestSuite) assertServices(active bool, services ...string) {
+	t := s.T()
+	t.Helper()
+	svcManager := s.serviceManager()
+	ok := assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		for _, service := range services {
+			running, err := svcManager.isActive(service)
+			if !assert.NoError(c, err) {
+				continue
 			}
-		}
-	} else if _, err = vm.Execute("/sbin/init --version 2>&1 | grep -q upstart;"); err == nil {
-		for _, serviceName := range serviceNames {
-			status := strings.TrimSuffix(vm.MustExecute(fmt.Sprintf("sudo status %s", serviceName)), "\n")
 			if active {
-				assert.Contains(t, status, "running", fmt.Sprintf("%s not running after Agent install", serviceName))
+				assert.True(c, running, "%s not running after Agent install", service)
 			} else {
-				assert.NotContains(t, status, "running", fmt.Sprintf("%s running after Agent install", serviceName))
+				assert.False(c, running, "%s running after Agent install", service)
 			}
 		}
-	} else {
-		require.FailNow(t, "Unknown service manager")
+	}, serviceTimeout, time.Second, "%s services did not reach the expected state", svcManager.name())
+	if ok {
+		return
 	}
-	if t.Failed() {
-		stdout, err := vm.Execute("sudo journalctl --no-pager")
-		if err != nil {
-			t.Logf("Failed to get journalctl logs: %s", err)
-		} else {
-			t.Logf("journalctl logs:\n%s", stdout)
-		}
-		stdout, err = vm.Execute("sudo systemctl status datadog*")
-		if err != nil {
-			t.Logf("Failed to get systemctl status: %s", err)
+	for _, service := range services {
+		if status, err := svcManager.status(service); err != nil {
+			t.Logf("Failed to get %s status: %s", service, err)
 		} else {
-			t.Logf("systemctl logs:\n%s", stdout)
+			t.Logf("%s status:\n%s", service, status)
 		}
 	}
 }
@@ -276,7 +316,7 @@ func (s *linuxInstallerTestSuite) addExtraIntegration() {
 	if flavor != "datadog-agent" {
 		return
 	}
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Log("Install an extra integration, and create a custom file")
 	_, err := vm.Execute("sudo -u dd-agent -- datadog-agent integration install -t datadog-bind9==0.1.0")
 	assert.NoError(t, err, "integration install failed")
@@ -285,27 +325,18 @@ func (s *linuxInstallerTestSuite) addExtraIntegration() {
 
 func (s *linuxInstallerTestSuite) uninstall() {
 	t := s.T()
-	vm := s.Env().RemoteHost
 	t.Helper()
-	t.Logf("Remove %s", flavor)
-	if _, err := vm.Execute("command -v apt"); err == nil {
-		t.Log("Uninstall with apt")
-		vm.Execute(fmt.Sprintf("sudo apt remove -y %s", flavor))
-	} else if _, err = vm.Execute("command -v yum"); err == nil {
-		t.Log("Uninstall with yum")
-		vm.Execute(fmt.Sprintf("sudo yum remove -y %s", flavor))
-	} else if _, err = vm.Execute("command -v zypper"); err == nil {
-		t.Log("Uninstall with zypper")
-		vm.Execute(fmt.Sprintf("sudo zypper remove -y %s", flavor))
-	} else {
-		require.FailNow(t, "Unknown package manager")
-	}
+	pkgManager := s.packageManager()
+	s.recordInventory(stageInstalled)
+	t.Logf("Remove %s with %s", flavor, pkgManager.name())
+	assert.NoError(t, pkgManager.remove(string(flavor)), "failed to remove %s", flavor)
 }
 
 func (s *linuxInstallerTestSuite) assertUninstall() {
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Logf("Assert %s is removed", flavor)
+	s.assertPackageNotInstalled(string(flavor))
 	// dd-agent user and config file should still be here
 	assert.EventuallyWithT(t, func(c *assert.CollectT) {
 		_, err := vm.Execute("id dd-agent")
@@ -322,14 +353,9 @@ func (s *linuxInstallerTestSuite) assertUninstall() {
 			assertFileNotExists(c, vm, fmt.Sprintf("/opt/%s", s.baseName))
 		}
 	}, 10*time.Second, time.Second)
-	if t.Failed() {
-		stdout, err := vm.Execute("journalctl --no-pager")
-		if err != nil {
-			t.Logf("Failed to get journalctl logs: %s", err)
-		} else {
-			t.Logf("journalctl logs:\n%s", stdout)
-		}
-	}
+
+	s.recordInventory(stageUninstalled)
+	s.assertUninstallInventory()
 }
 
 func (s *linuxInstallerTestSuite) purge() {
@@ -340,23 +366,13 @@ func (s *linuxInstallerTestSuite) purge() {
 		return
 	}
 
-	vm := s.Env().RemoteHost
-
 	t.Log("Purge package")
-	vm.Execute(fmt.Sprintf("sudo apt remove --purge -y %s", flavor))
+	assert.NoError(t, s.packageManager().purge(string(flavor)), "failed to purge %s", flavor)
 }
 
 func (s *linuxInstallerTestSuite) shouldSkipPurge() bool {
-	t := s.T()
-	vm := s.Env().RemoteHost
-	t.Helper()
-	if noFlush {
-		return true
-	}
-	if _, err := vm.Execute("command -v apt"); err != nil {
-		return true
-	}
-	return false
+	s.T().Helper()
+	return noFlush || !s.packageManager().canPurge()
 }
 
 func (s *linuxInstallerTestSuite) assertPurge() {
@@ -367,39 +383,49 @@ func (s *linuxInstallerTestSuite) assertPurge() {
 		return
 	}
 
-	vm := s.Env().RemoteHost
+	vm := s.host()
 
 	t.Log("Assert purge package")
 	_, err := vm.Execute("id datadog-agent")
 	assert.Error(t, err, "dd-agent present after %s purge")
 	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s", s.baseName))
 	assertFileNotExists(t, vm, fmt.Sprintf("/opt/%s", s.baseName))
-}
 
-func assertFileExists(t assert.TestingT, vm *components.RemoteHost, filepath string) {
-	_, err := vm.Execute(fmt.Sprintf("stat %s", filepath))
-	assert.NoError(t, err, fmt.Sprintf("file %s does not exist", filepath))
+	s.recordInventory(stagePurged)
+	s.assertPurgeInventory()
 }
 
-func assertFileNotExists(t assert.TestingT, vm *components.RemoteHost, filepath string) {
-	_, err := vm.Execute(fmt.Sprintf("stat %s", filepath))
-	assert.Error(t, err, fmt.Sprintf("file %s does exist", filepath))
+// assertPackageInstalled checks that the package manager reports the package as installed
+func (s *linuxInstallerTestSuite) assertPackageInstalled(pkg string) {
+	t := s.T()
+	t.Helper()
+	pkgManager := s.packageManager()
+	installed, err := pkgManager.installed(pkg)
+	require.NoError(t, err)
+	if !assert.True(t, installed, "%s is not installed according to %s", pkg, pkgManager.name()) {
+		return
+	}
+	version, err := pkgManager.installedVersion(pkg)
+	assert.NoError(t, err)
+	t.Logf("%s %s is installed", pkg, version)
 }
 
-func unmarshalConfigFile(t *testing.T, vm *components.RemoteHost, configFilePath string) map[string]any {
+// assertPackageNotInstalled checks that the package manager doesn't report the package as installed
+func (s *linuxInstallerTestSuite) assertPackageNotInstalled(pkg string) {
+	t := s.T()
 	t.Helper()
-	configContent := vm.MustExecute(fmt.Sprintf("sudo cat /%s", configFilePath))
-	config := map[string]any{}
-	err := yaml.Unmarshal([]byte(configContent), &config)
-	require.NoError(t, err, fmt.Sprintf("unexpected error on yaml parse %v, raw content:\n%s\n\n", err, configContent))
-	return config
+	pkgManager := s.packageManager()
+	installed, err := pkgManager.installed(pkg)
+	require.NoError(t, err)
+	assert.False(t, installed, "%s is installed according to %s", pkg, pkgManager.name())
 }
 
-func unmarshallEnvFile(t *testing.T, vm *components.RemoteHost, envFilePath string) map[string]string {
-	t.Helper()
-	configContent := vm.MustExecute(fmt.Sprintf("sudo cat /%s", envFilePath))
-	reader := strings.NewReader(configContent)
-	config, err := envparse.Parse(reader)
-	require.NoError(t, err, fmt.Sprintf("unexpected error on env parse %v, raw content:\n%s\n\n", err, configContent))
-	return config
+func assertFileExists(t assert.TestingT, vm installerHost, filepath string) {
+	_, err := vm.Execute(fmt.Sprintf("stat %s", filepath))
+	assert.NoError(t, err, fmt.Sprintf("file %s does not exist", filepath))
+}
+
+func assertFileNotExists(t assert.TestingT, vm installerHost, filepath string) {
+	_, err := vm.Execute(fmt.Sprintf("stat %s", filepath))
+	assert.Error(t, err, fmt.Sprintf("file %s does exist", filepath))
 }
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"fmt"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/agentconfig"
+	"github.com/stretchr/testify/require"
+)
+
+// readConfigFile returns the content of a file of the host, read as root
+func (s *linuxInstallerTestSuite) readConfigFile(path string) []byte {
+	s.T().Helper()
+	return []byte(s.host().MustExecute(fmt.Sprintf("sudo cat %s", path)))
+}
+
+// loadConfig parses a YAML configuration of the host into model, failing the test on mistyped fields and on the
+// required dotted paths that are missing
+func (s *linuxInstallerTestSuite) loadConfig(path string, model any, required ...string) {
+	t := s.T()
+	t.Helper()
+	content := s.readConfigFile(path)
+	err := agentconfig.Parse(content, model, required...)
+	require.NoError(t, err, fmt.Sprintf("unexpected content in %s, raw content:\n%s\n\n", path, content))
+}
+
+// loadDatadogConfig parses the main configuration of the flavor, datadog.yaml or dogstatsd.yaml
+func (s *linuxInstallerTestSuite) loadDatadogConfig(required ...string) agentconfig.Datadog {
+	s.T().Helper()
+	var config agentconfig.Datadog
+	s.loadConfig(fmt.Sprintf("/etc/%s/%s", s.baseName, s.configFile), &config, required...)
+	return config
+}
+
+func (s *linuxInstallerTestSuite) loadSystemProbeConfig(required ...string) agentconfig.SystemProbe {
+	s.T().Helper()
+	var config agentconfig.SystemProbe
+	s.loadConfig(fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName), &config, required...)
+	return config
+}
+
+func (s *linuxInstallerTestSuite) loadSecurityAgentConfig(required ...string) agentconfig.SecurityAgent {
+	s.T().Helper()
+	var config agentconfig.SecurityAgent
+	s.loadConfig(fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName), &config, required...)
+	return config
+}
+
+func (s *linuxInstallerTestSuite) loadOTelConfig(required ...string) agentconfig.OTel {
+	s.T().Helper()
+	var config agentconfig.OTel
+	s.loadConfig(fmt.Sprintf("/etc/%s/%s", s.baseName, otelConfigFileName), &config, required...)
+	return config
+}
+
+func (s *linuxInstallerTestSuite) loadFIPSProxyConfig() agentconfig.FIPSProxy {
+	t := s.T()
+	t.Helper()
+	content := s.readConfigFile(fipsConfigFilepath)
+	config, err := agentconfig.ParseFIPSProxy(content)
+	require.NoError(t, err, fmt.Sprintf("unexpected content in %s, raw content:\n%s\n\n", fipsConfigFilepath, content))
+	return config
+}
+
+// loadEnvironment parses an environment file, failing the test when a variable set by the script isn't a boolean
+// or when a required variable is not set
+func (s *linuxInstallerTestSuite) loadEnvironment(path string, required ...string) agentconfig.Environment {
+	t := s.T()
+	t.Helper()
+	content := s.readConfigFile(path)
+	env, err := agentconfig.ParseEnvironment(content, required...)
+	require.NoError(t, err, fmt.Sprintf("unexpected content in %s, raw content:\n%s\n\n", path, content))
+	return env
+}
@@ -0,0 +1,235 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/environments"
+	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners"
+	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/utils/e2e/client"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	provisionerAWS       = "aws"
+	provisionerContainer = "container"
+
+	// containerWorkDir plays the role of the ssh user home directory on EC2 instances,
+	// relative paths such as "scripts/install_script_agent7.sh" are resolved from it
+	containerWorkDir = "/root"
+	// containerBootTimeout is how long we wait for systemd to be up in the container
+	containerBootTimeout = 2 * time.Minute
+)
+
+var (
+	// Images running systemd as PID 1, so that services can be started as on a regular host
+	containerImageByPlatform = map[string]string{
+		"Debian_11":       "jrei/systemd-debian:11",
+		"Ubuntu_22_04":    "jrei/systemd-ubuntu:22.04",
+		"RedHat_CentOS_7": "centos/systemd",
+		"RedHat_8":        "registry.access.redhat.com/ubi8/ubi-init",
+	}
+
+	// Most systemd images don't ship sudo, which the suites use in their commands. Commands already run
+	// as root in the container so the shim only has to handle the "-u user" switch.
+	sudoShim = `#!/bin/bash
+user=
+while [ $# -gt 0 ]; do
+  case "$1" in
+    -u) user="$2"; shift 2;;
+    -E|-n|-H) shift;;
+    --) shift; break;;
+    *) break;;
+  esac
+done
+if [ -n "$user" ]; then
+  exec runuser -u "$user" -- "$@"
+fi
+exec "$@"`
+)
+
+// installerHost is the part of components.RemoteHost the suites rely on. It is implemented by both
+// the EC2 instance and the local container so that the same suite can run on either.
+type installerHost interface {
+	Execute(command string, options ...client.ExecuteOption) (string, error)
+	MustExecute(command string, options ...client.ExecuteOption) string
+	CopyFolder(srcFolder string, dstFolder string) error
+	ReadFile(path string) ([]byte, error)
+}
+
+func getContainerImage(t *testing.T) string {
+	t.Helper()
+	if containerImage != "" {
+		return containerImage
+	}
+	image, ok := containerImageByPlatform[platform]
+	if !ok {
+		// a skip would report the suite as passing without running anything
+		platforms := make([]string, 0, len(containerImageByPlatform))
+		for known := range containerImageByPlatform {
+			platforms = append(platforms, known)
+		}
+		sort.Strings(platforms)
+		t.Fatalf("no container image for platform %s, use -containerImage to provide a systemd image or one of %s", platform, strings.Join(platforms, ", "))
+	}
+	return image
+}
+
+// containerProvisioner runs the suite against a local systemd container instead of an EC2 instance.
+// It returns no resources, so s.Env().RemoteHost stays nil: suites, diagnostics and the secrets audit reach the
+// container through linuxInstallerTestSuite.host.
+type containerProvisioner struct {
+	image       string
+	containerID string
+}
+
+var _ provisioners.TypedProvisioner[environments.Host] = &containerProvisioner{}
+
+func newContainerProvisioner(image string) *containerProvisioner {
+	return &containerProvisioner{image: image}
+}
+
+// ID returns the provisioner identifier
+func (p *containerProvisioner) ID() string {
+	return "local-container"
+}
+
+// ProvisionEnv starts the container and waits for systemd to be ready
+func (p *containerProvisioner) ProvisionEnv(ctx context.Context, stackName string, logger io.Writer, _ *environments.Host) (provisioners.RawResources, error) {
+	if p.containerID != "" {
+		return provisioners.RawResources{}, nil
+	}
+	// A container from a previous run with the same stack name would prevent this one from starting
+	_ = exec.CommandContext(ctx, "docker", "rm", "--force", stackName).Run()
+
+	fmt.Fprintf(logger, "Starting container %s from %s\n", stackName, p.image)
+	output, err := exec.CommandContext(ctx, "docker", "run", "--detach",
+		"--name", stackName,
+		"--privileged",
+		"--cgroupns=host",
+		"--volume", "/sys/fs/cgroup:/sys/fs/cgroup:rw",
+		"--tmpfs", "/run",
+		"--tmpfs", "/run/lock",
+		p.image, "/sbin/init").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start container from %s: %w, output:\n%s", p.image, err, output)
+	}
+	p.containerID = strings.TrimSpace(string(output))
+
+	deadline := time.Now().Add(containerBootTimeout)
+	for {
+		state, _ := p.exec(ctx, "systemctl is-system-running")
+		state = strings.TrimSpace(state)
+		if state == "running" || state == "degraded" {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("systemd not ready in container %s after %s, state: %s", p.containerID, containerBootTimeout, state)
+		}
+		time.Sleep(time.Second)
+	}
+
+	if _, err := p.exec(ctx, "command -v sudo"); err != nil {
+		fmt.Fprintln(logger, "Installing sudo shim in the container")
+		if output, err := p.exec(ctx, fmt.Sprintf("echo '%s' > /usr/local/bin/sudo && chmod +x /usr/local/bin/sudo", sudoShim)); err != nil {
+			return nil, fmt.Errorf("failed to install sudo shim: %w, output:\n%s", err, output)
+		}
+	}
+	return provisioners.RawResources{}, nil
+}
+
+// Destroy removes the container
+func (p *containerProvisioner) Destroy(ctx context.Context, stackName string, logger io.Writer) error {
+	fmt.Fprintf(logger, "Removing container %s\n", stackName)
+	output, err := exec.CommandContext(ctx, "docker", "rm", "--force", stackName).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to remove container %s: %w, output:\n%s", stackName, err, output)
+	}
+	p.containerID = ""
+	return nil
+}
+
+func (p *containerProvisioner) exec(ctx context.Context, command string) (string, error) {
+	output, err := exec.CommandContext(ctx, "docker", "exec", "--workdir", containerWorkDir, p.containerID, "bash", "-c", command).CombinedOutput()
+	return string(output), err
+}
+
+func (p *containerProvisioner) host(t *testing.T) *containerHost {
+	require.NotEmpty(t, p.containerID, "container not started")
+	return &containerHost{t: t, containerID: p.containerID}
+}
+
+// containerHost offers the same command and file surface as components.RemoteHost on top of docker exec and docker cp
+type containerHost struct {
+	t           *testing.T
+	containerID string
+}
+
+var _ installerHost = &containerHost{}
+
+// Execute runs a command in the container and returns its combined output
+func (h *containerHost) Execute(command string, options ...client.ExecuteOption) (string, error) {
+	params := client.ExecuteParams{}
+	for _, option := range options {
+		if err := option(&params); err != nil {
+			return "", err
+		}
+	}
+	args := []string{"exec", "--workdir", containerWorkDir}
+	for key, value := range params.EnvVariables {
+		args = append(args, "--env", fmt.Sprintf("%s=%s", key, value))
+	}
+	args = append(args, h.containerID, "bash", "-c", command)
+	output, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("%s failed: %w", command, err)
+	}
+	return string(output), nil
+}
+
+// MustExecute runs a command in the container and fails the test if it fails
+func (h *containerHost) MustExecute(command string, options ...client.ExecuteOption) string {
+	output, err := h.Execute(command, options...)
+	require.NoError(h.t, err, output)
+	return output
+}
+
+// CopyFolder copies the content of a local folder to the container
+func (h *containerHost) CopyFolder(srcFolder string, dstFolder string) error {
+	dstFolder = h.resolve(dstFolder)
+	// The trailing "/." copies the content of srcFolder rather than the folder itself
+	output, err := exec.Command("docker", "cp", filepath.Clean(srcFolder)+"/.", fmt.Sprintf("%s:%s", h.containerID, dstFolder)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w, output:\n%s", srcFolder, dstFolder, err, output)
+	}
+	return nil
+}
+
+// ReadFile returns the content of a file in the container
+func (h *containerHost) ReadFile(filePath string) ([]byte, error) {
+	output, err := exec.Command("docker", "exec", h.containerID, "cat", h.resolve(filePath)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return output, nil
+}
+
+func (h *containerHost) resolve(filePath string) string {
+	if path.IsAbs(filePath) {
+		return filePath
+	}
+	return path.Join(containerWorkDir, filePath)
+}
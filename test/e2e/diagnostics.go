@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const diagnosticsArchive = "/tmp/datadog-e2e-diagnostics.tar.gz"
+
+// diagnosticsFiles are collected from the host when a test fails, along with the configuration directories, the
+// repository files and the journal of each service. Relative paths are relative to the home of the test user, where
+// the script writes its log.
+var diagnosticsFiles = []string{
+	"ddagent-install.log",
+	"/tmp/datadog-installer-trace.json",
+	"/tmp/datadog-installer-log.json",
+	"/tmp/ddog_install_error_msg",
+	"/tmp/datadog-installer-stdout.log",
+	"/tmp/datadog-installer-stderr.log",
+}
+
+// AfterTest audits the secrets given to the script and forgets the inventories of the test, then writes the
+// diagnostics of the host under diagnosticsDir when the test failed
+func (s *linuxInstallerTestSuite) AfterTest(suiteName, testName string) {
+	s.BaseSuite.AfterTest(suiteName, testName)
+	defer s.resetInstalls()
+	defer s.resetInventories()
+	s.auditSecrets()
+	t := s.T()
+	if !t.Failed() {
+		return
+	}
+	dir := filepath.Join(diagnosticsDir, strings.NewReplacer("/", "_", " ", "_").Replace(t.Name()))
+	if err := s.collectDiagnostics(dir); err != nil {
+		t.Logf("Failed to collect diagnostics: %s", err)
+		return
+	}
+	t.Logf("Diagnostics written to %s", dir)
+}
+
+// collectDiagnostics copies the diagnostics files of the host to dir, with the secrets given to the script redacted.
+// Errors are only returned when nothing can be collected, missing files are skipped.
+func (s *linuxInstallerTestSuite) collectDiagnostics(dir string) error {
+	vm := s.host()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	paths := []string{"/etc/datadog-agent"}
+	if s.baseName != "" && s.baseName != "datadog-agent" {
+		paths = append(paths, "/etc/"+s.baseName)
+	}
+	for _, file := range diagnosticsFiles {
+		if !filepath.IsAbs(file) {
+			file = "$HOME/" + file
+		}
+		paths = append(paths, file)
+	}
+	// the package manager may be unknown on a failed test, a missing piece of diagnostics is not worth another failure
+	pkgManager, err := detectPackageManager(vm)
+	if err == nil {
+		repoFiles, err := pkgManager.repoFiles()
+		if err != nil {
+			s.T().Logf("Failed to list repository files: %s", err)
+		}
+		paths = append(paths, repoFiles...)
+	}
+
+	// a single archive keeps the number of round trips to the host low, /etc/datadog-agent has hundreds of files
+	_, err = vm.Execute(fmt.Sprintf("sudo tar czf %[1]s --ignore-failed-read %[2]s 2>/dev/null; sudo chmod a+r %[1]s", diagnosticsArchive, strings.Join(paths, " ")))
+	if err != nil {
+		return err
+	}
+	archive, err := vm.ReadFile(diagnosticsArchive)
+	if err != nil {
+		return err
+	}
+	if err = extractDiagnostics(archive, dir, s.secrets); err != nil {
+		return err
+	}
+
+	svcManager, err := detectServiceManager(vm)
+	if err != nil {
+		s.T().Logf("Failed to collect service logs: %s", err)
+		return nil
+	}
+	journalDir := filepath.Join(dir, "journal")
+	if err = os.MkdirAll(journalDir, 0o755); err != nil {
+		return err
+	}
+	all := serviceFeatures{process: true, security: true, systemProbe: true, ddot: true, fipsProxy: true}
+	for _, service := range expectedServices(flavor, all) {
+		status, _ := svcManager.status(service)
+		logs, err := svcManager.logs(service)
+		if err != nil {
+			logs = fmt.Sprintf("failed to get %s logs: %s\n%s", svcManager.name(), err, logs)
+		}
+		content := redactSecrets([]byte(status+"\n"+logs), s.secrets)
+		if err = os.WriteFile(filepath.Join(journalDir, service+".log"), content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractDiagnostics extracts the regular files of a gzipped tar archive under dir, redacting the secrets
+func extractDiagnostics(archive []byte, dir string, secrets map[string]string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return fmt.Errorf("invalid diagnostics archive: %w", err)
+	}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid diagnostics archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !filepath.IsLocal(header.Name) {
+			continue
+		}
+		path := filepath.Join(dir, header.Name)
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(path, redactSecrets(content, secrets), 0o644); err != nil {
+			return err
+		}
+	}
+}
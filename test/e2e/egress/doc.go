@@ -0,0 +1,8 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package egress records the outbound requests of the install script through a local proxy, which terminates TLS
+// with a throwaway CA so that the method and path of https requests are recorded along with their host
+package egress
@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package egress
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	apiKey     = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	keysHost   = "keys.datadoghq.com"
+	intakeHost = "instrumentation-telemetry-intake.datadoghq.com"
+)
+
+// probeHosts are the hosts of the network health checks of report_installer_telemetry, whatever the site
+var probeHosts = []string{
+	"apt.datadoghq.com",
+	"gcr.io",
+	"install.datadoghq.com",
+	"public.ecr.aws",
+	"yum.datadoghq.com",
+}
+
+// failingInstall makes the install of the Agent packages fail, which reports the error from on_error
+var failingInstall = hermetic.WithRule(hermetic.Rule{Command: "apt-get", Args: `(^| )install( |$)`, Exit: 100})
+
+// hosts returns the sorted union of the given hosts
+func hosts(groups ...[]string) []string {
+	all := []string{}
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	sort.Strings(all)
+	return slices.Compact(all)
+}
+
+func TestEgress(t *testing.T) {
+	reporting := hosts([]string{keysHost, intakeHost}, probeHosts)
+	tests := []struct {
+		name    string
+		env     map[string]string
+		options []hermetic.Option
+		// hosts is the exact list of hosts the script contacts
+		hosts []string
+		// telemetry is the number of requests sent to the intake
+		telemetry int
+		// fails is set when the script exits with an error
+		fails bool
+	}{
+		{
+			name:      "telemetry enabled",
+			env:       map[string]string{"DD_API_KEY": apiKey},
+			hosts:     reporting,
+			telemetry: 3,
+		},
+		{
+			name:      "failed install",
+			env:       map[string]string{"DD_API_KEY": apiKey},
+			options:   []hermetic.Option{failingInstall},
+			hosts:     reporting,
+			telemetry: 3,
+			fails:     true,
+		},
+		{
+			name:  "telemetry disabled",
+			env:   map[string]string{"DD_API_KEY": apiKey, "DD_INSTRUMENTATION_TELEMETRY_ENABLED": "false"},
+			hosts: []string{keysHost},
+		},
+		{
+			name:    "failed install with telemetry disabled",
+			env:     map[string]string{"DD_API_KEY": apiKey, "DD_INSTRUMENTATION_TELEMETRY_ENABLED": "false"},
+			options: []hermetic.Option{failingInstall},
+			hosts:   []string{keysHost},
+			fails:   true,
+		},
+		{
+			name:  "ddog-gov.com",
+			env:   map[string]string{"DD_API_KEY": apiKey, "DD_SITE": "ddog-gov.com"},
+			hosts: []string{keysHost},
+		},
+		{
+			name:    "failed install on ddog-gov.com",
+			env:     map[string]string{"DD_API_KEY": apiKey, "DD_SITE": "ddog-gov.com"},
+			options: []hermetic.Option{failingInstall},
+			hosts:   []string{keysHost},
+			fails:   true,
+		},
+		{
+			name:    "no API key on an existing install",
+			options: []hermetic.Option{hermetic.WithFile("/etc/datadog-agent/datadog.yaml", "api_key: "+apiKey+"\n")},
+			hosts:   []string{keysHost},
+		},
+		{
+			name:  "no API key",
+			hosts: []string{},
+			fails: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := New(t)
+			h := hermetic.New(t, append(tt.options, hermetic.WithPassthrough(`https?://`))...)
+			env := proxy.Env()
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			result := h.Run(env)
+			assert.Equal(t, tt.fails, result.ExitCode != 0, result.Output)
+
+			require.Equal(t, tt.hosts, proxy.Hosts(), "hosts contacted\n%s", result.Output)
+			assert.Len(t, proxy.RequestsTo(intakeHost), tt.telemetry)
+			for _, request := range proxy.RequestsTo(intakeHost) {
+				assert.Equal(t, http.MethodPost, request.Method, request.String())
+				assert.Equal(t, "/api/v2/apmtelemetry", request.Path, request.String())
+			}
+			if tt.telemetry > 0 {
+				for _, host := range probeHosts {
+					requests := proxy.RequestsTo(host)
+					if assert.NotEmpty(t, requests, host) {
+						assert.Equal(t, http.MethodHead, requests[0].Method, requests[0].String())
+					}
+				}
+			} else {
+				// the proxy only sees the calls honoring https_proxy, the shims see them all
+				calls := append(result.CallsTo("curl"), result.CallsTo("wget")...)
+				for _, call := range calls {
+					for _, host := range append([]string{intakeHost}, probeHosts...) {
+						assert.NotContains(t, call.String(), host)
+					}
+				}
+			}
+			for _, request := range proxy.RequestsTo(keysHost) {
+				assert.Equal(t, http.MethodGet, request.Method, request.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package egress
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/internal/testca"
+	"github.com/stretchr/testify/require"
+)
+
+// Request is a request that went through the proxy
+type Request struct {
+	Method string
+	// Scheme is https for the requests tunneled with CONNECT, http otherwise
+	Scheme string
+	// Host is the host the request was sent to, without port
+	Host string
+	Path string
+	Body []byte
+}
+
+func (r Request) String() string {
+	return fmt.Sprintf("%s %s://%s%s", r.Method, r.Scheme, r.Host, r.Path)
+}
+
+// Proxy is a recording egress proxy. Every request gets an empty 200 answer, nothing leaves the machine.
+type Proxy struct {
+	t         testing.TB
+	authority *testca.Authority
+	caFile    string
+	server    *httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// New starts a proxy listening on 127.0.0.1 until the end of the test
+func New(t testing.TB) *Proxy {
+	t.Helper()
+	authority, err := testca.New("Datadog Test Egress Proxy")
+	require.NoError(t, err)
+	p := &Proxy{t: t, authority: authority}
+	p.caFile = filepath.Join(t.TempDir(), "egress-ca.pem")
+	require.NoError(t, os.WriteFile(p.caFile, authority.PEM(), 0644))
+	p.server = httptest.NewServer(http.HandlerFunc(p.handle))
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// URL returns the URL of the proxy, e.g. for https_proxy
+func (p *Proxy) URL() string {
+	return p.server.URL
+}
+
+// CAFile returns the path of the PEM certificate of the CA signing the intercepted hosts
+func (p *Proxy) CAFile() string {
+	return p.caFile
+}
+
+// Env returns the variables sending curl and wget through the proxy, trusting its CA
+func (p *Proxy) Env() map[string]string {
+	return map[string]string{
+		"http_proxy":     p.URL(),
+		"https_proxy":    p.URL(),
+		"HTTP_PROXY":     p.URL(),
+		"HTTPS_PROXY":    p.URL(),
+		"CURL_CA_BUNDLE": p.caFile,
+		"SSL_CERT_FILE":  p.caFile,
+	}
+}
+
+// Requests returns the requests received so far, in order
+func (p *Proxy) Requests() []Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Request{}, p.requests...)
+}
+
+// Hosts returns the sorted hosts contacted so far
+func (p *Proxy) Hosts() []string {
+	seen := map[string]bool{}
+	hosts := []string{}
+	for _, request := range p.Requests() {
+		if !seen[request.Host] {
+			seen[request.Host] = true
+			hosts = append(hosts, request.Host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// RequestsTo returns the requests sent to host
+func (p *Proxy) RequestsTo(host string) []Request {
+	var requests []Request
+	for _, request := range p.Requests() {
+		if request.Host == host {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+func (p *Proxy) record(scheme string, host string, r *http.Request) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, Request{Method: r.Method, Scheme: scheme, Host: host, Path: r.URL.RequestURI(), Body: body})
+	return nil
+}
+
+func (p *Proxy) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.intercept(w, r)
+		return
+	}
+	// plain http requests come with an absolute URL
+	if err := p.record("http", r.URL.Hostname(), r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// intercept terminates the TLS tunnel asked with CONNECT and answers the requests sent through it
+func (p *Proxy) intercept(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		p.t.Logf("egress proxy: failed to hijack the connection to %s: %s", host, err)
+		return
+	}
+	defer conn.Close()
+	if _, err = buffered.WriteString("HTTP/1.1 200 Connection established\r\n\r\n"); err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		return
+	}
+
+	tlsConn := tls.Server(conn, &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return p.authority.Certificate(host)
+		},
+	})
+	reader := bufio.NewReader(tlsConn)
+	for {
+		request, err := http.ReadRequest(reader)
+		if err != nil {
+			// the client closed the tunnel, or didn't trust the CA
+			return
+		}
+		if err = p.record("https", host, request); err != nil {
+			return
+		}
+		response := &http.Response{
+			StatusCode:    http.StatusOK,
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Request:       request,
+			Header:        http.Header{},
+			ContentLength: 0,
+			Body:          http.NoBody,
+			Close:         request.Close,
+		}
+		if err = response.Write(tlsConn); err != nil || request.Close {
+			return
+		}
+	}
+}
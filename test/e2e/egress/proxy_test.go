@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package egress
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRecordsRequests(t *testing.T) {
+	p := New(t)
+	proxyURL, err := url.Parse(p.URL())
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(p.authority.PEM()))
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+
+	response, err := client.Post("https://instrumentation-telemetry-intake.datadoghq.com/api/v2/apmtelemetry", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response, err = client.Head("http://apt.datadoghq.com/index.html")
+	require.NoError(t, err)
+	response.Body.Close()
+
+	requests := p.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "POST https://instrumentation-telemetry-intake.datadoghq.com/api/v2/apmtelemetry", requests[0].String())
+	assert.Equal(t, []byte(`{}`), requests[0].Body)
+	assert.Equal(t, "HEAD http://apt.datadoghq.com/index.html", requests[1].String())
+	assert.Equal(t, []string{"apt.datadoghq.com", "instrumentation-telemetry-intake.datadoghq.com"}, p.Hosts())
+	assert.Len(t, p.RequestsTo("apt.datadoghq.com"), 1)
+}
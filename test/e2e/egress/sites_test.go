@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package egress
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ssiPath = "/scripts/install-ssi.sh"
+	email   = "jane.doe@example.com"
+	// waitTimeout bounds the wait for each prompt of on_error
+	waitTimeout = 10 * time.Second
+)
+
+// siteCase is the endpoints the script derives from DD_SITE
+type siteCase struct {
+	site string
+	// intake is the host receiving the telemetry, empty when the site disables it
+	intake string
+	// report is the host receiving the failure reports, empty when the site disables the prompt
+	report string
+	// installer is the host serving install-ssi.sh
+	installer string
+}
+
+var siteCases = []siteCase{
+	{
+		site:      "datadoghq.com",
+		intake:    "instrumentation-telemetry-intake.datadoghq.com",
+		report:    "api.datadoghq.com",
+		installer: "install.datadoghq.com",
+	},
+	{
+		site:      "datadoghq.eu",
+		intake:    "instrumentation-telemetry-intake.datadoghq.eu",
+		report:    "api.datadoghq.eu",
+		installer: "install.datadoghq.com",
+	},
+	{
+		site:      "us3.datadoghq.com",
+		intake:    "instrumentation-telemetry-intake.us3.datadoghq.com",
+		report:    "api.us3.datadoghq.com",
+		installer: "install.datadoghq.com",
+	},
+	{
+		site:      "us5.datadoghq.com",
+		intake:    "instrumentation-telemetry-intake.us5.datadoghq.com",
+		report:    "api.us5.datadoghq.com",
+		installer: "install.datadoghq.com",
+	},
+	{
+		site:      "ap1.datadoghq.com",
+		intake:    "instrumentation-telemetry-intake.ap1.datadoghq.com",
+		report:    "api.ap1.datadoghq.com",
+		installer: "install.datadoghq.com",
+	},
+	{
+		site:      "ddog-gov.com",
+		installer: "install.datadoghq.com",
+	},
+	{
+		site:      "datad0g.com",
+		intake:    "instrumentation-telemetry-intake.datad0g.com",
+		report:    "api.datad0g.com",
+		installer: "install.datad0g.com",
+	},
+}
+
+// runOnSite runs the script through a new proxy with DD_SITE set to site
+func runOnSite(t *testing.T, site string, env map[string]string, options ...hermetic.Option) (*Proxy, *hermetic.Harness, map[string]string) {
+	proxy := New(t)
+	h := hermetic.New(t, append(options, hermetic.WithPassthrough(`https?://`))...)
+	runEnv := proxy.Env()
+	runEnv["DD_API_KEY"] = apiKey
+	runEnv["DD_SITE"] = site
+	for key, value := range env {
+		runEnv[key] = value
+	}
+	return proxy, h, runEnv
+}
+
+func TestSiteEndpoints(t *testing.T) {
+	for _, tt := range siteCases {
+		t.Run(tt.site, func(t *testing.T) {
+			proxy, h, env := runOnSite(t, tt.site, map[string]string{
+				"DD_OTELCOLLECTOR_ENABLED":       "true",
+				"DD_APM_INSTRUMENTATION_ENABLED": "host",
+			})
+			result := h.Run(env)
+			require.Equal(t, 0, result.ExitCode, result.Output)
+
+			expected := hosts([]string{keysHost, tt.installer})
+			if tt.intake != "" {
+				expected = hosts(expected, []string{tt.intake}, probeHosts)
+			}
+			// the failure report host is only contacted on failure
+			assert.Equal(t, expected, proxy.Hosts(), "hosts contacted\n%s", result.Output)
+
+			var downloads []string
+			for _, request := range proxy.Requests() {
+				if request.Method == http.MethodGet && request.Host != keysHost {
+					downloads = append(downloads, request.String())
+				}
+			}
+			assert.Equal(t, []string{"GET https://" + tt.installer + ssiPath}, downloads)
+
+			intake := proxy.RequestsTo(tt.intake)
+			if tt.intake == "" {
+				assert.Empty(t, intake)
+			} else {
+				assert.Len(t, intake, 3)
+				var traces *telemetry.Envelope
+				for _, request := range intake {
+					assert.Equal(t, "POST https://"+tt.intake+"/api/v2/apmtelemetry", request.String())
+					envelope, err := telemetry.ParseEnvelope(request.Body)
+					require.NoError(t, err, string(request.Body))
+					if envelope.RequestType == telemetry.RequestTypeTraces {
+						traces = envelope
+					}
+				}
+				require.NotNil(t, traces, "no trace sent to %s", tt.intake)
+				telemetry.AssertStageMeta(t, traces, "initialization", map[string]string{"site": tt.site})
+			}
+
+			config := h.ReadFile("/etc/datadog-agent/datadog.yaml")
+			assert.Contains(t, config, "\nsite: "+tt.site+"\n")
+			assert.NotContains(t, config, "# site:")
+			otelConfig := h.ReadFile("/etc/datadog-agent/otel-config.yaml")
+			assert.Contains(t, otelConfig, "site: "+tt.site+"\n")
+			assert.NotContains(t, otelConfig, "${env:DD_SITE}")
+
+			install, ok := result.FindCall("apt-get", "install", "datadog-agent")
+			if assert.True(t, ok, result.Transcript()) {
+				assert.Equal(t, tt.site, install.Env["DD_SITE"])
+			}
+		})
+	}
+}
+
+func TestSiteFailureReport(t *testing.T) {
+	for _, tt := range siteCases {
+		t.Run(tt.site, func(t *testing.T) {
+			proxy, h, env := runOnSite(t, tt.site, nil, failingInstall)
+			result := h.RunInTerminal(env, func(terminal *hermetic.Terminal) {
+				if tt.report == "" {
+					return
+				}
+				require.NoError(t, terminal.Expect(telemetry.ReportPrompt, waitTimeout), terminal.Output())
+				require.NoError(t, terminal.SendLine("y"))
+				require.NoError(t, terminal.Expect(telemetry.EmailPrompt, waitTimeout), terminal.Output())
+				require.NoError(t, terminal.SendLine(email))
+			})
+			assert.NotEqual(t, 0, result.ExitCode, result.Output)
+
+			if tt.report == "" {
+				assert.NotContains(t, result.Output, telemetry.ReportPrompt)
+				assert.Equal(t, []string{keysHost}, proxy.Hosts(), "hosts contacted\n%s", result.Output)
+				return
+			}
+			reports := proxy.RequestsTo(tt.report)
+			require.Len(t, reports, 1, result.Output)
+			assert.Equal(t, http.MethodPost, reports[0].Method)
+			assert.Equal(t, telemetry.ReportFailurePath, reports[0].Path)
+			telemetry.AssertFailureReport(t, reports[0].Body, apiKey, email)
+			assert.Len(t, proxy.RequestsTo(tt.intake), 3)
+		})
+	}
+}
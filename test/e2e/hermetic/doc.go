@@ -0,0 +1,8 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package hermetic runs the install script in a temporary root with shims in place of the package managers,
+// init systems and network tools, so that platform specific branches can be unit tested without a VM
+package hermetic
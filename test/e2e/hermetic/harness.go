@@ -0,0 +1,468 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package hermetic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	runTimeout = 2 * time.Minute
+	// defaultInstallID is served by /proc/sys/kernel/random/uuid in the root
+	defaultInstallID = "0f8fad5b-d9cb-469f-a165-70867728950e"
+)
+
+// Option configures a Harness
+type Option func(*Harness)
+
+// WithOS sets the distribution seen by the script, Ubuntu 22.04 by default
+func WithOS(distribution OS) Option {
+	return func(h *Harness) { h.os = distribution }
+}
+
+// WithVariant selects the script generated from the template, install_script_agent7.sh by default
+func WithVariant(variant Variant) Option {
+	return func(h *Harness) { h.variant = variant }
+}
+
+// WithArch sets what `uname -m` reports, x86_64 by default
+func WithArch(arch string) Option {
+	return func(h *Harness) { h.arch = arch }
+}
+
+// WithInit sets the init system, systemd by default
+func WithInit(init Init) Option {
+	return func(h *Harness) { h.init = init }
+}
+
+// WithAvailableVersions sets the versions the package repository lists, e.g. "7.35.2-1"
+func WithAvailableVersions(versions ...string) Option {
+	return func(h *Harness) { h.versions = versions }
+}
+
+// WithInstalledPackages marks packages as installed before the first run, curl, gnupg and apt-transport-https are by default
+func WithInstalledPackages(packages ...string) Option {
+	return func(h *Harness) { h.installed = append(h.installed, packages...) }
+}
+
+// WithRule adds a scripted answer to a shim
+func WithRule(rule Rule) Option {
+	return func(h *Harness) { h.rules = append(h.rules, rule) }
+}
+
+// WithPostInstall runs a bash snippet each time the package managers install pkg, once its payload is unpacked, as
+// the maintainer scripts of the real package would. The snippet can read the environment of the install command and
+// "$root", a failure fails the install.
+func WithPostInstall(pkg string, script string) Option {
+	return func(h *Harness) { h.postInstall[pkg] = script }
+}
+
+// WithoutCommands removes shims and tools from the PATH of the script, e.g. curl for hosts that only have wget
+func WithoutCommands(names ...string) Option {
+	return func(h *Harness) { h.missing = append(h.missing, names...) }
+}
+
+// WithFile seeds a file in the root, path is the absolute path seen by the script
+func WithFile(path string, content string) Option {
+	return func(h *Harness) { h.files[path] = content }
+}
+
+// WithExecutable seeds an executable file in the root, e.g. a command the script only finds on some hosts
+func WithExecutable(path string, content string) Option {
+	return func(h *Harness) {
+		h.files[path] = content
+		h.executables = append(h.executables, path)
+	}
+}
+
+// WithoutFile removes a file seeded by default, such as /proc/sys/kernel/random/uuid
+func WithoutFile(path string) Option {
+	return func(h *Harness) { delete(h.files, path) }
+}
+
+// WithPassthrough sends the curl and wget calls whose arguments match the POSIX extended regular expression to
+// the real binaries, typically to reach a fake intake on 127.0.0.1
+func WithPassthrough(pattern string) Option {
+	return func(h *Harness) { h.passthrough = pattern }
+}
+
+// WithTemplate sets the path of install_script.sh.template, it is looked for in the parent folders by default
+func WithTemplate(path string) Option {
+	return func(h *Harness) { h.template = path }
+}
+
+// Harness runs the install script against a temporary root. The commands touching the host are replaced by shims
+// recording their calls, so that the script can run without root nor network.
+type Harness struct {
+	t           testing.TB
+	template    string
+	variant     Variant
+	os          OS
+	arch        string
+	init        Init
+	versions    []string
+	installed   []string
+	rules       []Rule
+	files       map[string]string
+	executables []string
+	postInstall map[string]string
+	missing     []string
+	passthrough string
+
+	dir   string
+	root  string
+	state string
+}
+
+// New creates the harness root, shims and seeded files
+func New(t testing.TB, options ...Option) *Harness {
+	t.Helper()
+	h := &Harness{
+		t:         t,
+		variant:   VariantAgent7,
+		os:        Ubuntu("22.04"),
+		arch:      "x86_64",
+		init:      InitSystemd,
+		installed: []string{"curl", "gnupg", "apt-transport-https"},
+		files: map[string]string{
+			"/proc/sys/kernel/random/uuid": defaultInstallID + "\n",
+			// present on every distribution, the Error Tracking settings are appended to it
+			"/etc/environment": "",
+		},
+		postInstall: map[string]string{},
+	}
+	for _, option := range options {
+		option(h)
+	}
+	if h.template == "" {
+		template, err := FindTemplate()
+		require.NoError(t, err)
+		h.template = template
+	}
+
+	h.dir = t.TempDir()
+	h.root = filepath.Join(h.dir, "root")
+	h.state = filepath.Join(h.dir, "state")
+	for _, dir := range []string{
+		filepath.Join(h.state, "calls"),
+		filepath.Join(h.state, "installed"),
+		filepath.Join(h.state, "active"),
+		h.Path("/usr/bin"),
+		h.Path("/tmp"),
+		h.Path("/root"),
+		h.Path("/etc/apt/sources.list.d"),
+		h.Path("/etc/apt/trusted.gpg.d"),
+		h.Path("/usr/share/keyrings"),
+		h.Path("/etc/yum.repos.d"),
+		h.Path("/etc/zypp/repos.d"),
+	} {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+	}
+	for _, pkg := range h.installed {
+		require.NoError(t, os.WriteFile(filepath.Join(h.state, "installed", pkg), nil, 0644))
+	}
+	for pkg, files := range defaultPayloads {
+		for path, content := range files {
+			h.writeFile(filepath.Join(h.state, "payloads", pkg, path), content, 0644)
+		}
+	}
+	for pkg, script := range h.postInstall {
+		h.writeFile(filepath.Join(h.state, "postinst", pkg), script, 0644)
+	}
+	for path, content := range h.os.Files {
+		h.writeFile(h.Path(path), content, 0644)
+	}
+	for path, content := range h.files {
+		perm := os.FileMode(0644)
+		if slices.Contains(h.executables, path) {
+			perm = 0755
+		}
+		h.writeFile(h.Path(path), content, perm)
+	}
+	if h.init == InitUpstart {
+		h.writeFile(h.Path("/sbin/init"), "#!/bin/sh\necho 'init (upstart 1.5)'\n", 0755)
+	}
+	h.installShims()
+	return h
+}
+
+// Path returns the location in the harness root of an absolute path seen by the script
+func (h *Harness) Path(path string) string {
+	return filepath.Join(h.root, path)
+}
+
+// ReadFile returns the content of a file written by the script, path is the absolute path seen by the script
+func (h *Harness) ReadFile(path string) string {
+	h.t.Helper()
+	content, err := os.ReadFile(h.Path(path))
+	require.NoError(h.t, err)
+	return string(content)
+}
+
+// FileExists tells whether the script left a file at path
+func (h *Harness) FileExists(path string) bool {
+	_, err := os.Stat(h.Path(path))
+	return err == nil
+}
+
+// Installed tells whether a package is installed, either seeded or installed by a previous run
+func (h *Harness) Installed(pkg string) bool {
+	_, err := os.Stat(filepath.Join(h.state, "installed", pkg))
+	return err == nil
+}
+
+// Active tells whether a service was left started
+func (h *Harness) Active(service string) bool {
+	_, err := os.Stat(filepath.Join(h.state, "active", service))
+	return err == nil
+}
+
+// Run executes the script with the given environment. The root is kept between runs, so that upgrades and
+// reinstalls can be exercised, while the calls are recorded per run.
+func (h *Harness) Run(env map[string]string) *Result {
+	h.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+	return h.run(ctx, h.command(ctx, h.script(), env))
+}
+
+// RunVariant executes another variant of the script against the same root, e.g. to switch a host from the Agent 6
+// script to the Agent 7 one
+func (h *Harness) RunVariant(variant Variant, env map[string]string) *Result {
+	h.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+	return h.run(ctx, h.command(ctx, h.render(variant), env))
+}
+
+// RunFunctions runs a bash snippet after the definitions of the given functions of the script, with the same
+// shims and root as Run. It reaches branches the whole script can't, e.g. the wget fallbacks that only run on hosts
+// without curl, while the script needs curl to download the repository keys.
+func (h *Harness) RunFunctions(env map[string]string, snippet string, functions ...string) *Result {
+	h.t.Helper()
+	definitions, err := Functions(h.script(), functions...)
+	require.NoError(h.t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+	return h.run(ctx, h.command(ctx, definitions+"\n"+Rebase(snippet, h.root), env))
+}
+
+// run runs cmd with its output collected in the result
+func (h *Harness) run(ctx context.Context, cmd *exec.Cmd) *Result {
+	h.t.Helper()
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	return h.result(ctx, output.String(), err)
+}
+
+// script returns the script of the variant, with its paths moved under the root
+func (h *Harness) script() string {
+	h.t.Helper()
+	return h.render(h.variant)
+}
+
+// render returns the script of a variant, with its paths moved under the root
+func (h *Harness) render(variant Variant) string {
+	h.t.Helper()
+	template, err := os.ReadFile(h.template)
+	require.NoError(h.t, err)
+	script, err := Render(string(template), variant)
+	require.NoError(h.t, err)
+	return Rebase(script, h.root)
+}
+
+// command prepares the run of script, the caller sets its input and output
+func (h *Harness) command(ctx context.Context, script string, env map[string]string) *exec.Cmd {
+	h.t.Helper()
+	calls := filepath.Join(h.state, "calls")
+	require.NoError(h.t, os.RemoveAll(calls))
+	require.NoError(h.t, os.MkdirAll(calls, 0755))
+
+	// Same invocation as the documented `bash -c "$(curl -L .../install_script_agent7.sh)"`
+	cmd := exec.CommandContext(ctx, h.Path("/usr/bin/bash"), "-c", script)
+	cmd.Dir = h.Path("/root")
+	// Background jobs such as the log tee keep the output open after the script is killed
+	cmd.WaitDelay = 10 * time.Second
+	cmd.Env = []string{
+		"PATH=" + h.Path("/usr/bin"),
+		"HOME=" + h.Path("/root"),
+		"TMPDIR=" + h.Path("/tmp"),
+		"LANG=C",
+	}
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	return cmd
+}
+
+// result collects the outcome of a run, err is the error of the command
+func (h *Harness) result(ctx context.Context, output string, err error) *Result {
+	h.t.Helper()
+	result := &Result{Output: h.unroot(output)}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		require.NoError(h.t, err, output)
+	}
+	require.NoError(h.t, ctx.Err(), "install script timed out, output:\n%s", output)
+	result.Calls = h.readCalls()
+	result.Hooks = h.readHooks()
+	return result
+}
+
+func (h *Harness) installShims() {
+	h.t.Helper()
+	bin := h.Path("/usr/bin")
+	for _, tool := range passthroughTools {
+		if slices.Contains(h.missing, tool) {
+			continue
+		}
+		real, err := exec.LookPath(tool)
+		if err != nil {
+			continue
+		}
+		require.NoError(h.t, os.Symlink(real, filepath.Join(bin, tool)))
+	}
+	bash, err := exec.LookPath("bash")
+	require.NoError(h.t, err)
+
+	shims := append([]string{}, commonShims...)
+	shims = append(shims, shimsByFamily[h.os.Family]...)
+	shims = append(shims, shimsByInit[h.init]...)
+	if h.os.Description != "" {
+		shims = append(shims, "lsb_release")
+	}
+	initComm := "init"
+	if h.init == InitSystemd {
+		initComm = "systemd"
+	}
+	for _, name := range shims {
+		if slices.Contains(h.missing, name) {
+			continue
+		}
+		body := name
+		if name == "yum" && h.os.DNF {
+			name = "dnf"
+		}
+		data := shimData{
+			Bash:        bash,
+			Root:        h.root,
+			State:       h.state,
+			Arch:        h.arch,
+			Description: h.os.Description,
+			InitComm:    initComm,
+			Versions:    h.versions,
+			Body:        shimBodies[body],
+		}
+		for _, rule := range h.rules {
+			if rule.Command == body || rule.Command == name {
+				data.Rules = append(data.Rules, rule)
+			}
+		}
+		if (name == "curl" || name == "wget") && h.passthrough != "" {
+			if real, err := exec.LookPath(name); err == nil {
+				data.Passthrough = h.passthrough
+				data.Real = real
+			}
+		}
+		var shim bytes.Buffer
+		require.NoError(h.t, shimTemplate.Execute(&shim, data))
+		h.writeFile(filepath.Join(bin, name), shim.String(), 0755)
+	}
+	if h.os.DNF {
+		// yum is a symlink to dnf on modern Red Hat based distros, which makes the script pass --best
+		require.NoError(h.t, os.Symlink("dnf", filepath.Join(bin, "yum")))
+	}
+}
+
+func (h *Harness) readCalls() []Call {
+	h.t.Helper()
+	dir := filepath.Join(h.state, "calls")
+	entries, err := os.ReadDir(dir)
+	require.NoError(h.t, err)
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".args"); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	calls := make([]Call, 0, len(names))
+	for _, name := range names {
+		args, err := os.ReadFile(filepath.Join(dir, name+".args"))
+		require.NoError(h.t, err)
+		argv := strings.Split(strings.TrimSuffix(h.unroot(string(args)), "\x00"), "\x00")
+		call := Call{Command: argv[0], Args: argv[1:], Env: map[string]string{}}
+		if env, err := os.ReadFile(filepath.Join(dir, name+".env")); err == nil {
+			call.Env = h.parseEnv(env)
+		}
+		if stdin, err := os.ReadFile(filepath.Join(dir, name+".stdin")); err == nil {
+			call.Stdin = h.unroot(string(stdin))
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+func (h *Harness) readHooks() []Hook {
+	h.t.Helper()
+	dir := filepath.Join(h.state, "calls")
+	entries, err := os.ReadDir(dir)
+	require.NoError(h.t, err)
+	var hooks []Hook
+	for _, entry := range entries {
+		// <time>-<pid>-<command>.hook.<package>, the command is the name of the shim
+		call, pkg, ok := strings.Cut(entry.Name(), ".hook.")
+		if !ok {
+			continue
+		}
+		env, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(h.t, err)
+		command := strings.SplitN(call, "-", 3)[2]
+		hooks = append(hooks, Hook{Command: command, Package: pkg, Env: h.parseEnv(env)})
+	}
+	return hooks
+}
+
+// parseEnv decodes the output of env -0
+func (h *Harness) parseEnv(content []byte) map[string]string {
+	env := map[string]string{}
+	for _, variable := range strings.Split(h.unroot(string(content)), "\x00") {
+		if key, value, ok := strings.Cut(variable, "="); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// unroot turns harness root locations back into the paths seen by the script
+func (h *Harness) unroot(s string) string {
+	return strings.ReplaceAll(s, h.root, "")
+}
+
+func (h *Harness) writeFile(path string, content string, perm os.FileMode) {
+	h.t.Helper()
+	require.NoError(h.t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(h.t, os.WriteFile(path, []byte(content), perm))
+}
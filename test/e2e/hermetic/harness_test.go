@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package hermetic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var installEnv = map[string]string{
+	"DD_API_KEY": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+}
+
+func TestUbuntuInstall(t *testing.T) {
+	h := New(t)
+	result := h.Run(installEnv)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	install, ok := result.FindCall("apt-get", "install", "datadog-agent", "datadog-signing-keys")
+	require.True(t, ok, result.Transcript())
+	assert.Equal(t, "/tmp/policy-do-not-start-service-rc.d", install.Env["POLICYRCD"], "services must not be started by the package")
+	_, ok = result.FindCall("systemctl", "restart", "datadog-agent.service")
+	assert.True(t, ok, result.Transcript())
+
+	assert.Contains(t, h.ReadFile("/etc/apt/sources.list.d/datadog.list"), "https://apt.datadoghq.com/ stable 7")
+	assert.Contains(t, h.ReadFile("/etc/datadog-agent/datadog.yaml"), "api_key: aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	assert.True(t, h.Installed("datadog-agent"))
+	assert.True(t, h.Active("datadog-agent"))
+}
+
+func TestDebian7CapsAgentTo735(t *testing.T) {
+	h := New(t, WithOS(Debian("7")), WithAvailableVersions("7.34.0-1", "7.35.2-1", "7.36.1-1"))
+	result := h.Run(installEnv)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	assert.Contains(t, result.Output, "Datadog Agent 7.35 is the last supported version on Debian 7. Installing 7.35 now.")
+	_, ok := result.FindCall("apt-cache", "madison", "datadog-agent")
+	assert.True(t, ok, result.Transcript())
+	_, ok = result.FindCall("apt-get", "install", "datadog-agent=1:7.35.2-1")
+	assert.True(t, ok, result.Transcript())
+}
+
+func TestDebian7RejectsAgentAbove735(t *testing.T) {
+	h := New(t, WithOS(Debian("7")), WithAvailableVersions("7.36.1-1"))
+	env := map[string]string{"DD_AGENT_MINOR_VERSION": "36"}
+	for key, value := range installEnv {
+		env[key] = value
+	}
+	result := h.Run(env)
+
+	assert.Contains(t, result.Output, "Debian < 8 only supports Datadog Agent 7 up to 7.35.")
+	_, ok := result.FindCall("apt-get", "install")
+	assert.False(t, ok, result.Transcript())
+}
+
+func TestOpenSUSE13CapsAgentTo732(t *testing.T) {
+	h := New(t, WithOS(OpenSUSE("13.2")), WithAvailableVersions("7.32.4-1", "7.33.0-1"))
+	result := h.Run(installEnv)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	assert.Contains(t, result.Output, "Datadog Agent 7.32 is the last supported version on openSUSE 13")
+	_, ok := result.FindCall("zypper", "install", "datadog-agent-1:7.32.4-1")
+	assert.True(t, ok, result.Transcript())
+	assert.Contains(t, h.ReadFile("/etc/zypp/repos.d/datadog.repo"), "baseurl=https://yum.datadoghq.com/suse/stable/7/x86_64")
+}
+
+func TestArmv7lRejectsDatadogAgent(t *testing.T) {
+	h := New(t, WithArch("armv7l"))
+	result := h.Run(installEnv)
+
+	assert.Equal(t, 1, result.ExitCode, result.Output)
+	assert.Contains(t, result.Output, "The full Datadog Agent isn't available for your architecture (armv7l).")
+	assert.Empty(t, result.CallsTo("apt-get"), result.Transcript())
+	assert.False(t, h.Installed("datadog-agent"))
+}
+
+func TestRedHatUsesDNF(t *testing.T) {
+	h := New(t, WithOS(RedHat("9.4")))
+	result := h.Run(installEnv)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	_, ok := result.FindCall("yum", "install", "--best", "datadog-agent")
+	assert.True(t, ok, result.Transcript())
+}
+
+func TestPostInstall(t *testing.T) {
+	h := New(t, WithPostInstall("datadog-agent", `echo "$DD_SITE" > "$root/tmp/postinst-site"`))
+	env := map[string]string{"DD_SITE": "datadoghq.eu"}
+	for key, value := range installEnv {
+		env[key] = value
+	}
+	result := h.Run(env)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+	assert.Equal(t, "datadoghq.eu\n", h.ReadFile("/tmp/postinst-site"))
+	hook, ok := result.HookOf("datadog-agent")
+	require.True(t, ok)
+	assert.Equal(t, "apt-get", hook.Command)
+	assert.Equal(t, "datadoghq.eu", hook.Env["DD_SITE"])
+
+	failing := New(t, WithPostInstall("datadog-agent", "exit 3"))
+	result = failing.Run(installEnv)
+	assert.NotEqual(t, 0, result.ExitCode, result.Output)
+	assert.False(t, failing.Active("datadog-agent"))
+}
+
+func TestExecutable(t *testing.T) {
+	h := New(t, WithExecutable("/usr/bin/hello", "#!/bin/sh\necho \"hello $1\"\n"), WithFile("/usr/bin/readme", "#!/bin/sh\n"))
+	result := h.RunFunctions(nil, `hello world && readme`)
+	assert.Contains(t, result.Output, "hello world")
+	assert.Equal(t, 126, result.ExitCode, "seeded files are not executable")
+}
+
+func TestRunVariant(t *testing.T) {
+	h := New(t, WithVariant(VariantAgent6))
+	result := h.Run(installEnv)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+	assert.Contains(t, h.ReadFile("/etc/apt/sources.list.d/datadog.list"), "https://apt.datadoghq.com/ stable 6")
+
+	result = h.RunVariant(VariantAgent7, installEnv)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+	assert.Contains(t, result.Output, "Datadog Agent 7 install script")
+	assert.Contains(t, h.ReadFile("/etc/apt/sources.list.d/datadog.list"), "https://apt.datadoghq.com/ stable 7")
+	_, ok := result.FindCall("apt-get", "install", "datadog-agent")
+	assert.True(t, ok, result.Transcript())
+}
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package hermetic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Family selects the package manager the script finds on the host
+type Family string
+
+const (
+	FamilyDebian Family = "debian"
+	FamilyRedHat Family = "redhat"
+	FamilySUSE   Family = "suse"
+)
+
+// Init is the init system the script detects
+type Init string
+
+const (
+	InitSystemd Init = "systemd"
+	InitUpstart Init = "upstart"
+	InitSysV    Init = "sysv"
+)
+
+// OS describes the distribution seen by the script
+type OS struct {
+	Family Family
+	// Description is what `lsb_release -d` reports, lsb_release is missing when empty
+	Description string
+	// DNF installs dnf and makes yum a symlink to it, as on Red Hat 8 and later
+	DNF bool
+	// Files are seeded in the root, typically /etc/os-release and the distribution release file
+	Files map[string]string
+}
+
+func osRelease(name string, id string, version string) string {
+	return fmt.Sprintf("NAME=%q\nID=%s\nVERSION_ID=%q\nPRETTY_NAME=\"%s %s\"\n", name, id, version, name, version)
+}
+
+func majorAtLeast(version string, major int) bool {
+	v, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	return err == nil && v >= major
+}
+
+// Debian returns a Debian host of the given version, e.g. "7" or "12"
+func Debian(version string) OS {
+	return OS{
+		Family:      FamilyDebian,
+		Description: fmt.Sprintf("Debian GNU/Linux %s", version),
+		Files: map[string]string{
+			"/etc/os-release":     osRelease("Debian GNU/Linux", "debian", version),
+			"/etc/debian_version": version + "\n",
+		},
+	}
+}
+
+// Ubuntu returns an Ubuntu host of the given version, e.g. "22.04"
+func Ubuntu(version string) OS {
+	return OS{
+		Family:      FamilyDebian,
+		Description: fmt.Sprintf("Ubuntu %s LTS", version),
+		Files: map[string]string{
+			"/etc/os-release":     osRelease("Ubuntu", "ubuntu", version),
+			"/etc/debian_version": "bookworm/sid\n",
+		},
+	}
+}
+
+// RedHat returns a Red Hat Enterprise Linux host of the given version, e.g. "7.9" or "9.4"
+func RedHat(version string) OS {
+	release := fmt.Sprintf("Red Hat Enterprise Linux release %s\n", version)
+	return OS{
+		Family:      FamilyRedHat,
+		Description: strings.TrimSpace(release),
+		DNF:         majorAtLeast(version, 8),
+		Files: map[string]string{
+			"/etc/os-release":     osRelease("Red Hat Enterprise Linux", "rhel", version),
+			"/etc/redhat-release": release,
+		},
+	}
+}
+
+// CentOS returns a CentOS host of the given version, e.g. "6.10" or "7.9"
+func CentOS(version string) OS {
+	release := fmt.Sprintf("CentOS Linux release %s\n", version)
+	return OS{
+		Family:      FamilyRedHat,
+		Description: strings.TrimSpace(release),
+		DNF:         majorAtLeast(version, 8),
+		Files: map[string]string{
+			"/etc/os-release":     osRelease("CentOS Linux", "centos", version),
+			"/etc/redhat-release": release,
+		},
+	}
+}
+
+// AmazonLinux returns an Amazon Linux host of the given version, e.g. "2" or "2023". It has no lsb_release.
+func AmazonLinux(version string) OS {
+	return OS{
+		Family: FamilyRedHat,
+		DNF:    version != "2",
+		Files: map[string]string{
+			"/etc/os-release":     osRelease("Amazon Linux", "amzn", version),
+			"/etc/system-release": fmt.Sprintf("Amazon Linux release %s\n", version),
+		},
+	}
+}
+
+// OpenSUSE returns an openSUSE host of the given version, e.g. "13.2", "42.3" or "15.5"
+func OpenSUSE(version string) OS {
+	name, id := "openSUSE", "opensuse"
+	if majorAtLeast(version, 15) {
+		// Leap started at 42.1 then went back to 15.0
+		name, id = "openSUSE Leap", "opensuse-leap"
+	}
+	return OS{
+		Family:      FamilySUSE,
+		Description: fmt.Sprintf("%s %s", name, version),
+		Files: map[string]string{
+			"/etc/os-release": osRelease(name, id, version),
+		},
+	}
+}
+
+// SLES returns a SUSE Linux Enterprise Server host of the given version, e.g. "12.5" or "15.5"
+func SLES(version string) OS {
+	return OS{
+		Family:      FamilySUSE,
+		Description: fmt.Sprintf("SUSE Linux Enterprise Server %s", version),
+		Files: map[string]string{
+			"/etc/os-release": osRelease("SLES", "sles", version),
+		},
+	}
+}
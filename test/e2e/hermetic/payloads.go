@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package hermetic
+
+const (
+	datadogYAMLExample = `## Provides autodetected defaults, for kubernetes environments,
+## please see datadog.yaml.example for all supported options
+
+#########################
+## Basic Configuration ##
+#########################
+
+## @param api_key - string - required
+api_key:
+
+## @param app_key - string - optional
+# app_key:
+
+## @param site - string - optional - default: datadoghq.com
+# site: datadoghq.com
+
+## @param dd_url - string - optional - default: https://app.datadoghq.com
+# dd_url: https://app.datadoghq.com
+
+## @param hostname - string - optional - default: auto-detected
+# hostname: <HOSTNAME_NAME>
+
+## @param tags  - list of key:value elements - optional
+# tags:
+#   - team:infra
+#   - <TAG_KEY>:<TAG_VALUE>
+
+## @param env - string - optional
+# env: <environment name>
+
+## @param infrastructure_mode - string - optional - default: full
+# infrastructure_mode: full
+`
+
+	systemProbeYAMLExample = `## System Probe configuration
+# system_probe_config:
+  # enabled: false
+# network_config:
+  # enabled: false
+# service_monitoring_config:
+  # enabled: false
+# discovery:
+  # enabled: false
+# runtime_security_config:
+  # enabled: false
+`
+
+	securityAgentYAMLExample = `## Security Agent configuration
+# runtime_security_config:
+  # enabled: false
+# compliance_config:
+  # enabled: false
+`
+
+	otelConfigYAMLExample = `exporters:
+  datadog:
+    api:
+      key: ${env:DD_API_KEY}
+      site: ${env:DD_SITE}
+`
+
+	dogstatsdYAMLExample = `## @param api_key - string - required
+api_key:
+
+## @param site - string - optional - default: datadoghq.com
+# site: datadoghq.com
+`
+
+	fipsProxyCfgExample = `global
+    presetenv DD_FIPS_LOCAL_ADDRESS 127.0.0.1
+`
+)
+
+var (
+	agentPayload = map[string]string{
+		"/etc/datadog-agent/datadog.yaml.example":        datadogYAMLExample,
+		"/etc/datadog-agent/system-probe.yaml.example":   systemProbeYAMLExample,
+		"/etc/datadog-agent/security-agent.yaml.example": securityAgentYAMLExample,
+		"/etc/datadog-agent/otel-config.yaml.example":    otelConfigYAMLExample,
+		"/opt/datadog-agent/bin/agent/agent":             "#!/bin/sh\n",
+	}
+
+	// Files each package drops on the host when installed, the configuration steps of the script copy the examples
+	defaultPayloads = map[string]map[string]string{
+		"datadog-agent":      agentPayload,
+		"datadog-iot-agent":  agentPayload,
+		"datadog-fips-agent": agentPayload,
+		"datadog-dogstatsd": {
+			"/etc/datadog-dogstatsd/dogstatsd.yaml.example": dogstatsdYAMLExample,
+			"/opt/datadog-dogstatsd/bin/dogstatsd":          "#!/bin/sh\n",
+		},
+		"datadog-fips-proxy": {
+			"/etc/datadog-fips-proxy/datadog-fips-proxy.cfg.example": fipsProxyCfgExample,
+		},
+	}
+)
@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package hermetic
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Variant is one of the scripts the Makefile generates from install_script.sh.template
+type Variant string
+
+const (
+	VariantLegacy          Variant = "install_script.sh"
+	VariantAgent6          Variant = "install_script_agent6.sh"
+	VariantAgent7          Variant = "install_script_agent7.sh"
+	VariantDockerInjection Variant = "install_script_docker_injection.sh"
+
+	templateName = "install_script.sh.template"
+
+	deprecationMessage = `\n\ninstall_script.sh is deprecated. Please use one of\n\n` +
+		`* https://s3.amazonaws.com/dd-agent/scripts/install_script_agent6.sh to install Agent 6\n` +
+		`* https://s3.amazonaws.com/dd-agent/scripts/install_script_agent7.sh to install Agent 7\n`
+)
+
+var (
+	// Placeholder substitutions, kept in sync with the Makefile targets
+	substitutionsByVariant = map[Variant][]string{
+		VariantLegacy: {
+			"AGENT_MAJOR_VERSION_PLACEHOLDER", "6",
+			"INSTALL_SCRIPT_REPORT_VERSION_PLACEHOLDER", "Agent",
+			"INSTALL_INFO_VERSION_PLACEHOLDER", "",
+			"IS_LEGACY_SCRIPT_PLACEHOLDER", "true",
+			"DD_APM_INSTRUMENTATION_ENABLED_DOCKER_PLACEHOLDER", "",
+			"APM_TELEMETRY_SAFE_AGENT_VERSION_OVERRIDE_PLACEHOLDER", "",
+			"DEPRECATION_MESSAGE_PLACEHOLDER", `echo -e "\033[33m` + deprecationMessage + `\033[0m"`,
+		},
+		VariantAgent6: {
+			"AGENT_MAJOR_VERSION_PLACEHOLDER", "6",
+			"INSTALL_SCRIPT_REPORT_VERSION_PLACEHOLDER", "Agent 6",
+			"INSTALL_INFO_VERSION_PLACEHOLDER", "_agent6",
+			"IS_LEGACY_SCRIPT_PLACEHOLDER", "",
+			"DD_APM_INSTRUMENTATION_ENABLED_DOCKER_PLACEHOLDER", "",
+			"APM_TELEMETRY_SAFE_AGENT_VERSION_OVERRIDE_PLACEHOLDER", "",
+			"DEPRECATION_MESSAGE_PLACEHOLDER", "",
+		},
+		VariantAgent7: {
+			"AGENT_MAJOR_VERSION_PLACEHOLDER", "7",
+			"INSTALL_SCRIPT_REPORT_VERSION_PLACEHOLDER", "Agent 7",
+			"INSTALL_INFO_VERSION_PLACEHOLDER", "_agent7",
+			"IS_LEGACY_SCRIPT_PLACEHOLDER", "",
+			"DD_APM_INSTRUMENTATION_ENABLED_DOCKER_PLACEHOLDER", "",
+			"APM_TELEMETRY_SAFE_AGENT_VERSION_OVERRIDE_PLACEHOLDER", "",
+			"DEPRECATION_MESSAGE_PLACEHOLDER", "",
+		},
+		VariantDockerInjection: {
+			"AGENT_MAJOR_VERSION_PLACEHOLDER", "7",
+			"INSTALL_SCRIPT_REPORT_VERSION_PLACEHOLDER", "Docker Injection",
+			"INSTALL_INFO_VERSION_PLACEHOLDER", "_docker_injection",
+			"IS_LEGACY_SCRIPT_PLACEHOLDER", "",
+			"DD_APM_INSTRUMENTATION_ENABLED_DOCKER_PLACEHOLDER", `export DD_APM_INSTRUMENTATION_ENABLED="docker"`,
+			"APM_TELEMETRY_SAFE_AGENT_VERSION_OVERRIDE_PLACEHOLDER", "safe_agent_version=noagent_autoinstrumentation",
+			"DEPRECATION_MESSAGE_PLACEHOLDER", "",
+		},
+	}
+
+	// Absolute paths the script reads or writes, they are moved under the harness root. The leading
+	// character keeps URLs and sed expressions such as 's/^#...' untouched.
+	rootedPathRegexp = regexp.MustCompile(`([\s"'=(<>])(/(?:etc|tmp|opt|run|sbin|usr/share/keyrings|usr/bin/yum|usr/bin/dnf|proc/sys/kernel/random/uuid)(?:/|\b))`)
+)
+
+// Render applies the Makefile substitutions of variant to the template
+func Render(template string, variant Variant) (string, error) {
+	substitutions, ok := substitutionsByVariant[variant]
+	if !ok {
+		return "", fmt.Errorf("unknown variant %s", variant)
+	}
+	return strings.NewReplacer(substitutions...).Replace(template), nil
+}
+
+// Rebase moves the absolute paths used by a rendered script under root
+func Rebase(script string, root string) string {
+	script = rootedPathRegexp.ReplaceAllString(script, "${1}"+root+"${2}")
+	// "${APT_GPG_KEYS[@]/#//tmp/}" prefixes every key with /tmp/, the path follows the pattern separator
+	return strings.ReplaceAll(script, "/#//tmp/", "/#/"+root+"/tmp/")
+}
+
+// Functions returns the definitions of the named functions of script, in the order given. The functions of the
+// template start with `function <name>() {` and end with a closing brace in the first column.
+func Functions(script string, names ...string) (string, error) {
+	var definitions []string
+	for _, name := range names {
+		definition := regexp.MustCompile(`(?ms)^function ` + regexp.QuoteMeta(name) + `\(\) *\{\n.*?^\}\n`).FindString(script)
+		if definition == "" {
+			return "", fmt.Errorf("function %s not found", name)
+		}
+		definitions = append(definitions, definition)
+	}
+	return strings.Join(definitions, "\n"), nil
+}
+
+// FindTemplate looks for install_script.sh.template in the working directory and its parents
+func FindTemplate() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(dir, templateName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%s not found", templateName)
+		}
+		dir = parent
+	}
+}
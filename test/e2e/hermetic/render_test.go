@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package hermetic
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	path, err := FindTemplate()
+	require.NoError(t, err)
+	template, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	for variant := range substitutionsByVariant {
+		t.Run(string(variant), func(t *testing.T) {
+			script, err := Render(string(template), variant)
+			require.NoError(t, err)
+			assert.NotContains(t, script, "_PLACEHOLDER")
+		})
+	}
+
+	script, err := Render(string(template), VariantDockerInjection)
+	require.NoError(t, err)
+	assert.Contains(t, script, `export DD_APM_INSTRUMENTATION_ENABLED="docker"`)
+	assert.Contains(t, script, "variant=install_script_docker_injection\n")
+
+	_, err = Render(string(template), Variant("install_script_agent8.sh"))
+	assert.Error(t, err)
+}
+
+func TestRebase(t *testing.T) {
+	script := `etcdir="/etc/datadog-agent"
+npipe=/tmp/$$.tmp
+grep VERSION_ID < /etc/os-release
+$sudo_cmd cat "${APT_GPG_KEYS[@]/#//tmp/}"
+curl -o /dev/null https://keys.datadoghq.com/DATADOG_APT_KEY_CURRENT.public
+sed -i 's/^# hostname:.*$/hostname: $hostname/' $config_file
+if [ -f "/usr/bin/dnf" ] && [ ! -f "/usr/bin/yum" ]; then`
+
+	assert.Equal(t, `etcdir="/root/etc/datadog-agent"
+npipe=/root/tmp/$$.tmp
+grep VERSION_ID < /root/etc/os-release
+$sudo_cmd cat "${APT_GPG_KEYS[@]/#//root/tmp/}"
+curl -o /dev/null https://keys.datadoghq.com/DATADOG_APT_KEY_CURRENT.public
+sed -i 's/^# hostname:.*$/hostname: $hostname/' $config_file
+if [ -f "/root/usr/bin/dnf" ] && [ ! -f "/root/usr/bin/yum" ]; then`, Rebase(script, "/root"))
+}
+
+func TestFunctions(t *testing.T) {
+	script := `#!/bin/bash
+function first() {
+  if true; then
+    echo first
+  fi
+}
+
+function second() {
+  echo second
+}
+second
+`
+	definitions, err := Functions(script, "second", "first")
+	require.NoError(t, err)
+	assert.Equal(t, "function second() {\n  echo second\n}\n\nfunction first() {\n  if true; then\n    echo first\n  fi\n}\n", definitions)
+	_, err = Functions(script, "third")
+	assert.EqualError(t, err, "function third not found")
+}
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package hermetic
+
+import (
+	"slices"
+	"strings"
+)
+
+// Call is one invocation of a shim
+type Call struct {
+	Command string
+	Args    []string
+	Env     map[string]string
+	// Stdin is what the command read, only captured for bodies posted by curl and keys imported by gpg
+	Stdin string
+}
+
+// String returns the command line of the call
+func (c Call) String() string {
+	return strings.Join(append([]string{c.Command}, c.Args...), " ")
+}
+
+// HasArgs tells whether every given argument was passed to the call
+func (c Call) HasArgs(args ...string) bool {
+	for _, arg := range args {
+		if !slices.Contains(c.Args, arg) {
+			return false
+		}
+	}
+	return true
+}
+
+// Result is the outcome of one run of the script
+type Result struct {
+	ExitCode int
+	// Output is the combined stdout and stderr of the script
+	Output string
+	// Calls is the transcript of the shim invocations, in order
+	Calls []Call
+	// Hooks are the maintainer scripts run by the package managers, in order
+	Hooks []Hook
+}
+
+// Hook is a maintainer script run by a package manager shim for an installed package
+type Hook struct {
+	Command string
+	Package string
+	Env     map[string]string
+}
+
+// HookOf returns the first hook run for pkg
+func (r *Result) HookOf(pkg string) (Hook, bool) {
+	for _, hook := range r.Hooks {
+		if hook.Package == pkg {
+			return hook, true
+		}
+	}
+	return Hook{}, false
+}
+
+// CallsTo returns the calls made to command
+func (r *Result) CallsTo(command string) []Call {
+	var calls []Call
+	for _, call := range r.Calls {
+		if call.Command == command {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// FindCall returns the first call to command with all the given arguments
+func (r *Result) FindCall(command string, args ...string) (Call, bool) {
+	for _, call := range r.CallsTo(command) {
+		if call.HasArgs(args...) {
+			return call, true
+		}
+	}
+	return Call{}, false
+}
+
+// Transcript returns the command lines of all the calls, one per line
+func (r *Result) Transcript() string {
+	var lines []string
+	for _, call := range r.Calls {
+		lines = append(lines, call.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,299 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package hermetic
+
+import (
+	"strings"
+	"text/template"
+)
+
+// Rule scripts the answer of a shim. Rules are evaluated in order before the built-in behavior of the command.
+type Rule struct {
+	Command string
+	// Args is a POSIX extended regular expression matched against the space-joined arguments, empty matches any call
+	Args   string
+	Stdout string
+	Exit   int
+	// Script is a bash snippet run before exiting, it can read "$@", "$root" (the harness root) and "$state"
+	Script string
+}
+
+var (
+	// Real tools the script needs, they are linked next to the shims as PATH only contains the shims folder
+	passthroughTools = []string{
+		"awk", "base64", "basename", "bash", "cat", "chmod", "cp", "cut", "date", "dirname", "echo", "env", "expr", "false",
+		"find",
+		"grep", "head", "ln", "ls", "mkdir", "mknod", "mktemp", "mv", "od", "printf", "readlink", "rm", "sed", "seq", "sh",
+		"sort", "stat", "tail", "tar", "tee", "touch", "tr", "true", "tty", "uniq", "wc", "xargs",
+	}
+
+	commonShims = []string{"chown", "curl", "gpg", "groups", "ps", "service", "sleep", "sudo", "uname", "wget", "which"}
+
+	shimsByFamily = map[Family][]string{
+		FamilyDebian: {"apt", "apt-cache", "apt-get", "dpkg"},
+		FamilyRedHat: {"rpm", "yum"},
+		FamilySUSE:   {"rpm", "zypper"},
+	}
+
+	shimsByInit = map[Init][]string{
+		InitSystemd: {"systemctl"},
+		InitUpstart: {"start", "status", "stop"},
+	}
+
+	// Built-in behaviors, run when no rule matched
+	shimBodies = map[string]string{
+		"apt": `if [ "$1" = list ]; then
+  for pkg in "${@:2}"; do
+    case "$pkg" in -*) continue;; esac
+    installed "$pkg" && echo "$pkg/stable,now 1:7.0.0-1 amd64 [installed]"
+  done
+fi
+exit 0`,
+		"apt-cache": `if [ "$1" = madison ]; then
+  for v in "${versions[@]}"; do echo " $2 | 1:$v | https://apt.datadoghq.com stable/7 amd64 Packages"; done
+fi
+exit 0`,
+		"apt-get": `if [ "$1" = --version ]; then
+  echo "apt 2.6.1 (amd64)"
+  exit 0
+fi
+for pkg in $(packages install "$@"); do mark_installed "$pkg"; done
+for pkg in $(packages remove "$@") $(packages purge "$@"); do unmark_installed "$pkg"; done
+exit 0`,
+		"dpkg": `case "$1" in
+  -s|--status|-l)
+    if ! installed "$2"; then
+      echo "dpkg-query: package '$2' is not installed" >&2
+      exit 1
+    fi
+    printf 'Package: %s\nStatus: install ok installed\n' "$2";;
+esac
+exit 0`,
+		"yum": `if [[ " $* " == *" list "* ]]; then
+  if [[ " $* " == *" installed "* ]]; then
+    found=1
+    for pkg in $(packages installed "$@"); do
+      installed "$pkg" && echo "$pkg.$arch  1:7.0.0-1  @datadog" && found=0
+    done
+    exit $found
+  fi
+  for pkg in $(packages list "$@"); do
+    for v in "${versions[@]}"; do echo "$pkg.$arch  1:$v  datadog"; done
+  done
+  exit 0
+fi
+for pkg in $(packages install "$@"); do mark_installed "$pkg"; done
+for pkg in $(packages remove "$@") $(packages erase "$@"); do unmark_installed "$pkg"; done
+exit 0`,
+		"zypper": `if [[ " $* " == *" search "* ]]; then
+  if [[ " $* " == *" -i "* ]]; then
+    found=104
+    for pkg in $(packages -i "$@"); do
+      installed "$pkg" && echo "i | $pkg | package" && found=0
+    done
+    exit $found
+  fi
+  for pkg in $(packages -s "$@"); do
+    for v in "${versions[@]}"; do echo "v | $pkg | package | 1:$v | $arch | datadog"; done
+  done
+  exit 0
+fi
+for pkg in $(packages install "$@"); do mark_installed "$pkg"; done
+for pkg in $(packages remove "$@"); do unmark_installed "$pkg"; done
+exit 0`,
+		"rpm": `case "$1" in
+  -q)
+    for pkg in "${@:2}"; do
+      if ! installed "$pkg"; then
+        echo "package $pkg is not installed"
+        exit 1
+      fi
+      echo "$pkg"
+    done;;
+esac
+exit 0`,
+		"systemctl": `verb=
+units=()
+for a in "$@"; do
+  case "$a" in
+    -*) ;;
+    *) if [ -z "$verb" ]; then verb="$a"; else units+=("${a%.service}"); fi;;
+  esac
+done
+case "$verb" in
+  is-active)
+    for unit in "${units[@]}"; do
+      if [ ! -e "$state/active/$unit" ]; then
+        [[ " $* " == *" --quiet "* ]] || echo inactive
+        exit 3
+      fi
+    done
+    [[ " $* " == *" --quiet "* ]] || echo active;;
+  start|restart) for unit in "${units[@]}"; do touch "$state/active/$unit"; done;;
+  stop) for unit in "${units[@]}"; do rm -f "$state/active/$unit"; done;;
+  is-system-running) echo running;;
+esac
+exit 0`,
+		"service": `case "$2" in
+  start|restart) touch "$state/active/$1";;
+  stop) rm -f "$state/active/$1";;
+  status) [ -e "$state/active/$1" ] || exit 3;;
+esac
+exit 0`,
+		"start": `touch "$state/active/$1"
+echo "$1 start/running, process 4242"
+exit 0`,
+		"stop": `rm -f "$state/active/$1"
+echo "$1 stop/waiting"
+exit 0`,
+		"status": `if [ -e "$state/active/$1" ]; then echo "$1 start/running, process 4242"; else echo "$1 stop/waiting"; fi
+exit 0`,
+		"curl": `out=
+data=
+head=
+prev=
+for a in "$@"; do
+  case "$prev" in
+    -o|--output) out="$a";;
+    -d|--data|--data-binary) data="$a";;
+  esac
+  case "$a" in -I|--head) head=1;; esac
+  prev="$a"
+done
+if [ "$data" = "@-" ]; then cat > "$call.stdin"; fi
+if [ -n "$out" ] && [ "$out" != /dev/null ]; then echo "hermetic download" > "$out"; fi
+if [ -n "$head" ]; then printf 200; fi
+exit 0`,
+		"wget": `out=
+prev=
+for a in "$@"; do
+  [ "$prev" = -O ] && out="$a"
+  prev="$a"
+done
+if [ -n "$out" ] && [ "$out" != - ]; then echo "hermetic download" > "$out"; fi
+exit 0`,
+		"gpg": `[ -t 0 ] || cat > "$call.stdin"
+exit 0`,
+		"uname": `case "$1" in
+  -m) echo "$arch";;
+  -n) echo hermetic;;
+  -o) echo GNU/Linux;;
+  -r) echo 6.1.0-hermetic;;
+  -v) echo "#1 SMP PREEMPT_DYNAMIC hermetic";;
+  *) echo Linux;;
+esac
+exit 0`,
+		"lsb_release": `if [ "$1" = -d ]; then printf 'Description:\t%s\n' "$description"; fi
+exit 0`,
+		"sudo": `while [ $# -gt 0 ]; do
+  case "$1" in
+    -V|--version) echo "Sudo version 1.9.13p3"; exit 0;;
+    -u|-g) shift 2;;
+    -*) shift;;
+    *) break;;
+  esac
+done
+exec env "$@"`,
+		"ps":     `echo "$init_comm"`,
+		"which":  `command -v "$1"`,
+		"groups": `echo "$1 : $1"`,
+		"chown":  `exit 0`,
+		"sleep":  `exit 0`,
+	}
+
+	shimTemplate = template.Must(template.New("shim").Funcs(template.FuncMap{"quote": shellQuote}).Parse(`#!{{.Bash}}
+# Generated by the hermetic harness: records the call, then answers from the rules or the built-in behavior
+name="${0##*/}"
+root={{quote .Root}}
+state={{quote .State}}
+arch={{quote .Arch}}
+description={{quote .Description}}
+init_comm={{quote .InitComm}}
+versions=({{range .Versions}}{{quote .}} {{end}})
+call="$state/calls/$(date +%s%N)-$$-$name"
+printf '%s\0' "$name" "$@" > "$call.args"
+env -0 > "$call.env"
+args="$*"
+
+installed() {
+  [ -e "$state/installed/$1" ]
+}
+
+# mark_installed records a package and its name without version, unpacks its payload in the root and runs its
+# post install snippet, a failing snippet fails the command. The environment of the maintainer scripts is recorded.
+mark_installed() {
+  local base="${1%%=*}"
+  base="$(echo "$base" | sed -E 's/-[0-9]+:?[0-9].*$//')"
+  touch "$state/installed/$1" "$state/installed/$base"
+  env -0 > "$call.hook.$base"
+  if [ -d "$state/payloads/$base" ]; then
+    cp -r "$state/payloads/$base/." "$root/"
+  fi
+  if [ -f "$state/postinst/$base" ]; then
+    root="$root" bash "$state/postinst/$base" || exit $?
+  fi
+}
+
+unmark_installed() {
+  rm -f "$state/installed/$1"
+}
+
+# packages prints the arguments following the verb given as first argument, without options
+packages() {
+  local verb="$1" seen= skip= a
+  shift
+  for a in "$@"; do
+    if [ -n "$skip" ]; then skip=; continue; fi
+    if [ -z "$seen" ]; then
+      [ "$a" = "$verb" ] && seen=1
+      continue
+    fi
+    case "$a" in
+      -o) skip=1;;
+      -*) ;;
+      *) echo "$a";;
+    esac
+  done
+}
+{{range .Rules}}
+re={{quote .Args}}
+if [[ $args =~ $re ]]; then
+{{- if .Stdout}}
+  printf '%s' {{quote .Stdout}}
+{{- end}}
+{{- if .Script}}
+  {{.Script}}
+{{- end}}
+  exit {{.Exit}}
+fi
+{{end}}
+{{- if .Passthrough}}
+re={{quote .Passthrough}}
+if [[ $args =~ $re ]]; then
+  exec {{quote .Real}} "$@"
+fi
+{{end}}
+{{.Body}}
+`))
+)
+
+type shimData struct {
+	Bash        string
+	Root        string
+	State       string
+	Arch        string
+	Description string
+	InitComm    string
+	Versions    []string
+	Rules       []Rule
+	Passthrough string
+	Real        string
+	Body        string
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package hermetic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+// eof is the end-of-file character of the terminal, Ctrl-D
+const eof = "\x04"
+
+// Terminal is the controlling terminal of a script run by RunInTerminal. It plays the user answering the prompts of
+// on_error, which are only shown when the input of the script is a terminal.
+type Terminal struct {
+	master *os.File
+
+	mu     sync.Mutex
+	output bytes.Buffer
+	// consumed is the length of the output matched by Expect so far
+	consumed int
+	closed   bool
+	updated  chan struct{}
+}
+
+// openTerminal allocates a pseudo terminal, the slave end is given to the script
+func openTerminal() (*Terminal, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	var number uint32
+	unlock := int32(0)
+	var ioctlErr error
+	// SyscallConn keeps the master in non-blocking mode, so that Close interrupts a pending Read
+	conn, err := master.SyscallConn()
+	if err == nil {
+		err = conn.Control(func(fd uintptr) {
+			if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+				ioctlErr = fmt.Errorf("failed to unlock the terminal: %w", errno)
+				return
+			}
+			if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&number))); errno != 0 {
+				ioctlErr = fmt.Errorf("failed to get the terminal number: %w", errno)
+			}
+		})
+	}
+	if err == nil {
+		err = ioctlErr
+	}
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", number), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return &Terminal{master: master, updated: make(chan struct{}, 1)}, slave, nil
+}
+
+// copyOutput reads what the script writes to the terminal until it is closed
+func (t *Terminal) copyOutput() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := t.master.Read(buf)
+		t.mu.Lock()
+		// the terminal turns \n into \r\n, a chunk may end between the two
+		t.output.WriteString(strings.ReplaceAll(string(buf[:n]), "\r", ""))
+		if err != nil {
+			// reads fail with EIO once the script and its children closed the slave end
+			t.closed = true
+		}
+		t.mu.Unlock()
+		select {
+		case t.updated <- struct{}{}:
+		default:
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Output returns what the script wrote to the terminal so far
+func (t *Terminal) Output() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.output.String()
+}
+
+// Expect waits until the script writes text after the last expected one
+func (t *Terminal) Expect(text string, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		t.mu.Lock()
+		index := strings.Index(t.output.String()[t.consumed:], text)
+		if index >= 0 {
+			t.consumed += index + len(text)
+		}
+		closed := t.closed
+		t.mu.Unlock()
+		if index >= 0 {
+			return nil
+		}
+		if closed {
+			return fmt.Errorf("terminal closed before %q was written", text)
+		}
+		select {
+		case <-t.updated:
+		case <-deadline:
+			return fmt.Errorf("%q not written after %s", text, timeout)
+		}
+	}
+}
+
+// SendLine types the line followed by Enter
+func (t *Terminal) SendLine(line string) error {
+	_, err := t.master.WriteString(line + "\n")
+	return err
+}
+
+// SendEOF types Ctrl-D, `read` then fails as when its timeout expires
+func (t *Terminal) SendEOF() error {
+	_, err := t.master.WriteString(eof)
+	return err
+}
+
+// RunInTerminal executes the script like Run, with a terminal as input and output. interact is called once the
+// script is started, to answer its prompts, the result is collected once the script exits.
+func (h *Harness) RunInTerminal(env map[string]string, interact func(*Terminal)) *Result {
+	h.t.Helper()
+	terminal, slave, err := openTerminal()
+	require.NoError(h.t, err)
+	defer terminal.master.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+	cmd := h.command(ctx, h.script(), env)
+	// The terminal is not made the controlling terminal of the script: as session leader, the script would have the
+	// kernel hang up its log tee when exiting, while a user's shell outlives the script
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	err = cmd.Start()
+	slave.Close()
+	require.NoError(h.t, err)
+
+	done := make(chan struct{})
+	go func() {
+		terminal.copyOutput()
+		close(done)
+	}()
+	interact(terminal)
+	err = cmd.Wait()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	return h.result(ctx, terminal.Output(), err)
+}
@@ -10,7 +10,6 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,14 +27,14 @@ func TestInstallComplianceAgentSuite(t *testing.T) {
 		testSuite := &installComplianceAgentTestSuite{}
 		e2e.Run(t,
 			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
+			testSuite.provisionerOption(t),
 			e2e.WithStackName(stackName),
 		)
 	})
 }
 
 func (s *installComplianceAgentTestSuite) TestInstallComplianceAgent() {
-	s.InstallAgent(7, "DD_COMPLIANCE_CONFIG_ENABLED=true DD_SITE=\"datadoghq.com\"", "Install latest Agent 7")
+	s.InstallAgent(InstallOptions{ComplianceConfigEnabled: true, Site: "datadoghq.com"})
 
 	s.assertInstallScript()
 
@@ -54,7 +53,7 @@ func (s *installComplianceAgentTestSuite) assertInstallScript() {
 	s.linuxInstallerTestSuite.assertInstallScript(true)
 
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 
 	t.Log("Assert fips config is not created")
 	assertFileNotExists(t, vm, fipsConfigFilepath)
@@ -65,15 +64,15 @@ func (s *installComplianceAgentTestSuite) assertInstallScript() {
 	t.Log("Assert security-agent is created")
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
 
-	securityAgentConfig := unmarshalConfigFile(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
-	assert.Equal(t, true, securityAgentConfig["compliance_config"].(map[any]any)["enabled"], fmt.Sprintf("compliance_config should be enabled, raw config content:\n%v\n\n", securityAgentConfig))
-	assert.NotContains(t, securityAgentConfig, "runtime_security_config")
+	securityAgentConfig := s.loadSecurityAgentConfig("compliance_config.enabled")
+	assert.True(t, securityAgentConfig.ComplianceConfig.Enabled, "compliance_config should be enabled")
+	assert.False(t, securityAgentConfig.Has("runtime_security_config"))
 }
 
 func (s *installComplianceAgentTestSuite) assertUninstall() {
 	s.linuxInstallerTestSuite.assertUninstall()
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Log("Assert security-agent is there after uninstall")
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
 }
@@ -84,7 +83,7 @@ func (s *installComplianceAgentTestSuite) assertPurge() {
 	}
 	s.linuxInstallerTestSuite.assertPurge()
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Log("Assert security-agent is removed after purge")
 	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
 }
@@ -1,83 +0,0 @@
-// Unless explicitly stated otherwise all files in this repository are licensed
-// under the Apache License Version 2.0.
-// This product includes software developed at Datadog (https://www.datadoghq.com/).
-// Copyright 2016-present Datadog, Inc.
-
-package e2e
-
-import (
-	"fmt"
-	"testing"
-
-	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
-	"github.com/stretchr/testify/assert"
-)
-
-type installDiscoveryTestSuite struct {
-	linuxInstallerTestSuite
-}
-
-func TestInstallDiscoverySuite(t *testing.T) {
-	if flavor != agentFlavorDatadogAgent {
-		t.Skip("discovery test supports only datadog-agent flavor")
-	}
-	stackName := fmt.Sprintf("install-discovery-%s-%s-%s", flavor, platform, getenv("CI_PIPELINE_ID", "dev"))
-	t.Run(stackName, func(t *testing.T) {
-		t.Logf("We will install with system-probe %s with install script on %s", flavor, platform)
-		testSuite := &installDiscoveryTestSuite{}
-		e2e.Run(t,
-			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
-			e2e.WithStackName(stackName),
-		)
-	})
-}
-
-func (s *installDiscoveryTestSuite) TestInstallSystemProbe() {
-	s.InstallAgent(7, "DD_DISCOVERY_ENABLED=true DD_SITE=\"datadoghq.com\"", "Install latest Agent 7")
-
-	s.assertInstallScript()
-
-	s.addExtraIntegration()
-
-	s.uninstall()
-
-	s.assertUninstall()
-
-	s.purge()
-
-	s.assertPurge()
-}
-
-func (s *installDiscoveryTestSuite) assertInstallScript() {
-	s.linuxInstallerTestSuite.assertInstallScript(true)
-	t := s.T()
-	vm := s.Env().RemoteHost
-	t.Log("Assert system probe config is created and security-agent is not created")
-	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
-	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
-
-	systemProbeConfig := unmarshalConfigFile(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
-	assert.NotContains(t, systemProbeConfig, "runtime_security_config")
-	assert.Equal(t, true, systemProbeConfig["discovery"].(map[any]any)["enabled"])
-}
-
-func (s *installDiscoveryTestSuite) assertUninstall() {
-	s.linuxInstallerTestSuite.assertUninstall()
-	t := s.T()
-	vm := s.Env().RemoteHost
-	t.Log("Assert system probe is there after uninstall")
-	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
-}
-
-func (s *installDiscoveryTestSuite) assertPurge() {
-	if s.shouldSkipPurge() {
-		return
-	}
-	s.linuxInstallerTestSuite.assertPurge()
-	t := s.T()
-	vm := s.Env().RemoteHost
-	t.Log("Assert system probe is removed after purge")
-	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
-}
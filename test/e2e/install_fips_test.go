@@ -7,11 +7,10 @@ package e2e
 
 import (
 	"fmt"
-	"regexp"
 	"testing"
 
+	"github.com/DataDog/agent-linux-install-script/test/e2e/installlog"
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -28,16 +27,18 @@ func TestInstallFipsSuite(t *testing.T) {
 	t.Run(stackName, func(t *testing.T) {
 		t.Logf("We will install with fips %s with install script on %s", flavor, platform)
 		testSuite := &installFipsTestSuite{}
+		// the script starts the proxy next to the Agent
+		testSuite.services = serviceFeatures{fipsProxy: true}
 		e2e.Run(t,
 			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
+			testSuite.provisionerOption(t),
 			e2e.WithStackName(stackName),
 		)
 	})
 }
 
 func (s *installFipsTestSuite) TestInstallFips() {
-	output := s.InstallAgent(7, "DD_FIPS_MODE=true DD_URL=\"fake.url.com\" DD_SITE=\"darth.vader.com\"", "Install latest Agent 7")
+	output := s.InstallAgent(InstallOptions{FIPSMode: true, URL: "fake.url.com", Site: "darth.vader.com"})
 
 	s.assertInstallFips(output)
 	s.addExtraIntegration()
@@ -49,40 +50,44 @@ func (s *installFipsTestSuite) TestInstallFips() {
 
 func (s *installFipsTestSuite) assertInstallFips(installCommandOutput string) {
 	t := s.T()
-	vm := s.Env().RemoteHost
 
 	s.assertInstallScript(true)
 
 	t.Log("assert install output contains expected lines")
-	matched, err := regexp.MatchString(`Installing\ package\(s\):\ .*\ datadog-fips-proxy.*`, installCommandOutput)
-	require.NoError(t, err, "error matching installer output for datadog-fips-proxy package")
-	assert.True(t, matched, "Missing installer log line for installing package(s)")
-	assert.Contains(t, installCommandOutput, "* Adding your API key to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml", "Missing installer log line for API key")
-	assert.Contains(t, installCommandOutput, "* Setting Datadog Agent configuration to use FIPS proxy: /etc/datadog-agent/datadog.yaml", "Missing installer log line for FIPS proxy")
+	steps := installlog.Parse(installCommandOutput)
+	assert.Contains(t, steps.Packages(), "datadog-fips-proxy", "Missing installer log line for installing package(s)")
+	assert.Equal(t, []string{
+		"Adding your API key to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
+		"Setting Datadog Agent configuration to use FIPS proxy: /etc/datadog-agent/datadog.yaml",
+	}, steps.OfKind(installlog.KindConfig).Texts())
 
 	t.Log("assert agent configuration contains expected properties")
-	config := unmarshalConfigFile(t, vm, fmt.Sprintf("etc/%s/%s", s.baseName, s.configFile))
-	assert.Contains(t, config, "fips")
-	fipsConfig, ok := config["fips"].(map[any]any)
-	assert.True(t, ok, fmt.Sprintf("failed parsing config[fips] to map \n%v\n\n", config["fips"]))
-	assert.Equal(t, true, fipsConfig["enabled"], fmt.Sprintf("fips config enabled should be true, content:\n%v\n\n", fipsConfig))
-	assert.Equal(t, 9803, fipsConfig["port_range_start"], fmt.Sprintf("fips config port_range_start should be 9803, content:\n%v\n\n", fipsConfig))
-	assert.Equal(t, false, fipsConfig["https"], fmt.Sprintf("fips config https should be false, content:\n%v\n\n", fipsConfig))
-	assert.Equal(t, apiKey, config["api_key"], fmt.Sprintf("not matching api key in config, content:\n%v\n\n", fipsConfig))
-	assert.NotContains(t, config, "site", fmt.Sprintf("site modified in config, content:\n%v\n\n", fipsConfig))
-	assert.NotContains(t, config, "dd_url", fmt.Sprintf("dd_url modified in config, content:\n%v\n\n", fipsConfig))
+	config := s.loadDatadogConfig("api_key", "fips.enabled", "fips.port_range_start", "fips.https")
+	assert.True(t, config.FIPS.Enabled, "fips config enabled should be true")
+	assert.Equal(t, 9803, config.FIPS.PortRangeStart, "fips config port_range_start should be 9803")
+	assert.False(t, config.FIPS.HTTPS, "fips config https should be false")
+	assert.Equal(t, apiKey, config.APIKey, "not matching api key in config")
+	assert.False(t, config.Has("site"), "site modified in config")
+	assert.False(t, config.Has("dd_url"), "dd_url modified in config")
 
-	assertFileExists(t, vm, fipsConfigFilepath)
+	t.Log("assert fips proxy configuration binds to the ports of the agent configuration")
+	fipsProxyConfig := s.loadFIPSProxyConfig()
+	ports, err := fipsProxyConfig.BindPorts()
+	require.NoError(t, err)
+	require.NotEmpty(t, ports, "fips proxy configuration has no frontend")
+	for _, port := range ports {
+		assert.GreaterOrEqual(t, port, config.FIPS.PortRangeStart, "fips proxy binds outside of the agent port range")
+	}
 }
 
 func (s *installFipsTestSuite) purgeFips() {
 	t := s.T()
-	vm := s.Env().RemoteHost
+	pkgManager := s.packageManager()
 	// Remove installed binary
-	if _, err := vm.Execute("command -v apt"); err != nil {
-		t.Log("Purge supported only with apt")
+	if !pkgManager.canPurge() {
+		t.Logf("Purge not supported with %s", pkgManager.name())
 		return
 	}
 	t.Log("Purge")
-	vm.Execute(fmt.Sprintf("sudo apt remove --purge -y %s datadog-fips-proxy", flavor))
+	assert.NoError(t, pkgManager.purge(string(flavor), "datadog-fips-proxy"))
 }
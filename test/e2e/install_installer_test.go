@@ -6,13 +6,13 @@
 package e2e
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/DataDog/agent-linux-install-script/test/e2e/mockinstaller"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/telemetry"
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -30,47 +30,66 @@ func TestInstallUpdaterSuite(t *testing.T) {
 		testSuite := &installUpdaterTestSuite{}
 		e2e.Run(t,
 			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
+			testSuite.provisionerOption(t),
 			e2e.WithStackName(stackName),
 		)
 	})
 }
 
-// mock installer, it will return 0 for datadog-apm-inject and datadog-apm-library-python
-const isInstalledScript = `#!/bin/bash
-[[ "$1" == "is-installed" ]] && { [[ "$2" == "datadog-apm-inject" || "$2" == "datadog-apm-library-python" ]] && exit 0 || exit 1; } || { echo "Unsupported command"; exit 2; }`
+// mockInstaller has the injector and the Python library, the package manager must not install them
+var mockInstaller = mockinstaller.Installer{
+	Packages: []mockinstaller.Package{
+		{Name: "datadog-apm-inject", Version: "0.99.0-1"},
+		{Name: "datadog-apm-library-python", Version: "3.99.0-1"},
+	},
+}
 
 func (s *installUpdaterTestSuite) TestPackagesInstalledByInstallerAreNotInstalledByPackageManager() {
 	t := s.T()
-	vm := s.Env().RemoteHost
-	if _, err := vm.Execute("command -v zypper"); err == nil {
+	vm := s.host()
+	if s.packageManager().name() == "zypper" {
 		t.Skip("zypper does not support apm packages")
 	}
 	vm.Execute("echo 'export PATH=/usr/local/bin:$PATH' | sudo tee -a /etc/profile")
-	vm.Execute("echo '" + isInstalledScript + "' | sudo tee /usr/local/bin/datadog-installer && sudo chmod +x /usr/local/bin/datadog-installer")
-	_, _ = vm.Execute("echo '" + isInstalledScript + "' | sudo tee /sbin/datadog-installer && sudo chmod +x /sbin/datadog-installer")
-	cmd := fmt.Sprintf("DD_INSTALLER=true DD_APM_INSTRUMENTATION_ENABLED=host DD_API_KEY=%s DD_SITE=\"datadoghq.com\" bash -c \"$(cat scripts/install_script_agent7.sh)\"", apiKey)
-	output := vm.MustExecute(cmd)
-	t.Log(output)
+	s.installMockInstaller(mockInstaller)
+	s.InstallAgent(InstallOptions{
+		Description:               "Install latest Agent 7 with APM instrumentation",
+		APMInstrumentationEnabled: "host",
+		Site:                      "datadoghq.com",
+		Extra:                     map[string]string{"DD_INSTALLER": "true"},
+	})
 	defer s.purge()
 
 	s.assertInstallScript(true)
-	s.assertPackageInstalledByPackageManager("datadog-agent")
-	s.assertPackageInstalledByPackageManager("datadog-apm-library-ruby")
-	s.assertPackageNotInstalledByPackageManager("datadog-apm-inject")
-	s.assertPackageNotInstalledByPackageManager("datadog-apm-library-python")
+	s.assertPackageInstalled("datadog-agent")
+	s.assertPackageNotInstalled("datadog-apm-inject")
+	s.assertPackageNotInstalled("datadog-apm-library-python")
+
+	calls := s.mockInstallerCalls(mockInstaller)
+	queried := mockinstaller.Queried(calls)
+	assert.Contains(t, queried, "datadog-apm-inject")
+	assert.Contains(t, queried, "datadog-apm-library-python")
+
+	// install-ssi.sh runs during configuration_setup, the installer spans must nest under it
+	rawTrace, err := vm.ReadFile("/tmp/datadog-installer-trace.json")
+	require.NoError(t, err)
+	trace, err := telemetry.ParseEnvelope(rawTrace)
+	require.NoError(t, err, string(rawTrace))
+	for _, call := range calls {
+		telemetry.AssertParentStage(t, trace, call.Env, "configuration_setup", "datadog-installer "+call.Subcommand())
+	}
 }
 
 func (s *installUpdaterTestSuite) TestInstallWithRemoteUpdates() {
-	t := s.T()
-	vm := s.Env().RemoteHost
 	s.optPathOverride = "/opt/datadog-packages/%s/stable" // override the path to use the latest version
 	defer func() {
 		s.optPathOverride = ""
 	}()
-	cmd := fmt.Sprintf("DD_REMOTE_UPDATES=true DD_API_KEY=%s DD_SITE=\"datadoghq.com\" bash -c \"$(cat scripts/install_script_agent7.sh)\"", apiKey)
-	output := vm.MustExecute(cmd)
-	t.Log(output)
+	s.InstallAgent(InstallOptions{
+		Description:   "Install latest Agent 7 with remote updates",
+		RemoteUpdates: true,
+		Site:          "datadoghq.com",
+	})
 	defer s.purge()
 
 	s.assertInstallScriptWithRemoteUpdates(true)
@@ -79,7 +98,7 @@ func (s *installUpdaterTestSuite) TestInstallWithRemoteUpdates() {
 
 func (s *installUpdaterTestSuite) assertInstallScriptWithRemoteUpdates(active bool) {
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Helper()
 	t.Log("Check user, config file and service")
 	// check presence of the dd-agent user
@@ -92,99 +111,40 @@ func (s *installUpdaterTestSuite) assertInstallScriptWithRemoteUpdates(active bo
 	owner := strings.TrimSuffix(vm.MustExecute(fmt.Sprintf("stat -c \"%%U\" /etc/%s/", s.baseName)), "\n")
 	assert.Equal(t, "dd-agent", owner, fmt.Sprintf("dd-agent does not own /etc/%s", s.baseName))
 
-	serviceNames := []string{s.baseName}
-	if flavor == agentFlavorDatadogAgent {
-		serviceNames = append(serviceNames, "datadog-agent-trace")
-		// Cannot assert process-agent because it may be running or dead based on timing
-	}
-
-	// Check that the services are active
-	if _, err = vm.Execute("command -v systemctl"); err == nil {
-		for _, serviceName := range serviceNames {
-			_, err = vm.Execute(fmt.Sprintf("systemctl is-active %s", serviceName))
-			if active {
-				assert.NoError(t, err, fmt.Sprintf("%s not running after Agent install", serviceName))
-			} else {
-				assert.Error(t, err, fmt.Sprintf("%s running after Agent install", serviceName))
-			}
-		}
-	}
-
-	if t.Failed() {
-		stdout, err := vm.Execute("sudo journalctl --no-pager")
-		if err != nil {
-			t.Logf("Failed to get journalctl logs: %s", err)
-		} else {
-			t.Logf("journalctl logs:\n%s", stdout)
-		}
-		stdout, err = vm.Execute("sudo systemctl status datadog*")
-		if err != nil {
-			t.Logf("Failed to get systemctl status: %s", err)
-		} else {
-			t.Logf("systemctl logs:\n%s", stdout)
-		}
-	}
+	s.assertServices(active, expectedServices(flavor, s.services)...)
 }
 
 func (s *installUpdaterTestSuite) purge() {
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Helper()
 	vm.Execute("sudo datadog-installer purge")
-	if _, err := vm.Execute("command -v apt"); err == nil {
-		t.Log("Uninstall with apt")
-		// remove all datadog packages
-		vm.Execute("sudo apt remove -y --purge 'datadog-*'")
-	} else if _, err = vm.Execute("command -v yum"); err == nil {
-		t.Log("Uninstall with yum")
-		vm.Execute("sudo yum remove -y 'datadog-*'")
-	} else if _, err = vm.Execute("command -v zypper"); err == nil {
-		t.Log("Uninstall with zypper")
-		vm.Execute("sudo zypper remove -y 'datadog-*'")
-	} else {
-		require.FailNow(t, "Unknown package manager")
-	}
-}
-
-func (s *installUpdaterTestSuite) assertPackageInstalledByPackageManager(pkg string) {
-	t := s.T()
-	vm := s.Env().RemoteHost
-
-	if _, err := vm.Execute("command -v apt"); err == nil {
-		vm.Execute("dpkg -l " + pkg + " | grep '^ii'")
-	} else if _, err = vm.Execute("command -v yum"); err == nil {
-		vm.Execute("yum list installed " + pkg)
-	} else if _, err = vm.Execute("command -v zypper"); err == nil {
-		vm.Execute("zypper se -i " + pkg)
-	} else {
-		require.FailNow(t, "Unknown package manager")
-	}
-}
-
-func (s *installUpdaterTestSuite) assertPackageNotInstalledByPackageManager(pkg string) {
-	t := s.T()
-	vm := s.Env().RemoteHost
-
-	if _, err := vm.Execute("command -v apt"); err == nil {
-		vm.Execute("! dpkg -l " + pkg + " | grep -q '^ii'")
-	} else if _, err = vm.Execute("command -v yum"); err == nil {
-		vm.Execute("! yum list installed " + pkg + " | grep -q " + pkg)
-	} else if _, err = vm.Execute("command -v zypper"); err == nil {
-		vm.Execute("! zypper se -i " + pkg + " | grep -q " + pkg)
-	} else {
-		require.FailNow(t, "Unknown package manager")
+	pkgManager := s.packageManager()
+	t.Logf("Uninstall with %s", pkgManager.name())
+	// remove all datadog packages, there may be none left after the installer purge
+	if err := pkgManager.purge("'datadog-*'"); err != nil {
+		t.Logf("Failed to remove datadog packages: %s", err)
 	}
 }
 
 func (s *installUpdaterTestSuite) assertValidTraceGenerated() {
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 
 	t.Log("Assert valid trace generated")
 	assertFileExists(t, vm, "/tmp/datadog-installer-trace.json")
 	rawTrace, err := vm.ReadFile("/tmp/datadog-installer-trace.json")
 	require.NoError(t, err)
-	if !json.Valid(rawTrace) {
-		t.Fatalf("Trace is not valid JSON: %s", string(rawTrace))
-	}
+	trace, err := telemetry.ParseEnvelope(rawTrace)
+	require.NoError(t, err, string(rawTrace))
+	telemetry.AssertTrace(t, trace, 0, telemetry.Stages...)
+	telemetry.AssertStageMeta(t, trace, "configuration_setup", map[string]string{"apm_enabled": "false"})
+
+	assertFileExists(t, vm, "/tmp/datadog-installer-log.json")
+	rawLogs, err := vm.ReadFile("/tmp/datadog-installer-log.json")
+	require.NoError(t, err)
+	logs, err := telemetry.ParseEnvelope(rawLogs)
+	require.NoError(t, err, string(rawLogs))
+	require.Len(t, logs.Payload.Logs, 1)
+	assert.Equal(t, trace.RuntimeID, logs.Payload.Logs[0].TraceID)
 }
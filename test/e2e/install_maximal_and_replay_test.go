@@ -9,8 +9,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/DataDog/agent-linux-install-script/test/e2e/installlog"
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,19 +18,18 @@ type installMaximalAndRetryTestSuite struct {
 	linuxInstallerTestSuite
 }
 
-var (
-	maximalInstallLogLines = []string{
-		"* Adding your API key to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
-		"* Setting SITE in the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
-		"* Setting DD_URL in the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
-		"* Adding your HOSTNAME to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
-		"* Adding your HOST TAGS to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
-		"* Adding your DD_ENV to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
-		"* Enabling runtime security in /etc/datadog-agent/security-agent.yaml configuration",
-		"* Enabling compliance monitoring in /etc/datadog-agent/security-agent.yaml configuration",
-		"* Enabling runtime security in /etc/datadog-agent/system-probe.yaml configuration",
-	}
-)
+// maximalConfigSteps are the configuration updates of the first install, the replay keeps the configuration
+var maximalConfigSteps = []string{
+	"Adding your API key to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
+	"Setting SITE in the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
+	"Setting DD_URL in the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
+	"Adding your HOSTNAME to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
+	"Adding your HOST TAGS to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
+	"Adding your DD_ENV to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml",
+	"Enabling runtime security in /etc/datadog-agent/security-agent.yaml configuration",
+	"Enabling compliance monitoring in /etc/datadog-agent/security-agent.yaml configuration",
+	"Enabling runtime security in /etc/datadog-agent/system-probe.yaml configuration",
+}
 
 // TestInstallMaximalAndRetrySuite tests agent 7 installer with a quite exaustive list of
 // environment variables. At first run variables will end up in agent configuration files, at
@@ -45,22 +44,47 @@ func TestInstallMaximalAndRetrySuite(t *testing.T) {
 		testSuite := &installMaximalAndRetryTestSuite{}
 		e2e.Run(t,
 			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
+			testSuite.provisionerOption(t),
 			e2e.WithStackName(stackName),
 		)
 	})
 }
 
 func (s *installMaximalAndRetryTestSuite) TestInstallMaximalAndReplayScript() {
-	output := s.InstallAgent(7, "DD_TAGS=\"foo:bar,baz:toto\" DD_ENV=kiki DD_HOSTNAME=totoro DD_RUNTIME_SECURITY_CONFIG_ENABLED=true DD_COMPLIANCE_CONFIG_ENABLED=true DD_SITE=\"mysite.com\" DD_URL=myintake.com", "install agent 7 with maximal environment variables")
+	t := s.T()
+	output := s.InstallAgent(InstallOptions{
+		Description:                  "install agent 7 with maximal environment variables",
+		Tags:                         "foo:bar,baz:toto",
+		Env:                          "kiki",
+		Hostname:                     "totoro",
+		RuntimeSecurityConfigEnabled: true,
+		ComplianceConfigEnabled:      true,
+		Site:                         "mysite.com",
+		URL:                          "myintake.com",
+	})
 
 	s.assertInstallMaximal(output)
 
 	s.addExtraIntegration()
 
-	output = s.InstallAgent(7, "DD_TAGS=\"john:doe,john:lennon\" DD_ENV=totoro DD_HOSTNAME=kiki DD_RUNTIME_SECURITY_CONFIG_ENABLED=true DD_COMPLIANCE_CONFIG_ENABLED=true DD_SITE=darthmaul.com DD_URL=otherintake.com", "install Agent 7 RC again with new environment variables")
+	// the configuration is kept, the new options change nothing
+	signature := s.installSignature()
+	replayOptions := InstallOptions{
+		Description:                  "install Agent 7 RC again with new environment variables",
+		Tags:                         "john:doe,john:lennon",
+		Env:                          "totoro",
+		Hostname:                     "kiki",
+		RuntimeSecurityConfigEnabled: true,
+		ComplianceConfigEnabled:      true,
+		Site:                         "darthmaul.com",
+		URL:                          "otherintake.com",
+	}
+	output, _ = s.replayInstall(replayOptions)
 
 	s.assertRetryInstall(output)
+	// agent installs don't reuse the identity of install.json, see installinfo.Reusable
+	assert.NotEqual(t, signature.InstallID, s.installSignature().InstallID, "install.json identity kept on replay")
+	s.assertInstallInfo(replayOptions)
 
 	s.uninstall()
 	s.assertUninstall()
@@ -71,12 +95,11 @@ func (s *installMaximalAndRetryTestSuite) TestInstallMaximalAndReplayScript() {
 
 func (s *installMaximalAndRetryTestSuite) assertInstallMaximal(installCommandOutput string) {
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Log("assert install output contains configuration changes")
-
-	for _, line := range maximalInstallLogLines {
-		assert.Contains(t, installCommandOutput, line)
-	}
+	steps := installlog.Parse(installCommandOutput)
+	assert.Equal(t, maximalConfigSteps, steps.OfKind(installlog.KindConfig).Texts())
+	assert.False(t, steps.Has(installlog.KindKeepConfig), "nothing to keep on first install")
 
 	s.assertInstallScript(true)
 
@@ -89,39 +112,35 @@ func (s *installMaximalAndRetryTestSuite) assertInstallMaximal(installCommandOut
 
 func (s *installMaximalAndRetryTestSuite) assertRetryInstall(installCommandOutput string) {
 	t := s.T()
-	vm := s.Env().RemoteHost
-	t.Log("assert install output contains configuration changes")
-
-	for _, line := range maximalInstallLogLines {
-		assert.NotContains(t, installCommandOutput, line)
-	}
+	vm := s.host()
+	t.Log("assert install output contains no configuration change")
+	steps := installlog.Parse(installCommandOutput)
+	assert.Empty(t, steps.OfKind(installlog.KindConfig).Texts(), "no configuration step on replay")
+	assert.Equal(t, []string{"/etc/datadog-agent/datadog.yaml"}, steps.OfKind(installlog.KindKeepConfig).Files())
 
 	assertFileNotExists(t, vm, fipsConfigFilepath)
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
 
-	assert.Contains(t, installCommandOutput, "* Keeping old /etc/datadog-agent/datadog.yaml configuration file")
-
 	t.Log("assert configuration did not change")
 	s.assertMaximalConfiguration()
 }
 
 func (s *installMaximalAndRetryTestSuite) assertMaximalConfiguration() {
 	t := s.T()
-	vm := s.Env().RemoteHost
 	t.Log("assert comfiguration contains expected properties")
-	config := unmarshalConfigFile(t, vm, fmt.Sprintf("etc/%s/%s", s.baseName, s.configFile))
-	assert.Equal(t, apiKey, config["api_key"], "not matching api key in config")
-	assert.Equal(t, "mysite.com", config["site"])
-	assert.Equal(t, "myintake.com", config["dd_url"])
-	assert.Equal(t, "totoro", config["hostname"])
-	assert.Equal(t, []any{"foo:bar", "baz:toto"}, config["tags"].([]any))
-	assert.Equal(t, "kiki", config["env"])
-
-	securityAgentConfig := unmarshalConfigFile(t, vm, fmt.Sprintf("etc/%s/%s", s.baseName, securityAgentConfigFileName))
-	assert.Equal(t, true, securityAgentConfig["runtime_security_config"].(map[any]any)["enabled"])
-	assert.Equal(t, true, securityAgentConfig["compliance_config"].(map[any]any)["enabled"])
-
-	systemProbeConfig := unmarshalConfigFile(t, vm, fmt.Sprintf("etc/%s/%s", s.baseName, systemProbeConfigFileName))
-	assert.Equal(t, true, systemProbeConfig["runtime_security_config"].(map[any]any)["enabled"])
+	config := s.loadDatadogConfig("api_key", "site", "dd_url", "hostname", "tags", "env")
+	assert.Equal(t, apiKey, config.APIKey, "not matching api key in config")
+	assert.Equal(t, "mysite.com", config.Site)
+	assert.Equal(t, "myintake.com", config.DDURL)
+	assert.Equal(t, "totoro", config.Hostname)
+	assert.Equal(t, []string{"foo:bar", "baz:toto"}, config.Tags)
+	assert.Equal(t, "kiki", config.Env)
+
+	securityAgentConfig := s.loadSecurityAgentConfig("runtime_security_config.enabled", "compliance_config.enabled")
+	assert.True(t, securityAgentConfig.RuntimeSecurityConfig.Enabled)
+	assert.True(t, securityAgentConfig.ComplianceConfig.Enabled)
+
+	systemProbeConfig := s.loadSystemProbeConfig("runtime_security_config.enabled")
+	assert.True(t, systemProbeConfig.RuntimeSecurityConfig.Enabled)
 }
@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/replay"
+)
+
+// installScenarios returns the scenarios run by TestInstallScenarios, built when the test runs so that they can
+// refer to flags such as the API key. Covering a new DD_* option is a new entry.
+func installScenarios() []installScenario {
+	agentOnly := []agentFlavor{agentFlavorDatadogAgent}
+	return []installScenario{
+		{
+			// `curl ... | bash` is documented too: bash reads the script from stdin, which the script must leave alone
+			name:        "pipe",
+			description: "the script piped to bash",
+			options:     InstallOptions{Invocation: invocationPipe, Site: "datadoghq.com"},
+			flavors:     agentOnly,
+		},
+		{
+			name:        "usm",
+			description: "Universal Service Monitoring",
+			options:     InstallOptions{SystemProbeServiceMonitoringEnabled: true, Site: "datadoghq.com"},
+			flavors:     agentOnly,
+			files:       []string{systemProbeConfigFileName},
+			absentFiles: []string{securityAgentConfigFileName},
+			systemProbeConfig: configExpectation{
+				values: map[string]any{"service_monitoring_config.enabled": true},
+				absent: []string{"runtime_security_config"},
+			},
+		},
+		{
+			name:        "discovery",
+			description: "service discovery",
+			options:     InstallOptions{DiscoveryEnabled: true, Site: "datadoghq.com"},
+			flavors:     agentOnly,
+			files:       []string{systemProbeConfigFileName},
+			absentFiles: []string{securityAgentConfigFileName},
+			systemProbeConfig: configExpectation{
+				values: map[string]any{"discovery.enabled": true},
+				absent: []string{"runtime_security_config"},
+			},
+		},
+		{
+			name:        "privileged-logs-enabled",
+			description: "privileged logs enabled",
+			options:     InstallOptions{PrivilegedLogsEnabled: boolPtr(true), Site: "datadoghq.com"},
+			flavors:     agentOnly,
+			files:       []string{systemProbeConfigFileName},
+			absentFiles: []string{securityAgentConfigFileName},
+			systemProbeConfig: configExpectation{
+				values: map[string]any{"privileged_logs.enabled": true},
+				absent: []string{"runtime_security_config", "discovery"},
+			},
+		},
+		{
+			name:        "privileged-logs-disabled",
+			description: "privileged logs explicitly disabled",
+			options:     InstallOptions{PrivilegedLogsEnabled: boolPtr(false), Site: "datadoghq.com"},
+			flavors:     agentOnly,
+			files:       []string{systemProbeConfigFileName},
+			absentFiles: []string{securityAgentConfigFileName},
+			systemProbeConfig: configExpectation{
+				values: map[string]any{"privileged_logs.enabled": false},
+				absent: []string{"runtime_security_config", "discovery"},
+			},
+		},
+		{
+			name:        "infra-mode",
+			description: "basic infrastructure mode",
+			options:     InstallOptions{InfrastructureMode: "basic"},
+			// basic mode changes the services that run
+			skipInstallAssertions: true,
+			datadogConfig: configExpectation{
+				values: map[string]any{"infrastructure_mode": "basic"},
+			},
+		},
+		{
+			name:        "ddot",
+			description: "DDOT, Agent 7.69.3",
+			options:     InstallOptions{OTelCollectorEnabled: true, Site: "datadoghq.com", AgentMinorVersion: "69.3-1"},
+			flavors:     agentOnly,
+			files:       []string{otelConfigFileName},
+			services:    serviceFeatures{ddot: true},
+			datadogConfig: configExpectation{
+				values: map[string]any{
+					"otelcollector.enabled":             true,
+					"agent_ipc.port":                    5009,
+					"agent_ipc.config_refresh_interval": 60,
+				},
+			},
+			otelConfig: configExpectation{
+				values: map[string]any{
+					"exporters.datadog.api.key":  apiKey,
+					"exporters.datadog.api.site": "datadoghq.com",
+				},
+			},
+		},
+		{
+			name:        "error-tracking-standalone",
+			description: "Error Tracking standalone",
+			options:     InstallOptions{APMErrorTrackingStandalone: true, URL: "fake.url.com", Site: "darth.vader.com"},
+			flavors:     agentOnly,
+			logLines:    []string{"* Setting Datadog Agent configuration to use Error Tracking backend: /etc/datadog-agent/datadog.yaml"},
+			datadogConfig: configExpectation{
+				values: map[string]any{
+					"apm_config.enabled":                           true,
+					"apm_config.error_tracking_standalone.enabled": true,
+					"enable_payloads.series":                       false,
+					"enable_payloads.events":                       false,
+					"enable_payloads.service_checks":               false,
+					"enable_payloads.sketches":                     false,
+				},
+			},
+			environment: map[string]string{
+				"DD_APM_ERROR_TRACKING_STANDALONE_ENABLED": "true",
+				"DD_CORE_AGENT_ENABLED":                    "false",
+			},
+		},
+		{
+			name:        "sbom",
+			description: "container image SBOM, host SBOM added on replay",
+			options:     InstallOptions{SBOMContainerImageEnabled: true, Site: "datadoghq.com"},
+			flavors:     agentOnly,
+			// the SBOM settings are applied even when datadog.yaml is kept
+			replayOptions: &InstallOptions{SBOMContainerImageEnabled: true, SBOMHostEnabled: true, Site: "datadoghq.com"},
+			replayChanges: replay.SBOM,
+		},
+		{
+			name:        "logs-collect-all",
+			description: "process logs collection",
+			options:     InstallOptions{LogsConfigProcessCollectAll: true, Site: "datadoghq.com"},
+			flavors:     agentOnly,
+			files:       []string{systemProbeConfigFileName},
+			datadogConfig: configExpectation{
+				values: map[string]any{
+					"logs_enabled":                              true,
+					"logs_config.process_exclude_agent":         true,
+					"logs_config.auto_multi_line_detection":     true,
+					"process_config.process_collection.use_wlm": true,
+					"extra_config_providers":                    []any{"process_log"},
+				},
+			},
+			// discovery is required by process logs collection, privileged logs are enabled by default
+			systemProbeConfig: configExpectation{
+				values: map[string]any{"discovery.enabled": true, "privileged_logs.enabled": true},
+			},
+		},
+		{
+			name:        "logs-collect-all-nopl",
+			description: "process logs collection and privileged logs explicitly disabled",
+			options:     InstallOptions{LogsConfigProcessCollectAll: true, PrivilegedLogsEnabled: boolPtr(false), Site: "datadoghq.com"},
+			flavors:     agentOnly,
+			files:       []string{systemProbeConfigFileName},
+			systemProbeConfig: configExpectation{
+				values: map[string]any{"discovery.enabled": true, "privileged_logs.enabled": false},
+			},
+		},
+	}
+}
+
+func TestInstallScenarios(t *testing.T) {
+	runInstallScenarios(t, installScenarios())
+}
@@ -10,7 +10,6 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,14 +27,14 @@ func TestInstallSecurityAgentSuite(t *testing.T) {
 		testSuite := &installSecurityAgentTestSuite{}
 		e2e.Run(t,
 			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
+			testSuite.provisionerOption(t),
 			e2e.WithStackName(stackName),
 		)
 	})
 }
 
 func (s *installSecurityAgentTestSuite) TestInstallSecurityAgent() {
-	s.InstallAgent(7, "DD_RUNTIME_SECURITY_CONFIG_ENABLED=true DD_SITE=\"datadoghq.com\"", "Install latest Agent 7 ")
+	s.InstallAgent(InstallOptions{RuntimeSecurityConfigEnabled: true, Site: "datadoghq.com"})
 
 	s.assertInstallScript()
 
@@ -54,7 +53,7 @@ func (s *installSecurityAgentTestSuite) assertInstallScript() {
 	s.linuxInstallerTestSuite.assertInstallScript(true)
 
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 
 	t.Log("Assert fips config is not created")
 	assertFileNotExists(t, vm, fipsConfigFilepath)
@@ -63,18 +62,18 @@ func (s *installSecurityAgentTestSuite) assertInstallScript() {
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
 
-	securityAgentConfig := unmarshalConfigFile(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
-	assert.Equal(t, true, securityAgentConfig["runtime_security_config"].(map[any]any)["enabled"])
-	assert.NotContains(t, securityAgentConfig, "compliance_config")
+	securityAgentConfig := s.loadSecurityAgentConfig("runtime_security_config.enabled")
+	assert.True(t, securityAgentConfig.RuntimeSecurityConfig.Enabled)
+	assert.False(t, securityAgentConfig.Has("compliance_config"))
 
-	systemProbeConfig := unmarshalConfigFile(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
-	assert.Equal(t, true, systemProbeConfig["runtime_security_config"].(map[any]any)["enabled"])
+	systemProbeConfig := s.loadSystemProbeConfig("runtime_security_config.enabled")
+	assert.True(t, systemProbeConfig.RuntimeSecurityConfig.Enabled)
 }
 
 func (s *installSecurityAgentTestSuite) assertUninstall() {
 	s.linuxInstallerTestSuite.assertUninstall()
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Log("Assert system probe config and security-agent are there after uninstall")
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
@@ -86,7 +85,7 @@ func (s *installSecurityAgentTestSuite) assertPurge() {
 	}
 	s.linuxInstallerTestSuite.assertPurge()
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Log("Assert system probe config and security-agent are removed after purge")
 	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
 	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
@@ -10,7 +10,6 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,14 +27,14 @@ func TestInstallSystemProbeSuite(t *testing.T) {
 		testSuite := &installSystemProbeTestSuite{}
 		e2e.Run(t,
 			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
+			testSuite.provisionerOption(t),
 			e2e.WithStackName(stackName),
 		)
 	})
 }
 
 func (s *installSystemProbeTestSuite) TestInstallSystemProbe() {
-	s.InstallAgent(7, "DD_SYSTEM_PROBE_ENSURE_CONFIG=true DD_SITE=\"datadoghq.com\"", "Install latest Agent 7")
+	s.InstallAgent(InstallOptions{SystemProbeEnsureConfig: true, Site: "datadoghq.com"})
 
 	s.assertInstallScript()
 
@@ -53,19 +52,19 @@ func (s *installSystemProbeTestSuite) TestInstallSystemProbe() {
 func (s *installSystemProbeTestSuite) assertInstallScript() {
 	s.linuxInstallerTestSuite.assertInstallScript(true)
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Log("Assert system probe config is created and security-agent is not created")
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
 	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
 
-	systemProbeConfig := unmarshalConfigFile(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
-	assert.NotContains(t, systemProbeConfig, "runtime_security_config")
+	systemProbeConfig := s.loadSystemProbeConfig()
+	assert.False(t, systemProbeConfig.Has("runtime_security_config"))
 }
 
 func (s *installSystemProbeTestSuite) assertUninstall() {
 	s.linuxInstallerTestSuite.assertUninstall()
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Log("Assert system probe is there after uninstall")
 	assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
 }
@@ -76,7 +75,7 @@ func (s *installSystemProbeTestSuite) assertPurge() {
 	}
 	s.linuxInstallerTestSuite.assertPurge()
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Log("Assert system probe is removed after purge")
 	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
 }
@@ -10,8 +10,8 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type installTestSuite struct {
@@ -25,14 +25,14 @@ func TestInstallSuite(t *testing.T) {
 		testSuite := &installTestSuite{}
 		e2e.Run(t,
 			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
+			testSuite.provisionerOption(t),
 			e2e.WithStackName(stackName),
 		)
 	})
 }
 
 func (s *installTestSuite) TestInstall() {
-	s.InstallAgent(7)
+	s.InstallAgent(InstallOptions{}.withCIRepository())
 	s.assertInstallScript(true)
 	s.addExtraIntegration()
 	s.uninstall()
@@ -42,8 +42,14 @@ func (s *installTestSuite) TestInstall() {
 }
 
 func (s *installTestSuite) TestInstallOnly() {
-	s.InstallAgent(7, "DD_INSTALL_ONLY=true", "Install Only")
+	s.InstallAgent(InstallOptions{Description: "Install Only", InstallOnly: true})
 	s.assertInstallScript(false)
+	// the Agent installed but not started runs once started by hand
+	svcManager := s.serviceManager()
+	require.NoError(s.T(), svcManager.start(s.baseName))
+	s.assertServices(true, s.baseName)
+	require.NoError(s.T(), svcManager.stop(s.baseName))
+	s.assertServices(false, s.baseName)
 	s.addExtraIntegration()
 	s.uninstall()
 	s.assertUninstall()
@@ -52,18 +58,27 @@ func (s *installTestSuite) TestInstallOnly() {
 }
 
 func (s *installTestSuite) TestInstallMinorVersionPin() {
-	s.InstallAgent(7, "DD_AGENT_MINOR_VERSION=42.0", "Install Agent 7 pinned to 7.42.0")
+	s.InstallAgent(InstallOptions{Description: "Install Agent 7 pinned to 7.42.0", AgentMinorVersion: "42.0"})
 	s.assertPinnedInstallScript("7.42.0")
 	s.uninstall()
 	s.purge()
 	s.assertPurge()
 }
 
+func (s *installTestSuite) TestInstallInvalidMajorVersion() {
+	t := s.T()
+	result := s.RunInstall(InstallOptions{Description: "Install Agent 8, which doesn't exist", AgentMajorVersion: 8})
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stdout, "DD_AGENT_MAJOR_VERSION must be either 6 or 7. Current value: 8")
+	assert.True(t, result.HasArtifact("ddagent-install.log"), "no install log in %v", result.Artifacts)
+	s.assertPackageNotInstalled(string(flavor))
+}
+
 func (s *installTestSuite) assertPinnedInstallScript(pinVersion string) {
 	s.linuxInstallerTestSuite.assertInstallScript(true)
 
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 
 	t.Log("Assert security agent, system probe and fips config are not created")
 	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
@@ -81,7 +96,7 @@ func (s *installTestSuite) assertInstallScript(active bool) {
 	s.linuxInstallerTestSuite.assertInstallScript(active)
 
 	t := s.T()
-	vm := s.Env().RemoteHost
+	vm := s.host()
 	t.Log("Assert security agent, system probe and fips config are not created")
 	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, securityAgentConfigFileName))
 	assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, systemProbeConfigFileName))
@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/installinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// installSignature returns the install.json of the host
+func (s *linuxInstallerTestSuite) installSignature() installinfo.Signature {
+	t := s.T()
+	t.Helper()
+	content, err := s.host().ReadFile(installinfo.SignaturePath)
+	require.NoError(t, err)
+	signature, err := installinfo.ParseSignature(string(content))
+	require.NoError(t, err, string(content))
+	return signature
+}
+
+// assertInstallInfo asserts that install_info names the script the options ran and its version
+func (s *linuxInstallerTestSuite) assertInstallInfo(options InstallOptions) {
+	t := s.T()
+	t.Helper()
+	options = options.withDefaults()
+	script, err := os.ReadFile(filepath.Join(scriptPath, string(options.Script)))
+	require.NoError(t, err)
+	version, err := installinfo.ScriptVersion(string(script))
+	require.NoError(t, err)
+
+	content, err := s.host().ReadFile(installinfo.InfoPath)
+	require.NoError(t, err)
+	info, err := installinfo.ParseInfo(string(content))
+	require.NoError(t, err, string(content))
+	assert.Equal(t, installinfo.Tool, info.InstallMethod.Tool)
+	assert.Equal(t, installinfo.ToolVersion(options.Script), info.InstallMethod.ToolVersion)
+	assert.Equal(t, installinfo.InstallerVersion(version), info.InstallMethod.InstallerVersion)
+}
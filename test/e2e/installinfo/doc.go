@@ -0,0 +1,9 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package installinfo parses the files identifying an install, /etc/datadog-agent/install.json and install_info. The
+// script only reuses the install_id and install_time of an existing install.json for docker injection installs: the
+// agent installs get a new identity on each run.
+package installinfo
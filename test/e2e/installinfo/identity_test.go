@@ -0,0 +1,255 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package installinfo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	apiKey   = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	uuidPath = "/proc/sys/kernel/random/uuid"
+	// replayUUID is what the uuid source returns on the second run, a new identity has it
+	replayUUID  = "6f9619ff-8b86-d011-b42d-00c04fc964ff"
+	waitTimeout = 10 * time.Second
+)
+
+// scriptVersion returns the install_script_version of the template the harness renders
+func scriptVersion(t *testing.T) string {
+	t.Helper()
+	path, err := hermetic.FindTemplate()
+	require.NoError(t, err)
+	template, err := os.ReadFile(path)
+	require.NoError(t, err)
+	version, err := ScriptVersion(string(template))
+	require.NoError(t, err)
+	return version
+}
+
+// seedSignature rewrites install.json with edit, which gets the current content or an empty one
+func seedSignature(t *testing.T, h *hermetic.Harness, edit func(content string) string) {
+	t.Helper()
+	content := ""
+	if h.FileExists(SignaturePath) {
+		content = h.ReadFile(SignaturePath)
+	}
+	require.NoError(t, os.MkdirAll(filepath.Dir(h.Path(SignaturePath)), 0755))
+	require.NoError(t, os.WriteFile(h.Path(SignaturePath), []byte(edit(content)), 0644))
+}
+
+func readSignature(t *testing.T, h *hermetic.Harness) Signature {
+	t.Helper()
+	signature, err := ParseSignature(h.ReadFile(SignaturePath))
+	require.NoError(t, err)
+	return signature
+}
+
+// TestInstallIdentity re-runs the agent installs: they write an empty install_type, which never matches the offsets
+// the script compares, so the second run always writes a new identity
+func TestInstallIdentity(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []hermetic.Option
+		// first and second are the variants run in turn, first is skipped when empty
+		first, second hermetic.Variant
+		// edit rewrites install.json between the runs, or seeds it when first is empty
+		edit func(content string) string
+	}{
+		{
+			name:   "same script",
+			first:  hermetic.VariantAgent7,
+			second: hermetic.VariantAgent7,
+		},
+		{
+			name:   "agent6 then agent7",
+			first:  hermetic.VariantAgent6,
+			second: hermetic.VariantAgent7,
+		},
+		{
+			name:   "agent7 then agent6",
+			first:  hermetic.VariantAgent7,
+			second: hermetic.VariantAgent6,
+		},
+		{
+			name:   "legacy then agent7",
+			first:  hermetic.VariantLegacy,
+			second: hermetic.VariantAgent7,
+		},
+		{
+			name:   "hand-edited install id",
+			first:  hermetic.VariantAgent7,
+			second: hermetic.VariantAgent7,
+			edit:   func(content string) string { return strings.Replace(content, "0f8fad5b", "00000000", 1) },
+		},
+		{
+			name:   "reformatted by hand",
+			first:  hermetic.VariantAgent7,
+			second: hermetic.VariantAgent7,
+			edit: func(content string) string {
+				return strings.NewReplacer(`":`, `": `, `,"`, `, "`).Replace(content)
+			},
+		},
+		{
+			// the install type of the signature doesn't match the one of the agent install
+			name:   "signature of a docker install",
+			second: hermetic.VariantAgent7,
+			edit:   func(string) string { return docker },
+		},
+		{
+			name:   "signature of an agent install",
+			second: hermetic.VariantAgent7,
+			edit:   func(string) string { return agent7 },
+		},
+		{
+			// without /proc/sys/kernel/random/uuid nor uuidgen, the install id is empty
+			name:    "missing uuid source",
+			options: []hermetic.Option{hermetic.WithoutFile(uuidPath)},
+			first:   hermetic.VariantAgent7,
+			second:  hermetic.VariantAgent7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := hermetic.New(t, tt.options...)
+			env := map[string]string{"DD_API_KEY": apiKey}
+			if tt.first != "" {
+				result := h.RunVariant(tt.first, env)
+				require.Equal(t, 0, result.ExitCode, result.Output)
+			}
+			if tt.edit != nil {
+				seedSignature(t, h, tt.edit)
+			}
+			found := h.ReadFile(SignaturePath)
+			if h.FileExists(uuidPath) {
+				require.NoError(t, os.WriteFile(h.Path(uuidPath), []byte(replayUUID+"\n"), 0644))
+			}
+
+			result := h.RunVariant(tt.second, env)
+			require.Equal(t, 0, result.ExitCode, result.Output)
+
+			assert.False(t, Reusable(found, ""), "the model of the script disagrees")
+			signature := readSignature(t, h)
+			assert.Equal(t, "", signature.InstallType)
+			if h.FileExists(uuidPath) {
+				assert.Equal(t, replayUUID, signature.InstallID)
+			} else {
+				assert.Empty(t, signature.InstallID)
+			}
+			// the install time is new too, but both runs may happen within the same second
+			if previous, err := ParseSignature(found); err == nil {
+				assert.GreaterOrEqual(t, signature.InstallTime, previous.InstallTime)
+			}
+			assert.Equal(t, signature.String()+"\n", h.ReadFile(SignaturePath), "install.json is a single line")
+
+			info, err := ParseInfo(h.ReadFile(InfoPath))
+			require.NoError(t, err)
+			assert.Equal(t, Tool, info.InstallMethod.Tool)
+			assert.Equal(t, ToolVersion(tt.second), info.InstallMethod.ToolVersion)
+			assert.Equal(t, InstallerVersion(scriptVersion(t)), info.InstallMethod.InstallerVersion)
+		})
+	}
+}
+
+// TestDockerInjectionIdentity runs docker injection, which reports the identity in its onboarding event without
+// writing install.json: it keeps the identity of a docker signature only
+func TestDockerInjectionIdentity(t *testing.T) {
+	tests := []struct {
+		name string
+		// agentFirst runs an agent install first
+		agentFirst bool
+		// seed is the install.json found by docker injection, unless the agent install wrote it
+		seed string
+		// preserved is set when the event reports the identity of the install.json found
+		preserved bool
+	}{
+		{name: "signature of a docker install", seed: docker, preserved: true},
+		{name: "signature of an agent install", seed: agent7},
+		{name: "after an agent install", agentFirst: true},
+		{name: "no signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := hermetic.New(t, hermetic.WithPassthrough(`127\.0\.0\.1`))
+			if tt.agentFirst {
+				result := h.RunVariant(hermetic.VariantAgent7, map[string]string{"DD_API_KEY": apiKey})
+				require.Equal(t, 0, result.ExitCode, result.Output)
+			}
+			if tt.seed != "" {
+				seedSignature(t, h, func(string) string { return tt.seed })
+			}
+			var found, info string
+			if h.FileExists(SignaturePath) {
+				found = h.ReadFile(SignaturePath)
+			}
+			if h.FileExists(InfoPath) {
+				info = h.ReadFile(InfoPath)
+			}
+			require.NoError(t, os.WriteFile(h.Path(uuidPath), []byte(replayUUID+"\n"), 0644))
+
+			intake := telemetry.NewIntake()
+			t.Cleanup(intake.Close)
+			result := h.RunVariant(hermetic.VariantDockerInjection, map[string]string{"DD_API_KEY": apiKey, "TESTING_REPORT_URL": intake.URL()})
+			require.Equal(t, 0, result.ExitCode, result.Output)
+
+			assert.Equal(t, tt.preserved, Reusable(found, InstallTypeDocker), "the model of the script disagrees")
+			event, err := intake.WaitFor(telemetry.RequestTypeOnboardingEvent, waitTimeout)
+			require.NoError(t, err)
+			tags := event.Payload.Tags
+			assert.Equal(t, InstallTypeDocker, tags["install_type"])
+			if tt.preserved {
+				previous, err := ParseSignature(found)
+				require.NoError(t, err)
+				assert.Equal(t, previous.InstallID, tags["install_id"])
+				assert.Equal(t, strconv.FormatInt(previous.InstallTime, 10), fmt.Sprint(tags["install_time"]))
+			} else {
+				assert.Equal(t, replayUUID, tags["install_id"])
+			}
+
+			// docker injection installs no Agent, it leaves both files as they are
+			assert.Equal(t, found != "", h.FileExists(SignaturePath))
+			if found != "" {
+				assert.Equal(t, found, h.ReadFile(SignaturePath))
+			}
+			assert.Equal(t, info != "", h.FileExists(InfoPath))
+			if info != "" {
+				assert.Equal(t, info, h.ReadFile(InfoPath))
+			}
+		})
+	}
+}
+
+func TestDockerInjectionWritesNoIdentity(t *testing.T) {
+	h := hermetic.New(t, hermetic.WithVariant(hermetic.VariantDockerInjection))
+	result := h.Run(map[string]string{"DD_API_KEY": apiKey})
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	assert.False(t, h.FileExists(SignaturePath))
+	assert.False(t, h.FileExists(InfoPath))
+}
+
+func TestFIPSInstallInfo(t *testing.T) {
+	h := hermetic.New(t)
+	result := h.Run(map[string]string{"DD_API_KEY": apiKey, "DD_FIPS_MODE": "true"})
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	agent, err := ParseInfo(h.ReadFile(InfoPath))
+	require.NoError(t, err)
+	fips, err := ParseInfo(h.ReadFile(FIPSInfoPath))
+	require.NoError(t, err)
+	assert.Equal(t, agent, fips)
+	assert.Equal(t, ToolVersion(hermetic.VariantAgent7), fips.InstallMethod.ToolVersion)
+}
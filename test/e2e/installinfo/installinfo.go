@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package installinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// SignaturePath and InfoPath are written on each install, except by docker injection which installs no Agent
+	SignaturePath = "/etc/datadog-agent/install.json"
+	InfoPath      = "/etc/datadog-agent/install_info"
+	// FIPSInfoPath is the install_info the script also writes in FIPS mode
+	FIPSInfoPath = "/etc/datadog-fips-proxy/install_info"
+
+	// Tool is the install method of the script
+	Tool = "install_script"
+	// InstallTypeDocker is the install type of DD_APM_INSTRUMENTATION_ENABLED=docker, the others are empty
+	InstallTypeDocker = "linux_single_step_dkr"
+)
+
+var scriptVersionLine = regexp.MustCompile(`(?m)^install_script_version=(\S+)$`)
+
+// Signature is install.json, a single JSON line
+type Signature struct {
+	InstallID   string `json:"install_id"`
+	InstallType string `json:"install_type"`
+	InstallTime int64  `json:"install_time"`
+}
+
+// ParseSignature decodes install.json, rejecting the keys the script doesn't write
+func ParseSignature(content string) (Signature, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.DisallowUnknownFields()
+	var signature Signature
+	if err := decoder.Decode(&signature); err != nil {
+		return Signature{}, fmt.Errorf("invalid install.json: %w", err)
+	}
+	return signature, nil
+}
+
+// String formats the signature as the script writes it, without the trailing newline
+func (s Signature) String() string {
+	return fmt.Sprintf(`{"install_id":"%s","install_type":"%s","install_time":%d}`, s.InstallID, s.InstallType, s.InstallTime)
+}
+
+// Reusable tells whether the script keeps the identity of an existing install.json when it installs with
+// installType. The script doesn't parse the JSON, it checks the keys and the type at the fixed offsets of a 118 bytes
+// linux_single_step_dkr signature, so the empty install type of the agent installs never matches.
+func Reusable(content string, installType string) bool {
+	// the script reads it with a command substitution, which drops the trailing newlines
+	content = strings.TrimRight(content, "\n")
+	if len(content) != 118 {
+		return false
+	}
+	return content[2:12] == "install_id" &&
+		content[53:91] == fmt.Sprintf(`"install_type":"%s"`, installType) &&
+		content[93:105] == "install_time"
+}
+
+// Info is install_info, it describes the tool that installed the Agent
+type Info struct {
+	InstallMethod struct {
+		Tool             string `yaml:"tool"`
+		ToolVersion      string `yaml:"tool_version"`
+		InstallerVersion string `yaml:"installer_version"`
+	} `yaml:"install_method"`
+}
+
+// ParseInfo decodes install_info, rejecting the keys the script doesn't write
+func ParseInfo(content string) (Info, error) {
+	var info Info
+	if err := yaml.UnmarshalStrict([]byte(content), &info); err != nil {
+		return Info{}, fmt.Errorf("invalid install_info: %w", err)
+	}
+	return info, nil
+}
+
+// ToolVersion is the tool_version written by a variant of the script, e.g. install_script_agent7
+func ToolVersion(variant hermetic.Variant) string {
+	return strings.TrimSuffix(string(variant), ".sh")
+}
+
+// InstallerVersion is the installer_version written by the script of version scriptVersion
+func InstallerVersion(scriptVersion string) string {
+	return Tool + "-" + scriptVersion
+}
+
+// ScriptVersion returns the install_script_version of the template or of a script generated from it
+func ScriptVersion(script string) (string, error) {
+	match := scriptVersionLine.FindStringSubmatch(script)
+	if match == nil {
+		return "", fmt.Errorf("install_script_version not found")
+	}
+	return match[1], nil
+}
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package installinfo
+
+import (
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	installID = "0f8fad5b-d9cb-469f-a165-70867728950e"
+	agent7    = `{"install_id":"0f8fad5b-d9cb-469f-a165-70867728950e","install_type":"","install_time":1700000000}` + "\n"
+	docker    = `{"install_id":"0f8fad5b-d9cb-469f-a165-70867728950e","install_type":"linux_single_step_dkr","install_time":1700000000}` + "\n"
+)
+
+func TestParseSignature(t *testing.T) {
+	signature, err := ParseSignature(agent7)
+	require.NoError(t, err)
+	assert.Equal(t, Signature{InstallID: installID, InstallTime: 1700000000}, signature)
+	assert.Equal(t, agent7, signature.String()+"\n")
+
+	signature, err = ParseSignature(docker)
+	require.NoError(t, err)
+	assert.Equal(t, InstallTypeDocker, signature.InstallType)
+	assert.Len(t, signature.String(), 118)
+
+	for _, content := range []string{"", "{", `{"install_id":"x","tool":"y"}`, `{"install_time":"now"}`} {
+		_, err := ParseSignature(content)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestReusable(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		installType string
+		reusable    bool
+	}{
+		// 97 bytes, the script only compares the offsets of a docker signature
+		{name: "agent", content: agent7},
+		{name: "docker", content: docker, installType: InstallTypeDocker, reusable: true},
+		{name: "docker without newline", content: docker[:len(docker)-1], installType: InstallTypeDocker, reusable: true},
+		{name: "docker signature for an agent install", content: docker},
+		{name: "agent signature for a docker install", content: agent7, installType: InstallTypeDocker},
+		{name: "reformatted", content: `{"install_id": "0f8fad5b-d9cb-469f-a165-70867728950e", "install_type": "", "install_time": 1700000000}`},
+		{name: "empty install id", content: `{"install_id":"","install_type":"","install_time":1700000000}`},
+		{name: "keys swapped", content: `{"install_tm":"0f8fad5b-d9cb-469f-a165-70867728950e","install_type":"","install_id":1700000000}`},
+		{name: "empty", content: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.reusable, Reusable(tt.content, tt.installType))
+		})
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	info, err := ParseInfo("---\ninstall_method:\n  tool: install_script\n  tool_version: install_script_agent7\n  installer_version: install_script-1.46.0\n\n")
+	require.NoError(t, err)
+	assert.Equal(t, Tool, info.InstallMethod.Tool)
+	assert.Equal(t, ToolVersion(hermetic.VariantAgent7), info.InstallMethod.ToolVersion)
+	assert.Equal(t, InstallerVersion("1.46.0"), info.InstallMethod.InstallerVersion)
+
+	_, err = ParseInfo("install_method:\n  tool: install_script\n  version: 1\n")
+	assert.Error(t, err)
+}
+
+func TestToolVersion(t *testing.T) {
+	assert.Equal(t, "install_script", ToolVersion(hermetic.VariantLegacy))
+	assert.Equal(t, "install_script_agent6", ToolVersion(hermetic.VariantAgent6))
+	assert.Equal(t, "install_script_docker_injection", ToolVersion(hermetic.VariantDockerInjection))
+}
+
+func TestScriptVersion(t *testing.T) {
+	assert.Regexp(t, `^\d+\.\d+\.\d+`, scriptVersion(t))
+	version, err := ScriptVersion("#!/bin/bash\nvariant=install_script_agent7\ninstall_script_version=1.46.0.post\n")
+	require.NoError(t, err)
+	assert.Equal(t, "1.46.0.post", version)
+
+	_, err = ScriptVersion("#!/bin/bash\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,10 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package installlog turns the output of the install script into an ordered list of typed steps: configuration
+// updates and the file they target, kept configuration files, package installs, repository setup, warnings and
+// errors. Suites can then assert the sequence of steps, or the absence of a kind of step, rather than substrings of
+// the colored output.
+package installlog
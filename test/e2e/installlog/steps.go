@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package installlog
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kind is the type of a step
+type Kind string
+
+// Kinds of steps, the script prints the first ones in blue with a leading "* "
+const (
+	// KindConfig is an update of a configuration file, see Step.File
+	KindConfig Kind = "config"
+	// KindKeepConfig is an existing configuration file left as is, see Step.File
+	KindKeepConfig Kind = "keep-config"
+	// KindRepository is the setup of the package sources, their keys and their refresh
+	KindRepository Kind = "repository"
+	// KindInfo is any other blue or green message
+	KindInfo Kind = "info"
+	// KindPackages is the list of packages about to be installed, in yellow, see Step.Packages
+	KindPackages Kind = "packages"
+	// KindWarning is any other yellow message
+	KindWarning Kind = "warning"
+	// KindError is a red message
+	KindError Kind = "error"
+)
+
+// Step is a message of the script
+type Step struct {
+	Kind Kind
+	// Text is the message without colors, surrounding blank space nor the leading "* "
+	Text string
+	// File is the configuration file of KindConfig and KindKeepConfig steps
+	File string
+	// Packages are the packages of KindPackages steps, none when the script has nothing to install
+	Packages []string
+}
+
+// String returns the kind and text of the step
+func (s Step) String() string {
+	return string(s.Kind) + ": " + s.Text
+}
+
+// Steps are the steps of a run, in order
+type Steps []Step
+
+// OfKind returns the steps of the given kinds, in order
+func (s Steps) OfKind(kinds ...Kind) Steps {
+	var steps Steps
+	for _, step := range s {
+		for _, kind := range kinds {
+			if step.Kind == kind {
+				steps = append(steps, step)
+				break
+			}
+		}
+	}
+	return steps
+}
+
+// Has reports whether a step is of the kind
+func (s Steps) Has(kind Kind) bool {
+	return len(s.OfKind(kind)) > 0
+}
+
+// Texts returns the text of each step
+func (s Steps) Texts() []string {
+	texts := make([]string, 0, len(s))
+	for _, step := range s {
+		texts = append(texts, step.Text)
+	}
+	return texts
+}
+
+// Files returns the file of each step that has one, e.g. the files updated by steps.OfKind(KindConfig)
+func (s Steps) Files() []string {
+	var files []string
+	for _, step := range s {
+		if step.File != "" {
+			files = append(files, step.File)
+		}
+	}
+	return files
+}
+
+// Packages returns the packages of all the KindPackages steps
+func (s Steps) Packages() []string {
+	var packages []string
+	for _, step := range s.OfKind(KindPackages) {
+		packages = append(packages, step.Packages...)
+	}
+	return packages
+}
+
+var (
+	// colored matches a message in color: the code of the color, then its text up to the reset code
+	colored = regexp.MustCompile(`(?s)\x1b\[([0-9;]*)m(.*?)\x1b\[0m`)
+	// ansiEscape matches the color codes left in a message
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+	keepConfig = regexp.MustCompile(`^Keeping old (\S+) configuration file$`)
+	// "Adding your API key to the Datadog Agent configuration: /etc/datadog-agent/datadog.yaml" and
+	// "Enabling runtime security in /etc/datadog-agent/system-probe.yaml configuration"
+	config     = regexp.MustCompile(`: (/\S+)$|^Enabling .* in (/\S+) configuration$`)
+	repository = regexp.MustCompile(`^(?:Installing (?:YUM|APT) .*for Datadog|Importing the Datadog GPG Keys|Refreshing repositories)`)
+	packages   = regexp.MustCompile(`^Installing package\(s\): (.*)$`)
+)
+
+// Parse returns the steps of the script output, the output of the commands the script runs is ignored
+func Parse(output string) Steps {
+	var steps Steps
+	for _, match := range colored.FindAllStringSubmatch(output, -1) {
+		text := strings.TrimSpace(ansiEscape.ReplaceAllString(match[2], ""))
+		if text == "" {
+			continue
+		}
+		steps = append(steps, parseStep(colorOf(match[1]), text))
+	}
+	return steps
+}
+
+// colorOf returns the foreground color of an SGR code such as "34" or "1;31"
+func colorOf(code string) string {
+	parts := strings.Split(code, ";")
+	return parts[len(parts)-1]
+}
+
+func parseStep(color string, text string) Step {
+	switch color {
+	case "31":
+		return Step{Kind: KindError, Text: text}
+	case "33":
+		if text == "No packages to install." {
+			return Step{Kind: KindPackages, Text: text}
+		}
+		if match := packages.FindStringSubmatch(text); match != nil {
+			return Step{Kind: KindPackages, Text: text, Packages: strings.Fields(match[1])}
+		}
+		return Step{Kind: KindWarning, Text: text}
+	}
+	text = strings.TrimPrefix(text, "* ")
+	if match := keepConfig.FindStringSubmatch(text); match != nil {
+		return Step{Kind: KindKeepConfig, Text: text, File: match[1]}
+	}
+	if match := config.FindStringSubmatch(text); match != nil {
+		return Step{Kind: KindConfig, Text: text, File: match[1] + match[2]}
+	}
+	if repository.MatchString(text) {
+		return Step{Kind: KindRepository, Text: text}
+	}
+	return Step{Kind: KindInfo, Text: text}
+}
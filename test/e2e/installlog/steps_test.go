@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package installlog
+
+import (
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	apiKey        = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	datadog       = "/etc/datadog-agent/datadog.yaml"
+	security      = "/etc/datadog-agent/security-agent.yaml"
+	probe         = "/etc/datadog-agent/system-probe.yaml"
+	agentPackages = "Installing package(s): datadog-agent datadog-signing-keys"
+)
+
+func TestParse(t *testing.T) {
+	output := "\033[34m\n* Installing APT package sources for Datadog\n\033[0m\n" +
+		"Hit:1 https://apt.datadoghq.com stable InRelease\n" +
+		"\033[33mWarning: REPO_URL is deprecated and might be removed later (use DD_REPO_URL instead).\033[0m\n" +
+		"  \033[33mInstalling package(s): datadog-agent datadog-signing-keys\n\033[0m\n" +
+		"  \033[33mNo packages to install.\033[0m\n" +
+		"\033[34m\n* Adding your API key to the Datadog Agent configuration: " + datadog + "\n\033[0m\n" +
+		"\033[34m\n* Enabling runtime security in " + probe + " configuration\n\033[0m\n" +
+		"\033[34m\n* otelcollector configuration already exists in " + datadog + ", skipping the update.\n\033[0m\n" +
+		"\033[34m\n* Keeping old " + datadog + " configuration file\n\033[0m\n" +
+		"\033[1;31mInstallation failed: Unable to get lock.\nRetrying in 5s (1/3).\033[0m\n" +
+		"\033[32m  Your Datadog Agent is running and functioning properly.\n\033[0m"
+
+	steps := Parse(output)
+	assert.Equal(t, Steps{
+		{Kind: KindRepository, Text: "Installing APT package sources for Datadog"},
+		{Kind: KindWarning, Text: "Warning: REPO_URL is deprecated and might be removed later (use DD_REPO_URL instead)."},
+		{Kind: KindPackages, Text: agentPackages, Packages: []string{"datadog-agent", "datadog-signing-keys"}},
+		{Kind: KindPackages, Text: "No packages to install."},
+		{Kind: KindConfig, Text: "Adding your API key to the Datadog Agent configuration: " + datadog, File: datadog},
+		{Kind: KindConfig, Text: "Enabling runtime security in " + probe + " configuration", File: probe},
+		{Kind: KindInfo, Text: "otelcollector configuration already exists in " + datadog + ", skipping the update."},
+		{Kind: KindKeepConfig, Text: "Keeping old " + datadog + " configuration file", File: datadog},
+		{Kind: KindError, Text: "Installation failed: Unable to get lock.\nRetrying in 5s (1/3)."},
+		{Kind: KindInfo, Text: "Your Datadog Agent is running and functioning properly."},
+	}, steps)
+	assert.Equal(t, []string{datadog, probe}, steps.OfKind(KindConfig).Files())
+	assert.Equal(t, []string{"datadog-agent", "datadog-signing-keys"}, steps.Packages())
+	assert.Len(t, steps.OfKind(KindWarning, KindError), 2)
+	assert.True(t, steps.Has(KindKeepConfig))
+	assert.Empty(t, Parse("plain output\n"))
+}
+
+func TestInstallAndReplaySteps(t *testing.T) {
+	env := map[string]string{
+		"DD_API_KEY":                           apiKey,
+		"DD_INSTRUMENTATION_TELEMETRY_ENABLED": "false",
+		"DD_SITE":                              "mysite.com",
+		"DD_URL":                               "myintake.com",
+		"DD_HOSTNAME":                          "totoro",
+		"DD_TAGS":                              "foo:bar,baz:toto",
+		"DD_ENV":                               "kiki",
+		"DD_RUNTIME_SECURITY_CONFIG_ENABLED":   "true",
+		"DD_COMPLIANCE_CONFIG_ENABLED":         "true",
+	}
+	h := hermetic.New(t)
+	result := h.Run(env)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+	steps := Parse(result.Output)
+	assert.Equal(t, []Kind{KindInfo, KindInfo, KindRepository, KindPackages}, kinds(steps[:4]))
+	assert.Equal(t, []string{"datadog-agent", "datadog-signing-keys"}, steps.Packages())
+	assert.Equal(t, []string{datadog, datadog, datadog, datadog, datadog, datadog, security, security, probe}, steps.OfKind(KindConfig).Files())
+	assert.False(t, steps.Has(KindKeepConfig))
+	assert.Empty(t, steps.OfKind(KindWarning, KindError))
+
+	// the configuration of the first run is kept
+	result = h.Run(env)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+	steps = Parse(result.Output)
+	assert.False(t, steps.Has(KindConfig), steps.OfKind(KindConfig).Texts())
+	assert.Equal(t, []string{datadog}, steps.OfKind(KindKeepConfig).Files())
+	assert.Equal(t, []string{"datadog-agent", "datadog-signing-keys"}, steps.Packages())
+}
+
+func TestFIPSSteps(t *testing.T) {
+	h := hermetic.New(t, hermetic.WithOS(hermetic.RedHat("9.4")))
+	result := h.Run(map[string]string{
+		"DD_API_KEY":                           apiKey,
+		"DD_INSTRUMENTATION_TELEMETRY_ENABLED": "false",
+		"DD_FIPS_MODE":                         "true",
+		// the FIPS proxy replaces the intake of the site
+		"DD_SITE": "darth.vader.com",
+		"DD_URL":  "fake.url.com",
+	})
+	require.Equal(t, 0, result.ExitCode, result.Output)
+	steps := Parse(result.Output)
+	assert.Equal(t, []string{"Installing YUM sources for Datadog"}, steps.OfKind(KindRepository).Texts())
+	assert.Equal(t, []string{"datadog-agent", "datadog-fips-proxy"}, steps.Packages())
+	assert.Equal(t, []string{
+		"Adding your API key to the Datadog Agent configuration: " + datadog,
+		"Setting Datadog Agent configuration to use FIPS proxy: " + datadog,
+	}, steps.OfKind(KindConfig).Texts())
+}
+
+func TestWarningAndErrorSteps(t *testing.T) {
+	h := hermetic.New(t)
+	result := h.Run(map[string]string{"DD_INSTRUMENTATION_TELEMETRY_ENABLED": "false", "REPO_URL": "datadoghq.com"})
+	require.NotEqual(t, 0, result.ExitCode, result.Output)
+	steps := Parse(result.Output)
+	assert.Equal(t, []string{"Warning: REPO_URL is deprecated and might be removed later (use DD_REPO_URL instead)."}, steps.OfKind(KindWarning).Texts())
+	errors := steps.OfKind(KindError)
+	require.NotEmpty(t, errors)
+	assert.Equal(t, "API key not available in DD_API_KEY environment variable.", errors[0].Text)
+	assert.False(t, steps.Has(KindPackages))
+	assert.False(t, steps.Has(KindConfig))
+}
+
+func kinds(steps Steps) []Kind {
+	var kinds []Kind
+	for _, step := range steps {
+		kinds = append(kinds, step.Kind)
+	}
+	return kinds
+}
@@ -0,0 +1,263 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+)
+
+// scriptAgent5 is the Agent 5 install script, it comes from the dd-agent repository and not from the template
+const scriptAgent5 hermetic.Variant = "install_agent.sh"
+
+// invocationMode is how the script is run, all of them are documented ways to run it
+type invocationMode string
+
+const (
+	// invocationCommandSubstitution runs `bash -c "$(cat script)"`, as the one-liner of the documentation
+	invocationCommandSubstitution invocationMode = "bash-c"
+	// invocationPipe runs `cat script | bash`, the script reads nothing from stdin
+	invocationPipe invocationMode = "pipe"
+	// invocationFile runs `bash script`, once downloaded
+	invocationFile invocationMode = "file"
+)
+
+// InstallOptions describes a run of the install script: the script and how it's invoked, and the variables it reads.
+// Zero values leave the variable unset, boolean options are set to "true" only when enabled, and pointers are for the
+// options where an explicit false differs from unset.
+type InstallOptions struct {
+	// Description is logged before the install, "Install latest Agent <major>" by default
+	Description string
+	// Script is the script under scripts/, derived from AgentMajorVersion by default
+	Script     hermetic.Variant
+	Invocation invocationMode
+
+	AgentMajorVersion int         // DD_AGENT_MAJOR_VERSION, 7 by default, 5 runs the Agent 5 script
+	AgentMinorVersion string      // DD_AGENT_MINOR_VERSION, e.g. "42.0"
+	AgentFlavor       agentFlavor // DD_AGENT_FLAVOR, the -flavor flag by default
+	AgentDistChannel  string      // DD_AGENT_DIST_CHANNEL
+	DDOTDistChannel   string      // DD_DDOT_DIST_CHANNEL
+	InstallOnly       bool        // DD_INSTALL_ONLY
+	NoAgentInstall    bool        // DD_NO_AGENT_INSTALL
+	Upgrade           bool        // DD_UPGRADE, from Agent 5
+	RepoURL           string      // DD_REPO_URL
+	RPMRepoGPGCheck   string      // DD_RPM_REPO_GPGCHECK
+
+	APIKey             string // DD_API_KEY, the -apiKey flag by default
+	AppKey             string // DD_APP_KEY
+	Site               string // DD_SITE
+	URL                string // DD_URL
+	Hostname           string // DD_HOSTNAME
+	Tags               string // DD_TAGS, comma separated
+	HostTags           string // DD_HOST_TAGS, deprecated for DD_TAGS
+	Env                string // DD_ENV
+	InfrastructureMode string // DD_INFRASTRUCTURE_MODE
+
+	FIPSMode                            bool  // DD_FIPS_MODE
+	RemoteUpdates                       bool  // DD_REMOTE_UPDATES
+	OTelCollectorEnabled                bool  // DD_OTELCOLLECTOR_ENABLED
+	APMErrorTrackingStandalone          bool  // DD_APM_ERROR_TRACKING_STANDALONE
+	RuntimeSecurityConfigEnabled        bool  // DD_RUNTIME_SECURITY_CONFIG_ENABLED
+	ComplianceConfigEnabled             bool  // DD_COMPLIANCE_CONFIG_ENABLED
+	SystemProbeEnsureConfig             bool  // DD_SYSTEM_PROBE_ENSURE_CONFIG
+	SystemProbeServiceMonitoringEnabled bool  // DD_SYSTEM_PROBE_SERVICE_MONITORING_ENABLED
+	DiscoveryEnabled                    bool  // DD_DISCOVERY_ENABLED
+	PrivilegedLogsEnabled               *bool // DD_PRIVILEGED_LOGS_ENABLED, enabled with process logs collection when unset
+	LogsConfigProcessCollectAll         bool  // DD_LOGS_CONFIG_PROCESS_COLLECT_ALL
+	SBOMContainerImageEnabled           bool  // DD_SBOM_CONTAINER_IMAGE_ENABLED
+	SBOMHostEnabled                     bool  // DD_SBOM_HOST_ENABLED
+
+	APMInstrumentationEnabled            string // DD_APM_INSTRUMENTATION_ENABLED: host, docker or all
+	APMInstrumentationPipelineID         string // DD_APM_INSTRUMENTATION_PIPELINE_ID
+	InstallerRegistryURL                 string // DD_INSTALLER_REGISTRY_URL
+	InstallerRegistryURLInstallerPackage string // DD_INSTALLER_REGISTRY_URL_INSTALLER_PACKAGE
+	InstrumentationTelemetryEnabled      *bool  // DD_INSTRUMENTATION_TELEMETRY_ENABLED
+
+	PrivateActionRunnerEnabled              bool     // DD_PRIVATE_ACTION_RUNNER_ENABLED
+	PrivateActionRunnerActionsAllowlist     []string // DD_PRIVATE_ACTION_RUNNER_ACTIONS_ALLOWLIST
+	PrivateActionRunnerAPIKeyOnlyEnrollment *bool    // DD_PRIVATE_ACTION_RUNNER_API_KEY_ONLY_ENROLLMENT
+
+	TestingAPTURL         string // TESTING_APT_URL
+	TestingAPTRepoVersion string // TESTING_APT_REPO_VERSION
+	TestingYUMURL         string // TESTING_YUM_URL
+	TestingYUMVersionPath string // TESTING_YUM_VERSION_PATH
+	TestingKeysURL        string // TESTING_KEYS_URL
+	TestingReportURL      string // TESTING_REPORT_URL
+
+	// Extra sets variables without a field, such as DD_INSTALLER for scripts that no longer read it
+	Extra map[string]string
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+// withCIRepository returns the options with the TESTING_* repository variables of the environment, CI sets them to
+// install the Agent of the pipeline under test
+func (o InstallOptions) withCIRepository() InstallOptions {
+	for _, option := range []struct {
+		field *string
+		name  string
+	}{
+		{&o.TestingYUMVersionPath, "TESTING_YUM_VERSION_PATH"},
+		{&o.TestingAPTRepoVersion, "TESTING_APT_REPO_VERSION"},
+		{&o.TestingYUMURL, "TESTING_YUM_URL"},
+		{&o.TestingAPTURL, "TESTING_APT_URL"},
+		{&o.TestingKeysURL, "TESTING_KEYS_URL"},
+	} {
+		if value, ok := os.LookupEnv(option.name); ok {
+			*option.field = value
+		}
+	}
+	return o
+}
+
+// withDefaults fills the options left to the flags of the suite and the script of the major version
+func (o InstallOptions) withDefaults() InstallOptions {
+	if o.APIKey == "" {
+		o.APIKey = apiKey
+	}
+	if o.AgentMajorVersion == 0 {
+		o.AgentMajorVersion = 7
+	}
+	if o.Description == "" {
+		o.Description = fmt.Sprintf("Install latest Agent %d", o.AgentMajorVersion)
+	}
+	if o.Invocation == "" {
+		o.Invocation = invocationCommandSubstitution
+	}
+	if o.AgentMajorVersion == 5 {
+		// the Agent 5 script reads neither the major version nor the flavor
+		o.AgentMajorVersion = 0
+		if o.Script == "" {
+			o.Script = scriptAgent5
+		}
+		return o
+	}
+	if o.AgentFlavor == "" {
+		o.AgentFlavor = flavor
+	}
+	if o.Script == "" {
+		o.Script = hermetic.VariantAgent7
+		if o.AgentMajorVersion == 6 {
+			o.Script = hermetic.VariantAgent6
+		}
+	}
+	return o
+}
+
+// env returns the NAME=value assignments of the options, unquoted
+func (o InstallOptions) env() []string {
+	var env []string
+	set := func(name, value string) {
+		if value != "" {
+			env = append(env, name+"="+value)
+		}
+	}
+	enable := func(name string, enabled bool) {
+		if enabled {
+			set(name, "true")
+		}
+	}
+	explicit := func(name string, value *bool) {
+		if value != nil {
+			set(name, strconv.FormatBool(*value))
+		}
+	}
+
+	set("DD_API_KEY", o.APIKey)
+	if o.AgentMajorVersion != 0 {
+		set("DD_AGENT_MAJOR_VERSION", strconv.Itoa(o.AgentMajorVersion))
+	}
+	set("DD_AGENT_MINOR_VERSION", o.AgentMinorVersion)
+	set("DD_AGENT_FLAVOR", string(o.AgentFlavor))
+	set("DD_AGENT_DIST_CHANNEL", o.AgentDistChannel)
+	set("DD_DDOT_DIST_CHANNEL", o.DDOTDistChannel)
+	enable("DD_INSTALL_ONLY", o.InstallOnly)
+	enable("DD_NO_AGENT_INSTALL", o.NoAgentInstall)
+	enable("DD_UPGRADE", o.Upgrade)
+	set("DD_REPO_URL", o.RepoURL)
+	set("DD_RPM_REPO_GPGCHECK", o.RPMRepoGPGCheck)
+
+	set("DD_APP_KEY", o.AppKey)
+	set("DD_SITE", o.Site)
+	set("DD_URL", o.URL)
+	set("DD_HOSTNAME", o.Hostname)
+	set("DD_TAGS", o.Tags)
+	set("DD_HOST_TAGS", o.HostTags)
+	set("DD_ENV", o.Env)
+	set("DD_INFRASTRUCTURE_MODE", o.InfrastructureMode)
+
+	enable("DD_FIPS_MODE", o.FIPSMode)
+	enable("DD_REMOTE_UPDATES", o.RemoteUpdates)
+	enable("DD_OTELCOLLECTOR_ENABLED", o.OTelCollectorEnabled)
+	enable("DD_APM_ERROR_TRACKING_STANDALONE", o.APMErrorTrackingStandalone)
+	enable("DD_RUNTIME_SECURITY_CONFIG_ENABLED", o.RuntimeSecurityConfigEnabled)
+	enable("DD_COMPLIANCE_CONFIG_ENABLED", o.ComplianceConfigEnabled)
+	enable("DD_SYSTEM_PROBE_ENSURE_CONFIG", o.SystemProbeEnsureConfig)
+	enable("DD_SYSTEM_PROBE_SERVICE_MONITORING_ENABLED", o.SystemProbeServiceMonitoringEnabled)
+	enable("DD_DISCOVERY_ENABLED", o.DiscoveryEnabled)
+	explicit("DD_PRIVILEGED_LOGS_ENABLED", o.PrivilegedLogsEnabled)
+	enable("DD_LOGS_CONFIG_PROCESS_COLLECT_ALL", o.LogsConfigProcessCollectAll)
+	enable("DD_SBOM_CONTAINER_IMAGE_ENABLED", o.SBOMContainerImageEnabled)
+	enable("DD_SBOM_HOST_ENABLED", o.SBOMHostEnabled)
+
+	set("DD_APM_INSTRUMENTATION_ENABLED", o.APMInstrumentationEnabled)
+	set("DD_APM_INSTRUMENTATION_PIPELINE_ID", o.APMInstrumentationPipelineID)
+	set("DD_INSTALLER_REGISTRY_URL", o.InstallerRegistryURL)
+	set("DD_INSTALLER_REGISTRY_URL_INSTALLER_PACKAGE", o.InstallerRegistryURLInstallerPackage)
+	explicit("DD_INSTRUMENTATION_TELEMETRY_ENABLED", o.InstrumentationTelemetryEnabled)
+
+	enable("DD_PRIVATE_ACTION_RUNNER_ENABLED", o.PrivateActionRunnerEnabled)
+	set("DD_PRIVATE_ACTION_RUNNER_ACTIONS_ALLOWLIST", strings.Join(o.PrivateActionRunnerActionsAllowlist, ","))
+	explicit("DD_PRIVATE_ACTION_RUNNER_API_KEY_ONLY_ENROLLMENT", o.PrivateActionRunnerAPIKeyOnlyEnrollment)
+
+	set("TESTING_APT_URL", o.TestingAPTURL)
+	set("TESTING_APT_REPO_VERSION", o.TestingAPTRepoVersion)
+	set("TESTING_YUM_URL", o.TestingYUMURL)
+	set("TESTING_YUM_VERSION_PATH", o.TestingYUMVersionPath)
+	set("TESTING_KEYS_URL", o.TestingKeysURL)
+	set("TESTING_REPORT_URL", o.TestingReportURL)
+
+	var extra []string
+	for name := range o.Extra {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		set(name, o.Extra[name])
+	}
+	return env
+}
+
+// command returns the shell command running the script from scripts/ with the options, each value quoted
+func (o InstallOptions) command() string {
+	var assignments []string
+	for _, assignment := range o.env() {
+		name, value, _ := strings.Cut(assignment, "=")
+		assignments = append(assignments, name+"="+shellQuote(value))
+	}
+	env := strings.Join(assignments, " ")
+	script := "scripts/" + string(o.Script)
+	switch o.Invocation {
+	case invocationPipe:
+		return fmt.Sprintf("cat %s | %s bash", script, env)
+	case invocationFile:
+		return fmt.Sprintf("%s bash %s", env, script)
+	default:
+		return fmt.Sprintf("%s bash -c \"$(cat %s)\"", env, script)
+	}
+}
+
+// shellQuote quotes a value for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
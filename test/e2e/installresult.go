@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/installlog"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	installStdoutFile = "/tmp/datadog-e2e-install.stdout"
+	installStderrFile = "/tmp/datadog-e2e-install.stderr"
+)
+
+// installArtifacts are the files a run of the script may leave behind, relative to the home of the test user for the
+// log file
+var installArtifacts = []string{
+	"$HOME/ddagent-install.log",
+	"/tmp/datadog-installer-trace.json",
+	"/tmp/datadog-installer-log.json",
+	"/tmp/ddog_install_error_msg",
+	"/tmp/datadog-installer-stdout.log",
+	"/tmp/datadog-installer-stderr.log",
+}
+
+// InstallResult is the outcome of a run of the install script
+type InstallResult struct {
+	ExitCode int
+	// Stdout and Stderr of the script, the script redirects its stderr to its stdout once its log is set up, so Stderr
+	// only has the errors of bash itself and of what runs before
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
+	// Steps are the messages of the script, in order
+	Steps installlog.Steps
+	// Artifacts are the paths of installArtifacts that exist after the run
+	Artifacts []string
+}
+
+// Output returns stdout and stderr, as a single string
+func (r InstallResult) Output() string {
+	return r.Stdout + r.Stderr
+}
+
+// Succeeded reports whether the script exited with 0
+func (r InstallResult) Succeeded() bool {
+	return r.ExitCode == 0
+}
+
+// HasArtifact reports whether the run left the file behind, e.g. "/tmp/datadog-installer-trace.json"
+func (r InstallResult) HasArtifact(path string) bool {
+	for _, artifact := range r.Artifacts {
+		if artifact == path || strings.HasSuffix(artifact, "/"+path) {
+			return true
+		}
+	}
+	return false
+}
+
+// RunInstall runs the install script with the options, see InstallOptions for the defaults, and returns its result
+// whatever its exit code. Use InstallAgent when the install must succeed.
+func (s *linuxInstallerTestSuite) RunInstall(options InstallOptions) InstallResult {
+	t := s.T()
+	t.Helper()
+	vm := s.host()
+
+	options = options.withDefaults()
+	if _, ok := s.inventories[stageBaseline]; !ok {
+		s.recordInventory(stageBaseline)
+	}
+	t.Log(options.Description)
+	start := time.Now()
+	exitCode, err := vm.Execute(fmt.Sprintf("(%s) >%s 2>%s; echo $?", options.command(), installStdoutFile, installStderrFile))
+	duration := time.Since(start)
+	require.NoError(t, err, "failed to run the install script")
+	result := InstallResult{Duration: duration}
+	result.ExitCode, err = strconv.Atoi(strings.TrimSpace(exitCode))
+	require.NoError(t, err, "unexpected exit code %q", exitCode)
+
+	stdout, err := vm.ReadFile(installStdoutFile)
+	require.NoError(t, err)
+	stderr, err := vm.ReadFile(installStderrFile)
+	require.NoError(t, err)
+	result.Stdout, result.Stderr = string(stdout), string(stderr)
+	result.Steps = installlog.Parse(result.Stdout)
+	result.Artifacts, err = listFiles(vm, strings.Join(installArtifacts, " "))
+	require.NoError(t, err)
+
+	t.Logf("Install script exited with %d after %s", result.ExitCode, duration.Round(time.Second))
+	t.Log(result.Output())
+	s.trackInstall(options.env(), result.Output())
+	return result
+}
+
+// InstallAgent runs the install script with the options, fails the test when the script fails, and returns its output
+func (s *linuxInstallerTestSuite) InstallAgent(options InstallOptions) string {
+	t := s.T()
+	t.Helper()
+	result := s.RunInstall(options)
+	require.True(t, result.Succeeded(), "install script exited with %d", result.ExitCode)
+	return result.Output()
+}
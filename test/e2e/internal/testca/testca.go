@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package testca is a throwaway certificate authority for the fake servers of the tests. The script forces https on
+// its URLs, so the hosts under test have to trust the CA of the servers. RSA keeps old curl and NSS builds happy.
+package testca
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authority signs server certificates, valid for a day
+type Authority struct {
+	organization string
+	key          *rsa.PrivateKey
+	certificate  *x509.Certificate
+	pem          []byte
+	notBefore    time.Time
+
+	mu     sync.Mutex
+	serial int64
+	// certificates caches the certificates by hosts, generating a key takes tens of milliseconds
+	certificates map[string]*tls.Certificate
+}
+
+// New returns a CA named after organization, e.g. "Datadog Test Registry"
+func New(organization string) (*Authority, error) {
+	notBefore := time.Now().Add(-time.Hour)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the CA key: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{organization}, CommonName: organization + " CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the CA certificate: %w", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &Authority{
+		organization: organization,
+		key:          key,
+		certificate:  certificate,
+		pem:          pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		notBefore:    notBefore,
+		serial:       1,
+		certificates: map[string]*tls.Certificate{},
+	}, nil
+}
+
+// PEM returns the certificate of the CA, for the trust stores of the hosts
+func (a *Authority) PEM() []byte {
+	return a.pem
+}
+
+// Certificate returns a server certificate for the names and addresses of hosts, the first one is its common name
+func (a *Authority) Certificate(hosts ...string) (*tls.Certificate, error) {
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no host to certify")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cacheKey := strings.Join(hosts, " ")
+	if certificate, ok := a.certificates[cacheKey]; ok {
+		return certificate, nil
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the key of %s: %w", hosts[0], err)
+	}
+	a.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(a.serial),
+		Subject:      pkix.Name{Organization: []string{a.organization}, CommonName: hosts[0]},
+		NotBefore:    a.notBefore,
+		NotAfter:     a.notBefore.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.certificate, &key.PublicKey, a.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the certificate of %s: %w", hosts[0], err)
+	}
+	certificate := &tls.Certificate{Certificate: [][]byte{der, a.certificate.Raw}, PrivateKey: key}
+	a.certificates[cacheKey] = certificate
+	return certificate, nil
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package testca
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificate(t *testing.T) {
+	authority, err := New("Datadog Test CA")
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(authority.PEM()))
+
+	certificate, err := authority.Certificate("yum.datadoghq.com", "127.0.0.1")
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "yum.datadoghq.com", leaf.Subject.CommonName)
+	for _, host := range []string{"yum.datadoghq.com", "127.0.0.1"} {
+		_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: host})
+		assert.NoError(t, err, host)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: "apt.datadoghq.com"})
+	assert.Error(t, err)
+
+	cached, err := authority.Certificate("yum.datadoghq.com", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Same(t, certificate, cached)
+	_, err = authority.Certificate()
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"fmt"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/inventory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inventoryStage is a state of the host the inventory is recorded in, once per test
+type inventoryStage string
+
+const (
+	// stageBaseline is recorded by RunInstall, before the first install of the test
+	stageBaseline inventoryStage = "baseline"
+	// stageInstalled is recorded by uninstall, before removing the package
+	stageInstalled inventoryStage = "installed"
+	// stageUninstalled is recorded by assertUninstall
+	stageUninstalled inventoryStage = "uninstalled"
+	// stagePurged is recorded by assertPurge, on the platforms that purge
+	stagePurged inventoryStage = "purged"
+)
+
+// recordInventory records the entries of inventory.DefaultRoots on the host for stage
+func (s *linuxInstallerTestSuite) recordInventory(stage inventoryStage) {
+	t := s.T()
+	t.Helper()
+	output := s.runScriptAsRoot(inventory.Command(inventory.DefaultRoots...))
+	recorded, err := inventory.Parse(output)
+	require.NoError(t, err)
+	if s.inventories == nil {
+		s.inventories = map[inventoryStage]inventory.Inventory{}
+	}
+	s.inventories[stage] = recorded
+	t.Logf("Recorded the %s inventory, %d entries", stage, len(recorded))
+}
+
+// inventoryDiff compares the inventories recorded for two stages of the test
+func (s *linuxInstallerTestSuite) inventoryDiff(from, to inventoryStage) inventory.Diff {
+	t := s.T()
+	t.Helper()
+	before, ok := s.inventories[from]
+	require.True(t, ok, "no %s inventory recorded", from)
+	after, ok := s.inventories[to]
+	require.True(t, ok, "no %s inventory recorded", to)
+	return inventory.Compare(before, after)
+}
+
+// resetInventories forgets the inventories recorded, once the test is over
+func (s *linuxInstallerTestSuite) resetInventories() {
+	s.inventories = nil
+}
+
+// assertUninstallInventory asserts that removing the package kept the repository set up by the script and the
+// configuration, and left no unit behind
+func (s *linuxInstallerTestSuite) assertUninstallInventory() {
+	t := s.T()
+	t.Helper()
+	installed := s.inventoryDiff(stageBaseline, stageInstalled)
+	leftovers := s.inventoryDiff(stageBaseline, stageUninstalled)
+	removed := s.inventoryDiff(stageInstalled, stageUninstalled)
+	t.Logf("Left after remove:\n%s", leftovers)
+
+	assert.Equal(t, installed.Of(inventory.Repository, inventory.Keyring).Files(), leftovers.Of(inventory.Repository, inventory.Keyring).Files(),
+		"the repository files and keyrings changed on remove")
+	var orphans []string
+	for _, change := range leftovers.Of(inventory.Unit).Kind(inventory.Added) {
+		if change.After.Type == inventory.File {
+			orphans = append(orphans, change.Path)
+		}
+	}
+	assert.Empty(t, orphans, "unit files left after remove")
+	configFile := fmt.Sprintf("/etc/%s/%s", s.baseName, s.configFile)
+	assert.NotContains(t, removed.Paths(), configFile, "the configuration changed on remove")
+}
+
+// assertPurgeInventory asserts that purging the package removed the configuration, the installed files and the
+// units, and only left the repository set up by the script
+func (s *linuxInstallerTestSuite) assertPurgeInventory() {
+	t := s.T()
+	t.Helper()
+	installed := s.inventoryDiff(stageBaseline, stageInstalled)
+	leftovers := s.inventoryDiff(stageBaseline, stagePurged)
+	t.Logf("Left after purge:\n%s", leftovers)
+
+	assert.Equal(t, installed.Of(inventory.Repository, inventory.Keyring).Files().Paths(), leftovers.Of(inventory.Repository, inventory.Keyring).Files().Paths(),
+		"the repository files and keyrings changed on purge")
+	left := leftovers.Of(inventory.Unit, inventory.Config, inventory.Install).Kind(inventory.Added)
+	assert.Empty(t, left.Paths(), "left after purge:\n%s", left)
+}
@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package inventory
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Category sorts the entries by what an install, an uninstall or a purge is expected to do with them
+type Category string
+
+const (
+	// Repository are the apt sources, yum and zypper repositories
+	Repository Category = "repository"
+	// Keyring are the keys of the repositories
+	Keyring Category = "keyring"
+	// Unit are the systemd, upstart and SysV service definitions, with their enablement links
+	Unit Category = "unit"
+	// Config are the configuration directories of the Agent, the DogStatsD and the FIPS proxy
+	Config Category = "config"
+	// Install are the files of the packages under /opt
+	Install Category = "install"
+	// Other is anything else, such as the users and groups or the caches of the package managers
+	Other Category = "other"
+)
+
+// categories are matched in order against the path prefixes
+var categories = []struct {
+	prefixes []string
+	category Category
+}{
+	{[]string{"/etc/apt/sources.list.d/", "/etc/yum.repos.d/", "/etc/zypp/repos.d/"}, Repository},
+	{[]string{"/usr/share/keyrings/", "/etc/apt/trusted.gpg.d/", "/etc/pki/rpm-gpg/"}, Keyring},
+	{[]string{"/lib/systemd/system/", "/usr/lib/systemd/system/", "/etc/systemd/system/", "/etc/init/", "/etc/init.d/"}, Unit},
+	{[]string{"/etc/datadog-"}, Config},
+	{[]string{"/opt/datadog-"}, Install},
+}
+
+// Categorize returns the category of a path
+func Categorize(path string) Category {
+	for _, c := range categories {
+		for _, prefix := range c.prefixes {
+			if strings.HasPrefix(path, prefix) {
+				return c.category
+			}
+		}
+	}
+	return Other
+}
+
+// ChangeKind tells how an entry changed between two inventories
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "added"
+	Removed  ChangeKind = "removed"
+	Modified ChangeKind = "modified"
+)
+
+// Change is an entry that differs between two inventories
+type Change struct {
+	Path     string
+	Category Category
+	Kind     ChangeKind
+	// Before and After are the states of the entry, the zero Entry when it is missing
+	Before Entry
+	After  Entry
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s %s (%s)", c.Kind, c.Path, c.After)
+	case Removed:
+		return fmt.Sprintf("%s %s (%s)", c.Kind, c.Path, c.Before)
+	}
+	return fmt.Sprintf("%s %s (%s -> %s)", c.Kind, c.Path, c.Before, c.After)
+}
+
+// Diff lists the changes between two inventories, sorted by path
+type Diff []Change
+
+// Compare returns the entries added, removed or modified from before to after
+func Compare(before, after Inventory) Diff {
+	var diff Diff
+	for path, old := range before {
+		current, ok := after[path]
+		switch {
+		case !ok:
+			diff = append(diff, Change{Path: path, Category: Categorize(path), Kind: Removed, Before: old})
+		case old != current:
+			diff = append(diff, Change{Path: path, Category: Categorize(path), Kind: Modified, Before: old, After: current})
+		}
+	}
+	for path, current := range after {
+		if _, ok := before[path]; !ok {
+			diff = append(diff, Change{Path: path, Category: Categorize(path), Kind: Added, After: current})
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool { return diff[i].Path < diff[j].Path })
+	return diff
+}
+
+// Of returns the changes of the categories
+func (d Diff) Of(categories ...Category) Diff {
+	var changes Diff
+	for _, change := range d {
+		if slices.Contains(categories, change.Category) {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Kind returns the changes of a kind
+func (d Diff) Kind(kind ChangeKind) Diff {
+	var changes Diff
+	for _, change := range d {
+		if change.Kind == kind {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Files returns the changes of files and links, leaving the directories out
+func (d Diff) Files() Diff {
+	var changes Diff
+	for _, change := range d {
+		if change.Before.Type != Directory && change.After.Type != Directory {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Paths returns the paths of the changes
+func (d Diff) Paths() []string {
+	paths := make([]string, 0, len(d))
+	for _, change := range d {
+		paths = append(paths, change.Path)
+	}
+	return paths
+}
+
+// String lists the changes grouped by category, for the logs of the suites
+func (d Diff) String() string {
+	var b strings.Builder
+	for _, category := range []Category{Repository, Keyring, Unit, Config, Install, Other} {
+		changes := d.Of(category)
+		if len(changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %d changes\n", category, len(changes))
+		for _, change := range changes {
+			fmt.Fprintf(&b, "  %s\n", change)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
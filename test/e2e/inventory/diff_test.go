@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategorize(t *testing.T) {
+	tests := map[string]Category{
+		"/etc/apt/sources.list.d/datadog.list":                              Repository,
+		"/etc/yum.repos.d/datadog.repo":                                     Repository,
+		"/etc/zypp/repos.d/datadog.repo":                                    Repository,
+		"/usr/share/keyrings/datadog-archive-keyring.gpg":                   Keyring,
+		"/etc/apt/trusted.gpg.d/datadog-archive-keyring.gpg":                Keyring,
+		"/lib/systemd/system/datadog-agent.service":                         Unit,
+		"/usr/lib/systemd/system/datadog-agent-trace.service":               Unit,
+		"/etc/systemd/system/multi-user.target.wants/datadog-agent.service": Unit,
+		"/etc/init.d/datadog-agent":                                         Unit,
+		"/etc/datadog-agent/datadog.yaml":                                   Config,
+		"/etc/datadog-fips-proxy/datadog-fips-proxy.cfg":                    Config,
+		"/opt/datadog-agent/bin/agent/agent":                                Install,
+		"/opt/datadog-packages/run":                                         Install,
+		"/etc/passwd":                                                       Other,
+		"/etc/environment":                                                  Other,
+		"/etc/apt/sources.list":                                             Other,
+	}
+	for path, category := range tests {
+		assert.Equal(t, category, Categorize(path), path)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	config := Entry{Type: File, Mode: 0640, Owner: "dd-agent", Group: "dd-agent", SHA256: "aa"}
+	before := Inventory{
+		"/etc/apt/sources.list.d/datadog.list":      {Type: File, Mode: 0644, Owner: "root", Group: "root", SHA256: "bb"},
+		"/etc/datadog-agent":                        {Type: Directory, Mode: 0755, Owner: "dd-agent", Group: "dd-agent"},
+		"/etc/datadog-agent/datadog.yaml":           config,
+		"/lib/systemd/system/datadog-agent.service": {Type: File, Mode: 0644, Owner: "root", Group: "root", SHA256: "cc"},
+	}
+	modified := config
+	modified.Mode = 0644
+	after := Inventory{
+		"/etc/apt/sources.list.d/datadog.list": before["/etc/apt/sources.list.d/datadog.list"],
+		"/etc/datadog-agent":                   before["/etc/datadog-agent"],
+		"/etc/datadog-agent/datadog.yaml":      modified,
+		"/opt/datadog-agent":                   {Type: Directory, Mode: 0755, Owner: "root", Group: "root"},
+		"/opt/datadog-agent/run":               {Type: Link, Owner: "root", Group: "root", Link: "/var/run"},
+	}
+
+	diff := Compare(before, after)
+	assert.Equal(t, []string{
+		"/etc/datadog-agent/datadog.yaml",
+		"/lib/systemd/system/datadog-agent.service",
+		"/opt/datadog-agent",
+		"/opt/datadog-agent/run",
+	}, diff.Paths())
+	assert.Equal(t, Diff{{
+		Path:     "/etc/datadog-agent/datadog.yaml",
+		Category: Config,
+		Kind:     Modified,
+		Before:   config,
+		After:    modified,
+	}}, diff.Of(Config))
+	assert.Equal(t, []string{"/lib/systemd/system/datadog-agent.service"}, diff.Kind(Removed).Paths())
+	assert.Equal(t, []string{"/opt/datadog-agent/run"}, diff.Of(Install).Files().Paths())
+	assert.Empty(t, diff.Of(Repository, Keyring))
+	assert.Empty(t, Compare(after, after))
+
+	assert.Equal(t, `unit: 1 changes
+  removed /lib/systemd/system/datadog-agent.service (file 0644 root:root cc)
+config: 1 changes
+  modified /etc/datadog-agent/datadog.yaml (file 0640 dd-agent:dd-agent aa -> file 0644 dd-agent:dd-agent aa)
+install: 2 changes
+  added /opt/datadog-agent (directory 0755 root:root)
+  added /opt/datadog-agent/run (link root:root -> /var/run)`, diff.String())
+}
@@ -0,0 +1,10 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package inventory records the file trees an install, an uninstall or a purge touch, with the type, owner, mode and
+// hash of each entry, and compares them. Changes are sorted into categories, the repository files, the keyrings, the
+// service units, the configuration and the installed files, so that suites assert on the leftovers of each step
+// rather than on a few known paths.
+package inventory
@@ -0,0 +1,243 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package inventory
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DefaultRoots are the trees an install writes to. /etc holds the repository directories of apt, yum and zypper, the
+// others are the keyrings and the systemd units installed by the packages.
+var DefaultRoots = []string{
+	"/etc",
+	"/opt",
+	"/usr/share/keyrings",
+	"/lib/systemd/system",
+	"/usr/lib/systemd/system",
+}
+
+// Type is the type of an entry of the inventory
+type Type string
+
+const (
+	File      Type = "file"
+	Directory Type = "directory"
+	Link      Type = "link"
+)
+
+// Entry is the state of a file, a directory or a symbolic link
+type Entry struct {
+	Type Type
+	// Mode holds the permission bits, it is 0 for links
+	Mode  fs.FileMode
+	Owner string
+	Group string
+	// SHA256 is the hash of the content of files, empty for the other types
+	SHA256 string
+	// Link is the target of a symbolic link, empty for the other types
+	Link string
+}
+
+func (e Entry) String() string {
+	switch e.Type {
+	case Link:
+		return fmt.Sprintf("link %s:%s -> %s", e.Owner, e.Group, e.Link)
+	case File:
+		return fmt.Sprintf("file %04o %s:%s %.12s", e.Mode, e.Owner, e.Group, e.SHA256)
+	}
+	return fmt.Sprintf("%s %04o %s:%s", e.Type, e.Mode, e.Owner, e.Group)
+}
+
+// Inventory maps the absolute paths of the entries to their state
+type Inventory map[string]Entry
+
+// Read records the entries under the roots seen from root, such as the root of a hermetic harness. The paths of the
+// inventory are the ones seen from root, missing roots are skipped.
+func Read(root string, roots ...string) (Inventory, error) {
+	inventory := Inventory{}
+	for _, dir := range roots {
+		start := filepath.Join(root, dir)
+		if _, err := os.Lstat(start); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.WalkDir(start, func(path string, dirEntry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			entry, ok, err := readEntry(path, dirEntry)
+			if err != nil || !ok {
+				return err
+			}
+			inventory["/"+strings.TrimPrefix(strings.TrimPrefix(path, root), "/")] = entry
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return inventory, nil
+}
+
+// readEntry returns the state of a file, a directory or a link, ok is false for the other types
+func readEntry(path string, dirEntry fs.DirEntry) (entry Entry, ok bool, err error) {
+	info, err := dirEntry.Info()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	if stat, isStat := info.Sys().(*syscall.Stat_t); isStat {
+		entry.Owner = userName(stat.Uid)
+		entry.Group = groupName(stat.Gid)
+	}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		entry.Type = Link
+		entry.Link, err = os.Readlink(path)
+	case info.IsDir():
+		entry.Type = Directory
+		entry.Mode = info.Mode().Perm()
+	case info.Mode().IsRegular():
+		entry.Type = File
+		entry.Mode = info.Mode().Perm()
+		var content []byte
+		content, err = os.ReadFile(path)
+		sum := sha256.Sum256(content)
+		entry.SHA256 = hex.EncodeToString(sum[:])
+	default:
+		return Entry{}, false, nil
+	}
+	return entry, true, err
+}
+
+// userName and groupName fall back to the numeric ids, as find does
+func userName(uid uint32) string {
+	id := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return id
+}
+
+func groupName(gid uint32) string {
+	id := strconv.FormatUint(uint64(gid), 10)
+	if g, err := user.LookupGroupId(id); err == nil {
+		return g.Name
+	}
+	return id
+}
+
+// Command returns a shell command recording the entries under the roots, for Parse. It needs to run as root to read
+// the configuration files. Roots are resolved first, so /lib/systemd/system is reported under /usr/lib on merged-usr
+// systems, and the hashes are computed in a single sha256sum pass.
+func Command(roots ...string) string {
+	return fmt.Sprintf(`roots=$(for r in %s; do [ -e "$r" ] && readlink -f "$r"; done | sort -u)
+if [ -z "$roots" ]; then printf 'entries \nhashes \n'; exit 0; fi
+printf 'entries %%s\n' "$(find -H $roots \( -type f -o -type d -o -type l \) -printf '%%y %%m %%u %%g %%p\0%%l\0' | base64 -w0)"
+printf 'hashes %%s\n' "$(find -H $roots -type f -print0 | xargs -0 -r sha256sum | base64 -w0)"`, strings.Join(roots, " "))
+}
+
+// Parse decodes the output of Command: an "entries" line of NUL separated "<type> <mode> <owner> <group> <path>" and
+// link target records, then a "hashes" line of sha256sum output, both encoded in base64
+func Parse(output string) (Inventory, error) {
+	sections := map[string][]byte{}
+	for _, line := range strings.Split(output, "\n") {
+		name, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if name == "" {
+			continue
+		}
+		if name != "entries" && name != "hashes" {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		sections[name] = decoded
+	}
+	records, ok := sections["entries"]
+	if !ok {
+		return nil, fmt.Errorf("no entries")
+	}
+	sums, ok := sections["hashes"]
+	if !ok {
+		return nil, fmt.Errorf("no hashes")
+	}
+
+	inventory := Inventory{}
+	fields := strings.Split(string(records), "\x00")
+	if len(fields)%2 != 1 || fields[len(fields)-1] != "" {
+		return nil, fmt.Errorf("truncated entries")
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		path, entry, err := parseRecord(fields[i], fields[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i/2+1, err)
+		}
+		inventory[path] = entry
+	}
+
+	for i, line := range strings.Split(string(sums), "\n") {
+		if line == "" {
+			continue
+		}
+		sum, path, err := parseSum(line)
+		if err != nil {
+			return nil, fmt.Errorf("hash %d: %w", i+1, err)
+		}
+		entry, ok := inventory[path]
+		if !ok || entry.Type != File {
+			return nil, fmt.Errorf("hash %d: %s is not a file of the inventory", i+1, path)
+		}
+		entry.SHA256 = sum
+		inventory[path] = entry
+	}
+	return inventory, nil
+}
+
+func parseRecord(record string, link string) (string, Entry, error) {
+	parts := strings.SplitN(record, " ", 5)
+	if len(parts) != 5 || parts[4] == "" {
+		return "", Entry{}, fmt.Errorf("invalid record %q", record)
+	}
+	mode, err := strconv.ParseUint(parts[1], 8, 32)
+	if err != nil {
+		return "", Entry{}, fmt.Errorf("invalid mode: %w", err)
+	}
+	entry := Entry{Mode: fs.FileMode(mode).Perm(), Owner: parts[2], Group: parts[3]}
+	switch parts[0] {
+	case "f":
+		entry.Type = File
+	case "d":
+		entry.Type = Directory
+	case "l":
+		entry = Entry{Type: Link, Owner: parts[2], Group: parts[3], Link: link}
+	default:
+		return "", Entry{}, fmt.Errorf("invalid type %q", parts[0])
+	}
+	return parts[4], entry, nil
+}
+
+// parseSum decodes a "<hash>  <path>" line of sha256sum, which starts with a backslash when the path is escaped
+func parseSum(line string) (sum string, path string, err error) {
+	escaped := strings.HasPrefix(line, `\`)
+	sum, path, ok := strings.Cut(strings.TrimPrefix(line, `\`), "  ")
+	if !ok || len(sum) != sha256.Size*2 {
+		return "", "", fmt.Errorf("invalid line %q", line)
+	}
+	if escaped {
+		path = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r").Replace(path)
+	}
+	return sum, path, nil
+}
@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package inventory
+
+import (
+	"encoding/base64"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiKey = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	etc := filepath.Join(dir, "etc", "datadog-agent")
+	require.NoError(t, os.MkdirAll(filepath.Join(etc, "conf.d"), 0755))
+	files := map[string]string{
+		"datadog.yaml":           "api_key: x\n",
+		"conf.d/with space.yaml": "",
+		"new\nline.yaml":         "x",
+		`back\slash.yaml`:        "y",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(etc, name), []byte(content), 0644))
+	}
+	require.NoError(t, os.Chmod(filepath.Join(etc, "datadog.yaml"), 0640))
+	require.NoError(t, os.Symlink("/opt/datadog-agent/run", filepath.Join(etc, "run")))
+
+	roots := []string{"/etc/datadog-agent", "/usr/share/keyrings"}
+	rooted := make([]string, 0, len(roots))
+	for _, root := range roots {
+		rooted = append(rooted, dir+root)
+	}
+	output, err := exec.Command("bash", "-c", Command(rooted...)).CombinedOutput()
+	require.NoError(t, err, string(output))
+	parsed, err := Parse(string(output))
+	require.NoError(t, err)
+
+	read, err := Read(dir, roots...)
+	require.NoError(t, err)
+	assert.Len(t, read, 7)
+	assert.Equal(t, Directory, read["/etc/datadog-agent/conf.d"].Type)
+	assert.Equal(t, Entry{Type: Link, Owner: read["/etc/datadog-agent"].Owner, Group: read["/etc/datadog-agent"].Group, Link: "/opt/datadog-agent/run"}, read["/etc/datadog-agent/run"])
+	datadogYAML := read["/etc/datadog-agent/datadog.yaml"]
+	assert.Equal(t, fs.FileMode(0640), datadogYAML.Mode)
+	// sha256 of "api_key: x\n"
+	assert.Equal(t, "0350ad445ffc9363e3f6ab6fbe4064a79eb1af34cd1fcce3f55b9fe2e498cea4", datadogYAML.SHA256)
+	assert.Len(t, parsed, len(read))
+	for path, entry := range read {
+		assert.Equal(t, entry, parsed[dir+path], path)
+	}
+
+	output, err = exec.Command("bash", "-c", Command(dir+"/missing")).CombinedOutput()
+	require.NoError(t, err, string(output))
+	parsed, err = Parse(string(output))
+	require.NoError(t, err)
+	assert.Empty(t, parsed)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, output := range []string{
+		"",
+		"entries \n",
+		"entries !!\nhashes \n",
+		"files \nhashes \n",
+		// x 644 root root /etc
+		"entries eCA2NDQgcm9vdCByb290IC9ldGMAAA==\nhashes \n",
+		// f 644 root root /etc, without the link target
+		"entries ZiA2NDQgcm9vdCByb290IC9ldGMA\nhashes \n",
+		// a hash of /etc, which is not in the entries
+		"entries \nhashes " + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 64)+"  /etc\n")) + "\n",
+	} {
+		_, err := Parse(output)
+		assert.Error(t, err, output)
+	}
+}
+
+// TestInstall records the inventory of a hermetic install: the packages are shims, so it only holds what the script
+// writes itself
+func TestInstall(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    []hermetic.Option
+		repository string
+		keyrings   []string
+	}{
+		{
+			name:       "debian",
+			repository: "/etc/apt/sources.list.d/datadog.list",
+			keyrings:   []string{"/usr/share/keyrings/datadog-archive-keyring.gpg"},
+		},
+		{
+			name:       "redhat",
+			options:    []hermetic.Option{hermetic.WithOS(hermetic.RedHat("9.4"))},
+			repository: "/etc/yum.repos.d/datadog.repo",
+		},
+		{
+			name:       "sles",
+			options:    []hermetic.Option{hermetic.WithOS(hermetic.SLES("15.5"))},
+			repository: "/etc/zypp/repos.d/datadog.repo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := hermetic.New(t, tt.options...)
+			before, err := Read(h.Path("/"), DefaultRoots...)
+			require.NoError(t, err)
+			result := h.Run(map[string]string{"DD_API_KEY": apiKey})
+			require.Equal(t, 0, result.ExitCode, result.Output)
+			after, err := Read(h.Path("/"), DefaultRoots...)
+			require.NoError(t, err)
+
+			diff := Compare(before, after)
+			t.Log(diff)
+			assert.Empty(t, diff.Kind(Removed), "an install removes nothing")
+			assert.Equal(t, []string{tt.repository}, diff.Of(Repository).Kind(Added).Files().Paths())
+			assert.Subset(t, diff.Of(Keyring).Kind(Added).Paths(), tt.keyrings)
+			assert.Contains(t, diff.Of(Config).Paths(), "/etc/datadog-agent/datadog.yaml")
+			assert.Equal(t, fs.FileMode(0640), after["/etc/datadog-agent/datadog.yaml"].Mode)
+			assert.NotEmpty(t, after[tt.repository].SHA256)
+
+			// a replay keeps the inventory, except install.json which gets a new identity
+			result = h.Run(map[string]string{"DD_API_KEY": apiKey})
+			require.Equal(t, 0, result.ExitCode, result.Output)
+			replayed, err := Read(h.Path("/"), DefaultRoots...)
+			require.NoError(t, err)
+			replayDiff := Compare(after, replayed)
+			changed := replayDiff.Of(Repository, Keyring, Config).Paths()
+			assert.Subset(t, []string{"/etc/datadog-agent/install.json"}, changed, replayDiff.String())
+		})
+	}
+}
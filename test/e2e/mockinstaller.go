@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/mockinstaller"
+	"github.com/stretchr/testify/require"
+)
+
+// installMockInstaller replaces datadog-installer on the host by the mock, with a fresh package set and call log. The
+// mock is removed at the end of the test.
+func (s *linuxInstallerTestSuite) installMockInstaller(installer mockinstaller.Installer) {
+	t := s.T()
+	vm := s.host()
+	t.Helper()
+	remove := "sudo rm -rf " + path.Dir(installer.LogPath()) + " " + strings.Join(mockinstaller.Paths, " ")
+	vm.MustExecute(remove)
+	t.Cleanup(func() { _, _ = vm.Execute(remove) })
+	script := base64.StdEncoding.EncodeToString([]byte(installer.Script()))
+	for i, bin := range mockinstaller.Paths {
+		_, err := vm.Execute(fmt.Sprintf("echo %s | base64 -d | sudo tee %s > /dev/null && sudo chmod 755 %s", script, bin, bin))
+		// /sbin is a symlink to /usr/sbin on most platforms, only the first path is required
+		if i == 0 {
+			require.NoError(t, err)
+		}
+	}
+}
+
+// mockInstallerCalls returns the invocations of the mock so far, in call order. The mock writes its log on its first
+// call, so the test fails when the script never called it.
+func (s *linuxInstallerTestSuite) mockInstallerCalls(installer mockinstaller.Installer) []mockinstaller.Call {
+	t := s.T()
+	vm := s.host()
+	t.Helper()
+	if _, err := vm.Execute("sudo test -f " + installer.LogPath()); err != nil {
+		require.FailNow(t, "mock never called", "no call log at %s, the script did not run %s", installer.LogPath(), strings.Join(mockinstaller.Paths, " or "))
+	}
+	content, err := vm.ReadFile(installer.LogPath())
+	require.NoError(t, err)
+	calls, err := mockinstaller.ParseLog(content)
+	require.NoError(t, err)
+	return calls
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package mockinstaller
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Call is an invocation of the mock
+type Call struct {
+	// Path is the path the mock was invoked as
+	Path string
+	Args []string
+	Env  map[string]string
+}
+
+// Subcommand returns the first argument of the call
+func (c Call) Subcommand() string {
+	if len(c.Args) == 0 {
+		return ""
+	}
+	return c.Args[0]
+}
+
+// Package returns the package an is-installed or install call is about, as a package name
+func (c Call) Package() string {
+	if len(c.Args) < 2 {
+		return ""
+	}
+	return PackageName(c.Args[1])
+}
+
+// PackageName turns an oci:// URL into the name of its package, e.g.
+// oci://install.datadoghq.com/apm-inject-package:latest into datadog-apm-inject. Names are returned as is.
+func PackageName(reference string) string {
+	if !strings.HasPrefix(reference, "oci://") {
+		return reference
+	}
+	name := reference[strings.LastIndex(reference, "/")+1:]
+	if i := strings.IndexAny(name, ":@"); i >= 0 {
+		name = name[:i]
+	}
+	return "datadog-" + strings.TrimSuffix(name, "-package")
+}
+
+// ParseLog parses the call log of the mock, in call order
+func ParseLog(content []byte) ([]Call, error) {
+	var calls []Call
+	for n, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if line == "" {
+			continue
+		}
+		encodedArgs, encodedEnv, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing environment", n+1)
+		}
+		args, err := base64.StdEncoding.DecodeString(encodedArgs)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: decode arguments: %w", n+1, err)
+		}
+		env, err := base64.StdEncoding.DecodeString(encodedEnv)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: decode environment: %w", n+1, err)
+		}
+		argv := strings.Split(strings.TrimSuffix(string(args), "\x00"), "\x00")
+		call := Call{Path: argv[0], Args: argv[1:], Env: map[string]string{}}
+		for _, variable := range strings.Split(string(env), "\x00") {
+			if key, value, ok := strings.Cut(variable, "="); ok {
+				call.Env[key] = value
+			}
+		}
+		calls = append(calls, call)
+	}
+	return calls, nil
+}
+
+// Queried returns the packages asked about with is-installed, in call order
+func Queried(calls []Call) []string {
+	return packages(calls, "is-installed")
+}
+
+// Delegated returns the packages installed through the mock, in call order
+func Delegated(calls []Call) []string {
+	return packages(calls, "install")
+}
+
+func packages(calls []Call, subcommand string) []string {
+	var names []string
+	for _, call := range calls {
+		if call.Subcommand() == subcommand && call.Package() != "" {
+			names = append(names, call.Package())
+		}
+	}
+	return names
+}
@@ -0,0 +1,9 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package mockinstaller generates a bash script standing in for datadog-installer. It answers from a package set
+// declared in Go and logs each invocation with its environment, so that tests can tell which packages were
+// delegated to the installer and which ones were left to the package manager.
+package mockinstaller
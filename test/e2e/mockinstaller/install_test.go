@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package mockinstaller
+
+import (
+	"os"
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/ssi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiKey = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+// installSSI stands for install-ssi.sh: the packages datadog-installer has are skipped, the injector is installed
+// with it and the libraries with apt
+const installSSI = `for pkg in datadog-apm-inject datadog-apm-library-python datadog-apm-library-ruby; do
+  datadog-installer is-installed "$pkg" && continue
+  if [ "$pkg" = datadog-apm-inject ]; then
+    datadog-installer install "oci://install.datadoghq.com/apm-inject-package:latest" || exit 1
+  else
+    apt-get install -y "$pkg" || exit 1
+  fi
+done
+`
+
+func TestDelegation(t *testing.T) {
+	installer := Installer{
+		Packages: []Package{{Name: "datadog-apm-library-python", Version: "3.99.0-1"}},
+		Dir:      t.TempDir(),
+	}
+	server := ssi.New(t, ssi.Response{Script: installSSI})
+	h := hermetic.New(t,
+		hermetic.WithPassthrough(`127\.0\.0\.1`),
+		hermetic.WithExecutable("/usr/bin/datadog-installer", installer.Script()),
+	)
+	env := server.Env()
+	env["DD_API_KEY"] = apiKey
+	env["DD_APM_INSTRUMENTATION_ENABLED"] = "host"
+	env["DD_INSTRUMENTATION_TELEMETRY_ENABLED"] = "false"
+	result := h.Run(env)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+	assert.NotContains(t, result.Output, "Error:")
+
+	log, err := os.ReadFile(installer.LogPath())
+	require.NoError(t, err)
+	calls, err := ParseLog(log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"datadog-apm-inject", "datadog-apm-library-python", "datadog-apm-library-ruby"}, Queried(calls))
+	assert.Equal(t, []string{"datadog-apm-inject"}, Delegated(calls))
+
+	_, ok := result.FindCall("apt-get", "install", "-y", "datadog-apm-library-ruby")
+	assert.True(t, ok, result.Transcript())
+	for _, pkg := range []string{"datadog-apm-inject", "datadog-apm-library-python"} {
+		_, ok := result.FindCall("apt-get", "install", "-y", pkg)
+		assert.False(t, ok, "%s is not left to apt", pkg)
+	}
+
+	// the trace context of the script reaches the installer through install-ssi.sh
+	agent, ok := result.FindCall("apt-get", "install", "datadog-agent")
+	require.True(t, ok, result.Transcript())
+	require.NotEmpty(t, agent.Env["DATADOG_TRACE_ID"])
+	for _, call := range calls {
+		assert.Equal(t, agent.Env["DATADOG_TRACE_ID"], call.Env["DATADOG_TRACE_ID"], call.Args)
+		assert.NotEmpty(t, call.Env["DATADOG_PARENT_ID"], call.Args)
+	}
+}
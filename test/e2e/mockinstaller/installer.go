@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package mockinstaller
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	// DefaultDir holds the installed packages and the call log on the host
+	DefaultDir = "/var/log/datadog-installer-mock"
+	// DefaultVersion is printed by the version subcommand
+	DefaultVersion = "7.99.0-1"
+	// LogName is the name of the call log in the directory of the mock
+	LogName = "calls.log"
+)
+
+// Paths are where the install scripts look for datadog-installer
+var Paths = []string{"/usr/local/bin/datadog-installer", "/sbin/datadog-installer"}
+
+// Package is a package the mock reports as installed until it is purged
+type Package struct {
+	Name    string
+	Version string
+}
+
+// Installer configures the mock
+type Installer struct {
+	// Version is printed by the version subcommand, DefaultVersion by default
+	Version string
+	// Packages are installed when the mock first runs
+	Packages []Package
+	// Dir holds the installed packages and the call log, DefaultDir by default
+	Dir string
+}
+
+// LogPath returns the path of the call log
+func (i Installer) LogPath() string {
+	return i.dir() + "/" + LogName
+}
+
+func (i Installer) dir() string {
+	if i.Dir == "" {
+		return DefaultDir
+	}
+	return i.Dir
+}
+
+// Script returns the content of the mock. It supports:
+//   - version, printing the version
+//   - is-installed <package>, exiting 0 when the package is installed and 1 otherwise
+//   - install <package or oci:// URL>, recording the package as installed
+//   - purge, forgetting all packages
+//
+// and answers "Unsupported command" with exit code 2 to anything else.
+func (i Installer) Script() string {
+	version := i.Version
+	if version == "" {
+		version = DefaultVersion
+	}
+	var script strings.Builder
+	if err := scriptTemplate.Execute(&script, map[string]any{"Dir": i.dir(), "Version": version, "Packages": i.Packages, "Log": LogName}); err != nil {
+		panic(fmt.Sprintf("render the mock installer: %s", err))
+	}
+	return script.String()
+}
+
+var scriptTemplate = template.Must(template.New("datadog-installer").Funcs(template.FuncMap{"quote": shellQuote}).Parse(`#!/bin/bash
+# datadog-installer mock generated by the e2e tests
+dir={{quote .Dir}}
+if [ ! -d "$dir/packages" ]; then
+  mkdir -p "$dir/packages"
+{{- range .Packages}}
+  echo {{quote .Version}} > "$dir/packages/"{{quote .Name}}
+{{- end}}
+fi
+# one line per call: its arguments then its environment, NUL separated and base64 encoded
+echo "$(printf '%s\0' "$0" "$@" | base64 -w0) $(env -0 | base64 -w0)" >> "$dir/{{.Log}}"
+
+# oci://install.datadoghq.com/apm-inject-package:latest is datadog-apm-inject
+package_name() {
+  case "$1" in
+    oci://*) local name="${1##*/}"; name="${name%%[:@]*}"; echo "datadog-${name%-package}" ;;
+    *) echo "$1" ;;
+  esac
+}
+
+package_version() {
+  case "$1" in
+    oci://*@*) echo "${1##*@}" ;;
+    oci://*/*:*) echo "${1##*:}" ;;
+    *) echo "latest" ;;
+  esac
+}
+
+case "$1" in
+  version|--version)
+    echo {{quote .Version}}
+    ;;
+  is-installed)
+    [ -n "$2" ] && [ -f "$dir/packages/$(package_name "$2")" ]
+    ;;
+  install)
+    [ -n "$2" ] || { echo "Error: missing package"; exit 1; }
+    package_version "$2" > "$dir/packages/$(package_name "$2")"
+    ;;
+  purge)
+    rm -f "$dir/packages/"*
+    ;;
+  *)
+    echo "Unsupported command"
+    exit 2
+    ;;
+esac
+`))
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package mockinstaller
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// run invokes the mock at path and returns its output and exit code
+func run(t *testing.T, path string, env []string, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(path, args...)
+	cmd.Env = append([]string{"PATH=" + os.Getenv("PATH")}, env...)
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(output), exitErr.ExitCode()
+	}
+	require.NoError(t, err, string(output))
+	return string(output), 0
+}
+
+func TestScript(t *testing.T) {
+	installer := Installer{
+		Version:  "7.60.0-1",
+		Packages: []Package{{Name: "datadog-apm-library-python", Version: "3.99.0-1"}},
+		Dir:      filepath.Join(t.TempDir(), "state"),
+	}
+	path := filepath.Join(t.TempDir(), "datadog-installer")
+	require.NoError(t, os.WriteFile(path, []byte(installer.Script()), 0755))
+	trace := []string{"DATADOG_TRACE_ID=1234", "DATADOG_PARENT_ID=5678"}
+
+	output, code := run(t, path, trace, "version")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "7.60.0-1\n", output)
+
+	_, code = run(t, path, trace, "is-installed", "datadog-apm-library-python")
+	assert.Equal(t, 0, code)
+	_, code = run(t, path, trace, "is-installed", "datadog-apm-inject")
+	assert.Equal(t, 1, code)
+
+	_, code = run(t, path, nil, "install", "oci://install.datadoghq.com/apm-inject-package:0.99.0-1")
+	assert.Equal(t, 0, code)
+	_, code = run(t, path, nil, "is-installed", "datadog-apm-inject")
+	assert.Equal(t, 0, code)
+	_, code = run(t, path, nil, "is-installed", "oci://install.datadoghq.com/apm-inject-package:latest")
+	assert.Equal(t, 0, code)
+	version, err := os.ReadFile(filepath.Join(installer.Dir, "packages", "datadog-apm-inject"))
+	require.NoError(t, err)
+	assert.Equal(t, "0.99.0-1\n", string(version))
+
+	output, code = run(t, path, nil, "remove", "datadog-apm-inject")
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "Unsupported command\n", output)
+
+	_, code = run(t, path, nil, "purge")
+	assert.Equal(t, 0, code)
+	for _, pkg := range []string{"datadog-apm-inject", "datadog-apm-library-python"} {
+		_, code = run(t, path, nil, "is-installed", pkg)
+		assert.Equal(t, 1, code, "%s is purged", pkg)
+	}
+
+	log, err := os.ReadFile(installer.LogPath())
+	require.NoError(t, err)
+	calls, err := ParseLog(log)
+	require.NoError(t, err)
+	require.Len(t, calls, 10)
+	assert.Equal(t, path, calls[0].Path)
+	assert.Equal(t, []string{"version"}, calls[0].Args)
+	assert.Equal(t, "1234", calls[1].Env["DATADOG_TRACE_ID"])
+	assert.Equal(t, "5678", calls[1].Env["DATADOG_PARENT_ID"])
+	assert.NotContains(t, calls[3].Env, "DATADOG_TRACE_ID")
+	assert.Equal(t, []string{"datadog-apm-inject"}, Delegated(calls))
+	assert.Equal(t, []string{
+		"datadog-apm-library-python", "datadog-apm-inject", "datadog-apm-inject", "datadog-apm-inject",
+		"datadog-apm-inject", "datadog-apm-library-python",
+	}, Queried(calls))
+}
+
+func TestPackageName(t *testing.T) {
+	for reference, name := range map[string]string{
+		"datadog-agent": "datadog-agent",
+		"oci://install.datadoghq.com/apm-inject-package:latest":        "datadog-apm-inject",
+		"oci://install.datad0g.com/pipeline-42/agent-package:7.99.0-1": "datadog-agent",
+		"oci://127.0.0.1:5000/apm-library-python-package@sha256:0123":  "datadog-apm-library-python",
+	} {
+		assert.Equal(t, name, PackageName(reference), reference)
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	calls, err := ParseLog(nil)
+	require.NoError(t, err)
+	assert.Empty(t, calls)
+
+	_, err = ParseLog([]byte("YQA=\n"))
+	assert.EqualError(t, err, "line 1: missing environment")
+	_, err = ParseLog([]byte("YQA= !\n"))
+	assert.ErrorContains(t, err, "line 1: decode environment")
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package packageholds parses the configurations listing the packages a package manager won't upgrade, as read from
+// the hosts under test
+package packageholds
+
+import "strings"
+
+// ParseYumExcludes returns the packages of the exclude and excludepkgs options of yum.conf, dnf.conf or repo files
+func ParseYumExcludes(conf string) []string {
+	var packages []string
+	for _, line := range strings.Split(conf, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if key = strings.TrimSpace(key); key != "exclude" && key != "excludepkgs" {
+			continue
+		}
+		packages = append(packages, strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+	return packages
+}
+
+// ParseZypperLocks returns the packages of the solvable_name lines of /etc/zypp/locks
+func ParseZypperLocks(locks string) []string {
+	var packages []string
+	for _, line := range strings.Split(locks, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "solvable_name:"); ok {
+			packages = append(packages, strings.TrimSpace(value))
+		}
+	}
+	return packages
+}
@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package packageholds
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYumExcludes(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     string
+		expected []string
+	}{
+		{
+			name:     "comma separated",
+			conf:     "[datadog]\nenabled=1\nexclude=datadog-apm-library-java,datadog-apm-library-python\n",
+			expected: []string{"datadog-apm-library-java", "datadog-apm-library-python"},
+		},
+		{
+			name:     "space separated",
+			conf:     "[main]\nexclude = kernel* datadog-agent\n",
+			expected: []string{"kernel*", "datadog-agent"},
+		},
+		{
+			name:     "excludepkgs",
+			conf:     "[main]\nexcludepkgs=datadog-agent, datadog-fips-proxy\n",
+			expected: []string{"datadog-agent", "datadog-fips-proxy"},
+		},
+		{
+			name:     "several files",
+			conf:     "[main]\nexclude=kernel\n[datadog]\nexclude=datadog-agent\n",
+			expected: []string{"kernel", "datadog-agent"},
+		},
+		{
+			name: "other options",
+			conf: "[datadog]\nname = Datadog, Inc.\nbaseurl = https://yum.datadoghq.com/stable/7/x86_64/\nexclude_from_weak=true\n",
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseYumExcludes(tt.conf))
+		})
+	}
+}
+
+func TestParseZypperLocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		locks    string
+		expected []string
+	}{
+		{
+			name:     "locks",
+			locks:    "type: package\nmatch_type: glob\ncase_sensitive: on\nsolvable_name: datadog-agent\n\ntype: package\nsolvable_name:  kernel-default \n",
+			expected: []string{"datadog-agent", "kernel-default"},
+		},
+		{
+			name:  "no name",
+			locks: "type: package\nmatch_type: glob\n",
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseZypperLocks(tt.locks))
+		})
+	}
+}
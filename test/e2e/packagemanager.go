@@ -0,0 +1,196 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/packageholds"
+)
+
+// packageManager runs the package operations the suites need on the host under test, with apt, yum/dnf or zypper
+type packageManager interface {
+	name() string
+	// installed reports whether the package is installed, a removed Debian package whose configuration files are
+	// left is not
+	installed(pkg string) (bool, error)
+	// installedVersion returns the version of an installed package, as epoch:version-release when it has an epoch
+	installedVersion(pkg string) (string, error)
+	remove(packages ...string) error
+	// purge removes the packages and their configuration files, only apt makes the difference with remove
+	purge(packages ...string) error
+	canPurge() bool
+	// repoFiles lists the repository definitions, such as /etc/apt/sources.list.d/datadog.list
+	repoFiles() ([]string, error)
+	// heldPackages lists the packages the package manager won't upgrade: apt holds, yum/dnf excludes, including the
+	// exclude line of datadog.repo, and zypper locks
+	heldPackages() ([]string, error)
+}
+
+// detectPackageManager returns the package manager of the host, probing apt, yum/dnf and zypper in that order
+func detectPackageManager(vm installerHost) (packageManager, error) {
+	if _, err := vm.Execute("command -v apt-get"); err == nil {
+		return aptPackageManager{vm}, nil
+	}
+	for _, bin := range []string{"yum", "dnf"} {
+		if _, err := vm.Execute("command -v " + bin); err == nil {
+			return yumPackageManager{rpmPackages{vm}, bin}, nil
+		}
+	}
+	if _, err := vm.Execute("command -v zypper"); err == nil {
+		return zypperPackageManager{rpmPackages{vm}}, nil
+	}
+	return nil, errors.New("unknown package manager, none of apt-get, yum, dnf and zypper is available")
+}
+
+// listFiles returns the files matching a shell glob, none when it doesn't match
+func listFiles(vm installerHost, glob string) ([]string, error) {
+	output, err := vm.Execute(fmt.Sprintf("for f in %s; do [ -e \"$f\" ] && echo \"$f\"; done; true", glob))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+type aptPackageManager struct {
+	vm installerHost
+}
+
+func (aptPackageManager) name() string {
+	return "apt"
+}
+
+func (p aptPackageManager) installed(pkg string) (bool, error) {
+	// dpkg-query fails on packages it has never seen
+	status, err := p.vm.Execute(fmt.Sprintf("dpkg-query -W -f='${Status}' %s 2>/dev/null || true", pkg))
+	if err != nil {
+		return false, err
+	}
+	return strings.HasSuffix(strings.TrimSpace(status), " installed"), nil
+}
+
+func (p aptPackageManager) installedVersion(pkg string) (string, error) {
+	ok, err := p.installed(pkg)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%s is not installed", pkg)
+	}
+	version, err := p.vm.Execute(fmt.Sprintf("dpkg-query -W -f='${Version}' %s", pkg))
+	return strings.TrimSpace(version), err
+}
+
+func (p aptPackageManager) remove(packages ...string) error {
+	_, err := p.vm.Execute(fmt.Sprintf("sudo apt-get remove -y %s", strings.Join(packages, " ")))
+	return err
+}
+
+func (p aptPackageManager) purge(packages ...string) error {
+	_, err := p.vm.Execute(fmt.Sprintf("sudo apt-get remove --purge -y %s", strings.Join(packages, " ")))
+	return err
+}
+
+func (aptPackageManager) canPurge() bool {
+	return true
+}
+
+func (p aptPackageManager) repoFiles() ([]string, error) {
+	return listFiles(p.vm, "/etc/apt/sources.list.d/*.list /etc/apt/sources.list.d/*.sources")
+}
+
+func (p aptPackageManager) heldPackages() ([]string, error) {
+	output, err := p.vm.Execute("apt-mark showhold")
+	return strings.Fields(output), err
+}
+
+// rpmPackages queries the rpm database, shared by yum/dnf and zypper
+type rpmPackages struct {
+	vm installerHost
+}
+
+func (p rpmPackages) installed(pkg string) (bool, error) {
+	output, err := p.vm.Execute(fmt.Sprintf("rpm -q %s >/dev/null 2>&1 && echo installed || true", pkg))
+	return strings.TrimSpace(output) == "installed", err
+}
+
+func (p rpmPackages) installedVersion(pkg string) (string, error) {
+	ok, err := p.installed(pkg)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%s is not installed", pkg)
+	}
+	version, err := p.vm.Execute(fmt.Sprintf("rpm -q --qf '%%|EPOCH?{%%{EPOCH}:}:{}|%%{VERSION}-%%{RELEASE}' %s", pkg))
+	return strings.TrimSpace(version), err
+}
+
+func (rpmPackages) canPurge() bool {
+	return false
+}
+
+type yumPackageManager struct {
+	rpmPackages
+	// bin is yum, or dnf on hosts without the yum alias
+	bin string
+}
+
+func (p yumPackageManager) name() string {
+	return p.bin
+}
+
+func (p yumPackageManager) remove(packages ...string) error {
+	_, err := p.vm.Execute(fmt.Sprintf("sudo %s remove -y %s", p.bin, strings.Join(packages, " ")))
+	return err
+}
+
+func (p yumPackageManager) purge(packages ...string) error {
+	return p.remove(packages...)
+}
+
+func (p yumPackageManager) repoFiles() ([]string, error) {
+	return listFiles(p.vm, "/etc/yum.repos.d/*.repo")
+}
+
+func (p yumPackageManager) heldPackages() ([]string, error) {
+	output, err := p.vm.Execute("sudo cat /etc/yum.conf /etc/dnf/dnf.conf /etc/yum.repos.d/*.repo 2>/dev/null || true")
+	if err != nil {
+		return nil, err
+	}
+	return packageholds.ParseYumExcludes(output), nil
+}
+
+type zypperPackageManager struct {
+	rpmPackages
+}
+
+func (zypperPackageManager) name() string {
+	return "zypper"
+}
+
+func (p zypperPackageManager) remove(packages ...string) error {
+	_, err := p.vm.Execute(fmt.Sprintf("sudo zypper --non-interactive remove %s", strings.Join(packages, " ")))
+	return err
+}
+
+func (p zypperPackageManager) purge(packages ...string) error {
+	return p.remove(packages...)
+}
+
+func (p zypperPackageManager) repoFiles() ([]string, error) {
+	return listFiles(p.vm, "/etc/zypp/repos.d/*.repo")
+}
+
+func (p zypperPackageManager) heldPackages() ([]string, error) {
+	output, err := p.vm.Execute("sudo cat /etc/zypp/locks 2>/dev/null || true")
+	if err != nil {
+		return nil, err
+	}
+	return packageholds.ParseZypperLocks(output), nil
+}
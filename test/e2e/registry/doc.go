@@ -0,0 +1,9 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package registry serves a throwaway OCI registry of stub Datadog packages, in place of install.datadoghq.com. The
+// packages installing through the Datadog installer reach it through DD_INSTALLER_REGISTRY_URL, so that the remote
+// updates and installer managed paths run offline, against packages whose versions the tests control.
+package registry
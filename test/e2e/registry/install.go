@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package registry
+
+import (
+	"strings"
+)
+
+// installFunctions pull a package for the architecture of the host and unpack it the way the installer lays out
+// the packages it manages. The manifests are compact JSON with the fields in a fixed order, sed is enough to read
+// the digests.
+const installFunctions = `set -eo pipefail
+registry="${DD_INSTALLER_REGISTRY_URL:-install.datadoghq.com}"
+case "$(uname -m)" in
+  x86_64) platform=amd64;;
+  aarch64) platform=arm64;;
+  *) echo "No Datadog package for $(uname -m)" >&2; exit 1;;
+esac
+
+install_package() {
+  local name="$1" version="$2"
+  local base="https://$registry/v2/${name#datadog-}-package"
+  local dir="${root:-}/opt/datadog-packages/$name"
+  local index manifest layer
+  index="$(curl -fsSL -H 'Accept: ` + MediaTypeIndex + `' "$base/manifests/$version")"
+  manifest="$(echo "$index" | sed -n 's/.*"digest":"\(sha256:[0-9a-f]*\)","size":[0-9]*,"platform":{"architecture":"'"$platform"'".*/\1/p')"
+  if [ -z "$manifest" ]; then
+    echo "No $platform image of $name $version in $registry" >&2
+    return 1
+  fi
+  layer="$(curl -fsSL -H 'Accept: ` + MediaTypeManifest + `' "$base/manifests/$manifest" | sed -n 's|.*"mediaType":"` + MediaTypeLayer + `","digest":"\(sha256:[0-9a-f]*\)".*|\1|p')"
+  if [ -z "$layer" ]; then
+    echo "No package layer in $name $version" >&2
+    return 1
+  fi
+  mkdir -p "$dir/$version"
+  curl -fsSL "$base/blobs/$layer" | tar -x -C "$dir/$version"
+  ln -sfn "$version" "$dir/stable"
+}
+`
+
+// InstallScript returns a bash snippet installing packages from the registry named by DD_INSTALLER_REGISTRY_URL,
+// as /opt/datadog-packages/<name>/<version> with a stable link to it. It stands for the maintainer scripts of the
+// agent packages, e.g. with hermetic.WithPostInstall, and installs under "$root" when set. curl has to trust the CA
+// of the registry.
+func InstallScript(packages ...Package) string {
+	var script strings.Builder
+	script.WriteString(installFunctions)
+	for _, pkg := range packages {
+		script.WriteString("install_package " + shellQuote(pkg.Name) + " " + shellQuote(pkg.Version) + "\n")
+	}
+	return script.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
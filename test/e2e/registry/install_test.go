@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package registry
+
+import (
+	"os"
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/agentconfig"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiKey = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+func TestRemoteUpdates(t *testing.T) {
+	packages := DefaultPackages(DefaultVersion)
+	installer, agent := packages[0], packages[1]
+	tests := []struct {
+		name          string
+		options       []hermetic.Option
+		arch          string
+		command       string
+		remoteUpdates bool
+	}{
+		{name: "ubuntu", arch: "x86_64", command: "apt-get", remoteUpdates: true},
+		{name: "ubuntu arm64", options: []hermetic.Option{hermetic.WithArch("aarch64")}, arch: "aarch64", command: "apt-get", remoteUpdates: true},
+		{name: "redhat", options: []hermetic.Option{hermetic.WithOS(hermetic.RedHat("9.4"))}, arch: "x86_64", command: "yum", remoteUpdates: true},
+		{name: "sles", options: []hermetic.Option{hermetic.WithOS(hermetic.SLES("15"))}, arch: "x86_64", command: "zypper", remoteUpdates: true},
+		{name: "without remote updates", arch: "x86_64", command: "apt-get"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(t)
+			h := hermetic.New(t, append(tt.options,
+				hermetic.WithPassthrough(`127\.0\.0\.1`),
+				hermetic.WithPostInstall("datadog-agent", InstallScript(installer, agent)),
+			)...)
+			env := r.Env()
+			env["CURL_CA_BUNDLE"] = r.CAFile()
+			env["DD_API_KEY"] = apiKey
+			if tt.remoteUpdates {
+				env["DD_REMOTE_UPDATES"] = "true"
+			}
+			result := h.Run(env)
+			require.Equal(t, 0, result.ExitCode, result.Output)
+
+			install, ok := result.FindCall(tt.command, "install", "datadog-agent")
+			require.True(t, ok, result.Transcript())
+			assert.Equal(t, r.Host(), install.Env["DD_INSTALLER_REGISTRY_URL"])
+
+			var manifests, blobs []string
+			for _, pkg := range []Package{installer, agent} {
+				image, ok := r.Image(pkg, tt.arch)
+				require.True(t, ok)
+				manifests = append(manifests, pkg.Repository()+":"+pkg.Version, pkg.Repository()+"@"+image.Manifest)
+				blobs = append(blobs, image.Layer)
+
+				dir := "/opt/datadog-packages/" + pkg.Name
+				for name, content := range pkg.Files {
+					assert.Equal(t, content, h.ReadFile(dir+"/"+pkg.Version+"/"+name))
+				}
+				stable, err := os.Readlink(h.Path(dir + "/stable"))
+				require.NoError(t, err)
+				assert.Equal(t, pkg.Version, stable)
+			}
+			assert.Equal(t, manifests, r.PulledManifests())
+			assert.Equal(t, blobs, r.PulledBlobs())
+			assert.True(t, h.FileExists("/opt/datadog-packages/datadog-agent/stable/bin/agent/agent"))
+
+			var datadog agentconfig.Datadog
+			require.NoError(t, agentconfig.Parse([]byte(h.ReadFile("/etc/datadog-agent/datadog.yaml")), &datadog))
+			assert.Equal(t, tt.remoteUpdates, datadog.Has("remote_updates"))
+			assert.Equal(t, tt.remoteUpdates, datadog.RemoteUpdates)
+		})
+	}
+}
+
+func TestRemoteUpdatesMissingArchitecture(t *testing.T) {
+	r := New(t, WithArchitectures("x86_64"))
+	h := hermetic.New(t,
+		hermetic.WithArch("aarch64"),
+		hermetic.WithPassthrough(`127\.0\.0\.1`),
+		hermetic.WithPostInstall("datadog-agent", InstallScript(DefaultPackages(DefaultVersion)[1])),
+	)
+	env := r.Env()
+	env["CURL_CA_BUNDLE"] = r.CAFile()
+	env["DD_API_KEY"] = apiKey
+	env["DD_REMOTE_UPDATES"] = "true"
+	result := h.Run(env)
+
+	assert.NotEqual(t, 0, result.ExitCode, result.Output)
+	assert.Contains(t, result.Output, "No arm64 image of datadog-agent 7.99.0-1 in "+r.Host())
+	assert.Equal(t, []string{"agent-package:7.99.0-1"}, r.PulledManifests())
+	assert.Empty(t, r.PulledBlobs())
+	assert.False(t, h.FileExists("/opt/datadog-packages/datadog-agent/stable"))
+}
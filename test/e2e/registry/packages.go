@@ -0,0 +1,242 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultVersion is the version of the default installer and agent packages, above any released one
+const DefaultVersion = "7.99.0-1"
+
+// Media types and annotations of the Datadog packages
+const (
+	MediaTypeIndex    = "application/vnd.oci.image.index.v1+json"
+	MediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeConfig   = "application/vnd.oci.image.config.v1+json"
+	// MediaTypeLayer is the layer holding the files of a package. The real layers are compressed with zstd, the stub
+	// ones are plain tarballs so that any host can unpack them.
+	MediaTypeLayer = "application/vnd.datadog.package.layer.v1.tar"
+
+	AnnotationPackage = "com.datadoghq.package.name"
+	AnnotationVersion = "com.datadoghq.package.version"
+)
+
+// ociArchitectures maps `uname -m` to the OCI platform architectures
+var ociArchitectures = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+}
+
+// Package is a stub package published for every architecture of the registry
+type Package struct {
+	// Name is the name of the package, e.g. datadog-agent, served as the agent-package repository
+	Name    string
+	Version string
+	// Files are unpacked in /opt/datadog-packages/<name>/<version>, keyed by path relative to it. Contents starting
+	// with "#!" are executable.
+	Files map[string]string
+}
+
+// Repository returns the repository the package is served from
+func (p Package) Repository() string {
+	return strings.TrimPrefix(p.Name, "datadog-") + "-package"
+}
+
+// DefaultPackages returns stubs of the installer and agent packages at version, and of the APM injector and Python
+// library, which have their own versions
+func DefaultPackages(version string) []Package {
+	return []Package{
+		{
+			Name:    "datadog-installer",
+			Version: version,
+			Files: map[string]string{
+				"bin/installer/installer": stubBinary("Datadog Installer", version),
+			},
+		},
+		{
+			Name:    "datadog-agent",
+			Version: version,
+			Files: map[string]string{
+				"bin/agent/agent":      stubBinary("Agent", version),
+				"version-manifest.txt": fmt.Sprintf("datadog-agent %s\n", version),
+			},
+		},
+		{
+			Name:    "datadog-apm-inject",
+			Version: "0.99.0-1",
+			Files: map[string]string{
+				"inject/launcher.preload.so": "stub APM injector\n",
+			},
+		},
+		{
+			Name:    "datadog-apm-library-python",
+			Version: "3.99.0-1",
+			Files: map[string]string{
+				"version": "3.99.0\n",
+			},
+		},
+	}
+}
+
+func stubBinary(name string, version string) string {
+	return fmt.Sprintf("#!/bin/sh\necho \"%s %s - Commit: stub\"\n", name, strings.SplitN(version, "-", 2)[0])
+}
+
+// Image is the digests of a package for one architecture
+type Image struct {
+	// Index is the digest of the image index the version tag points to, listing every architecture
+	Index    string
+	Manifest string
+	Config   string
+	Layer    string
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *platform         `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+// manifest is an image manifest, or an image index when it lists manifests
+type manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	Config        *descriptor       `json:"config,omitempty"`
+	Layers        []descriptor      `json:"layers,omitempty"`
+	Manifests     []descriptor      `json:"manifests,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// content is a manifest or a blob, addressed by its digest
+type content struct {
+	mediaType string
+	data      []byte
+}
+
+func newContent(mediaType string, data []byte) content {
+	return content{mediaType: mediaType, data: data}
+}
+
+func (c content) digest() string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(c.data))
+}
+
+func (c content) descriptor() descriptor {
+	return descriptor{MediaType: c.mediaType, Digest: c.digest(), Size: int64(len(c.data))}
+}
+
+// built is a package published for every architecture: the index, the manifests and blobs it references, and the
+// digests of each architecture
+type built struct {
+	index     content
+	manifests []content
+	blobs     []content
+	images    map[string]Image
+}
+
+func buildPackage(pkg Package, archs []string, buildTime time.Time) (*built, error) {
+	annotations := map[string]string{AnnotationPackage: pkg.Name, AnnotationVersion: pkg.Version}
+	layer, err := layerTar(pkg.Files, buildTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the layer of %s: %w", pkg.Name, err)
+	}
+	layerContent := newContent(MediaTypeLayer, layer)
+
+	b := &built{images: map[string]Image{}, blobs: []content{layerContent}}
+	index := manifest{SchemaVersion: 2, MediaType: MediaTypeIndex, Annotations: annotations}
+	for _, arch := range archs {
+		ociArch, ok := ociArchitectures[arch]
+		if !ok {
+			return nil, fmt.Errorf("unsupported architecture %s", arch)
+		}
+		config, err := json.Marshal(platform{Architecture: ociArch, OS: "linux"})
+		if err != nil {
+			return nil, err
+		}
+		configContent := newContent(MediaTypeConfig, config)
+		configDescriptor := configContent.descriptor()
+		data, err := json.Marshal(manifest{
+			SchemaVersion: 2,
+			MediaType:     MediaTypeManifest,
+			Config:        &configDescriptor,
+			Layers:        []descriptor{layerContent.descriptor()},
+			Annotations:   annotations,
+		})
+		if err != nil {
+			return nil, err
+		}
+		manifestContent := newContent(MediaTypeManifest, data)
+		b.manifests = append(b.manifests, manifestContent)
+		b.blobs = append(b.blobs, configContent)
+
+		manifestDescriptor := manifestContent.descriptor()
+		manifestDescriptor.Platform = &platform{Architecture: ociArch, OS: "linux"}
+		index.Manifests = append(index.Manifests, manifestDescriptor)
+		b.images[arch] = Image{Manifest: manifestContent.digest(), Config: configContent.digest(), Layer: layerContent.digest()}
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	b.index = newContent(MediaTypeIndex, data)
+	for arch, image := range b.images {
+		image.Index = b.index.digest()
+		b.images[arch] = image
+	}
+	return b, nil
+}
+
+// layerTar returns the uncompressed tarball of files, sorted so that the digests only depend on the content
+func layerTar(files map[string]string, buildTime time.Time) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		mode := int64(0644)
+		if strings.HasPrefix(files[name], "#!") {
+			mode = 0755
+		}
+		header := &tar.Header{
+			Name:    "./" + strings.TrimPrefix(name, "/"),
+			Mode:    mode,
+			Size:    int64(len(files[name])),
+			ModTime: buildTime,
+			Uname:   "root",
+			Gname:   "root",
+			Format:  tar.FormatGNU,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
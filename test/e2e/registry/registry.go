@@ -0,0 +1,307 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package registry
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/internal/testca"
+	"github.com/stretchr/testify/require"
+)
+
+// Option configures a Registry
+type Option func(*Registry)
+
+// WithPackages replaces the packages served, DefaultPackages(DefaultVersion) by default. Several versions of a
+// package can be served at once, the last one listed is tagged latest.
+func WithPackages(packages ...Package) Option {
+	return func(r *Registry) { r.packages = packages }
+}
+
+// WithArchitectures sets the architectures images are published for, as `uname -m` reports them, x86_64 and
+// aarch64 by default
+func WithArchitectures(archs ...string) Option {
+	return func(r *Registry) { r.archs = archs }
+}
+
+// WithHosts sets the names and addresses the TLS certificate is valid for, the first one is used by Host.
+// 127.0.0.1 by default, a test VM needs the address of the runner instead.
+func WithHosts(hosts ...string) Option {
+	return func(r *Registry) { r.hosts = hosts }
+}
+
+// WithListenAddress sets where the server listens, 127.0.0.1:0 by default
+func WithListenAddress(address string) Option {
+	return func(r *Registry) { r.listenAddress = address }
+}
+
+// Request is a request received by the registry
+type Request struct {
+	Method     string
+	Repository string
+	// Kind is manifests, blobs or tags
+	Kind string
+	// Reference is the tag or digest asked for, list for the tags
+	Reference string
+	Status    int
+}
+
+func (r Request) String() string {
+	return fmt.Sprintf("%s %s/%s/%s %d", r.Method, r.Repository, r.Kind, r.Reference, r.Status)
+}
+
+// repository holds the content of one repository, e.g. agent-package
+type repository struct {
+	tags      map[string]string
+	manifests map[string]content
+	blobs     map[string]content
+}
+
+// Registry is a throwaway OCI registry serving the distribution API over https, read only:
+//
+//	/v2/                                   version check
+//	/v2/<repository>/manifests/<reference> image index by version tag, or manifest by digest
+//	/v2/<repository>/blobs/<digest>        image config and package layer
+//	/v2/<repository>/tags/list
+type Registry struct {
+	packages      []Package
+	archs         []string
+	hosts         []string
+	listenAddress string
+
+	repositories map[string]*repository
+	images       map[string]map[string]Image
+	caFile       string
+	server       *httptest.Server
+	mu           sync.Mutex
+	requests     []Request
+}
+
+// New publishes the packages and serves them until the end of the test
+func New(t testing.TB, options ...Option) *Registry {
+	t.Helper()
+	r := &Registry{
+		packages:      DefaultPackages(DefaultVersion),
+		archs:         []string{"x86_64", "aarch64"},
+		hosts:         []string{"127.0.0.1"},
+		listenAddress: "127.0.0.1:0",
+	}
+	for _, option := range options {
+		option(r)
+	}
+	require.NoError(t, r.build(time.Now()))
+
+	authority, err := testca.New("Datadog Test Registry")
+	require.NoError(t, err)
+	certificate, err := authority.Certificate(r.hosts...)
+	require.NoError(t, err)
+	r.caFile = filepath.Join(t.TempDir(), "registry-ca.pem")
+	require.NoError(t, os.WriteFile(r.caFile, authority.PEM(), 0644))
+
+	listener, err := net.Listen("tcp", r.listenAddress)
+	require.NoError(t, err)
+	r.server = httptest.NewUnstartedServer(http.HandlerFunc(r.handle))
+	r.server.Listener.Close()
+	r.server.Listener = listener
+	r.server.TLS = &tls.Config{Certificates: []tls.Certificate{*certificate}}
+	r.server.StartTLS()
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+// Host returns the host:port the hosts have to reach, the value of DD_INSTALLER_REGISTRY_URL
+func (r *Registry) Host() string {
+	_, port, _ := net.SplitHostPort(r.server.Listener.Addr().String())
+	return net.JoinHostPort(r.hosts[0], port)
+}
+
+// Env returns the variables pointing the packages installed by the script to the registry
+func (r *Registry) Env() map[string]string {
+	return map[string]string{
+		"DD_INSTALLER_REGISTRY_URL": r.Host(),
+	}
+}
+
+// CAFile returns the path of the PEM certificate of the CA that signed the server certificate. Hosts have to trust
+// it, e.g. through CURL_CA_BUNDLE or the system trust store.
+func (r *Registry) CAFile() string {
+	return r.caFile
+}
+
+// Image returns the digests of a package for arch, as `uname -m` reports it
+func (r *Registry) Image(pkg Package, arch string) (Image, bool) {
+	image, ok := r.images[pkg.Repository()+":"+pkg.Version][arch]
+	return image, ok
+}
+
+// Requests returns the requests received so far, in order
+func (r *Registry) Requests() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Request{}, r.requests...)
+}
+
+// PulledManifests returns the manifests downloaded so far, as <repository>:<tag> or <repository>@<digest>
+func (r *Registry) PulledManifests() []string {
+	var manifests []string
+	for _, request := range r.pulled("manifests") {
+		separator := ":"
+		if strings.HasPrefix(request.Reference, "sha256:") {
+			separator = "@"
+		}
+		manifests = append(manifests, request.Repository+separator+request.Reference)
+	}
+	return manifests
+}
+
+// PulledBlobs returns the digests of the blobs downloaded so far
+func (r *Registry) PulledBlobs() []string {
+	var blobs []string
+	for _, request := range r.pulled("blobs") {
+		blobs = append(blobs, request.Reference)
+	}
+	return blobs
+}
+
+// pulled returns the successful downloads of kind, HEAD requests only check for existence
+func (r *Registry) pulled(kind string) []Request {
+	var requests []Request
+	for _, request := range r.Requests() {
+		if request.Method == http.MethodGet && request.Kind == kind && request.Status == http.StatusOK {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+func (r *Registry) build(now time.Time) error {
+	r.repositories = map[string]*repository{}
+	r.images = map[string]map[string]Image{}
+	for _, pkg := range r.packages {
+		b, err := buildPackage(pkg, r.archs, now)
+		if err != nil {
+			return err
+		}
+		repo, ok := r.repositories[pkg.Repository()]
+		if !ok {
+			repo = &repository{tags: map[string]string{}, manifests: map[string]content{}, blobs: map[string]content{}}
+			r.repositories[pkg.Repository()] = repo
+		}
+		for _, manifest := range append([]content{b.index}, b.manifests...) {
+			repo.manifests[manifest.digest()] = manifest
+		}
+		for _, blob := range b.blobs {
+			repo.blobs[blob.digest()] = blob
+		}
+		repo.tags[pkg.Version] = b.index.digest()
+		repo.tags["latest"] = b.index.digest()
+		r.images[pkg.Repository()+":"+pkg.Version] = b.images
+	}
+	return nil
+}
+
+func (r *Registry) handle(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		registryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "the registry is read only")
+		return
+	}
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	if req.URL.Path == "/v2/" || req.URL.Path == "/v2" {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+		return
+	}
+
+	// /v2/<repository>/<kind>/<reference>, the repository may contain slashes
+	request := Request{Method: req.Method, Status: http.StatusNotFound}
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	if rest, reference, ok := cutLast(path); ok {
+		request.Reference = reference
+		if repository, kind, ok := cutLast(rest); ok {
+			request.Repository, request.Kind = repository, kind
+		}
+	}
+	request.Status = r.serve(w, req, request)
+	r.mu.Lock()
+	r.requests = append(r.requests, request)
+	r.mu.Unlock()
+}
+
+// serve answers a distribution API request and returns the status sent
+func (r *Registry) serve(w http.ResponseWriter, req *http.Request, request Request) int {
+	repo, ok := r.repositories[request.Repository]
+	if !ok {
+		return registryError(w, http.StatusNotFound, "NAME_UNKNOWN", "unknown repository "+request.Repository)
+	}
+	var found content
+	switch request.Kind {
+	case "manifests":
+		digest := request.Reference
+		if tagged, ok := repo.tags[digest]; ok {
+			digest = tagged
+		}
+		if found, ok = repo.manifests[digest]; !ok {
+			return registryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "unknown manifest "+request.Reference)
+		}
+	case "blobs":
+		if found, ok = repo.blobs[request.Reference]; !ok {
+			return registryError(w, http.StatusNotFound, "BLOB_UNKNOWN", "unknown blob "+request.Reference)
+		}
+		found.mediaType = "application/octet-stream"
+	case "tags":
+		tags := make([]string, 0, len(repo.tags))
+		for tag := range repo.tags {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		data, err := json.Marshal(map[string]any{"name": request.Repository, "tags": tags})
+		if err != nil {
+			return registryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		}
+		found = newContent("application/json", data)
+	default:
+		return registryError(w, http.StatusNotFound, "UNSUPPORTED", "unknown endpoint "+req.URL.Path)
+	}
+	w.Header().Set("Content-Type", found.mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(found.data)))
+	if request.Kind != "tags" {
+		w.Header().Set("Docker-Content-Digest", found.digest())
+	}
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(found.data)
+	}
+	return http.StatusOK
+}
+
+// registryError sends an error in the format of the distribution API and returns its status
+func registryError(w http.ResponseWriter, status int, code string, message string) int {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"errors": []map[string]string{{"code": code, "message": message}}})
+	return status
+}
+
+func cutLast(path string) (string, string, bool) {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "", "", false
+	}
+	return path[:i], path[i+1:], true
+}
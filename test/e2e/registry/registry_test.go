@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func httpsClient(t *testing.T, r *Registry) *http.Client {
+	t.Helper()
+	ca, err := os.ReadFile(r.CAFile())
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(ca))
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+}
+
+func fetch(t *testing.T, client *http.Client, r *Registry, path string) (*http.Response, []byte) {
+	t.Helper()
+	resp, err := client.Get("https://" + r.Host() + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, body
+}
+
+func TestPullPackages(t *testing.T) {
+	r := New(t)
+	client := httpsClient(t, r)
+
+	resp, _ := fetch(t, client, r, "/v2/")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "registry/2.0", resp.Header.Get("Docker-Distribution-API-Version"))
+
+	for _, pkg := range DefaultPackages(DefaultVersion) {
+		base := "/v2/" + pkg.Repository()
+		resp, body := fetch(t, client, r, base+"/manifests/"+pkg.Version)
+		require.Equal(t, http.StatusOK, resp.StatusCode, pkg.Name)
+		assert.Equal(t, MediaTypeIndex, resp.Header.Get("Content-Type"))
+		var index manifest
+		require.NoError(t, json.Unmarshal(body, &index))
+		assert.Equal(t, map[string]string{AnnotationPackage: pkg.Name, AnnotationVersion: pkg.Version}, index.Annotations)
+		require.Len(t, index.Manifests, 2)
+
+		for i, arch := range []string{"x86_64", "aarch64"} {
+			image, ok := r.Image(pkg, arch)
+			require.True(t, ok)
+			assert.Equal(t, resp.Header.Get("Docker-Content-Digest"), image.Index)
+			assert.Equal(t, image.Manifest, index.Manifests[i].Digest)
+			assert.Equal(t, &platform{Architecture: ociArchitectures[arch], OS: "linux"}, index.Manifests[i].Platform)
+
+			resp, body := fetch(t, client, r, base+"/manifests/"+image.Manifest)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			var m manifest
+			require.NoError(t, json.Unmarshal(body, &m))
+			require.Len(t, m.Layers, 1)
+			assert.Equal(t, MediaTypeLayer, m.Layers[0].MediaType)
+			assert.Equal(t, image.Layer, m.Layers[0].Digest)
+			assert.Equal(t, image.Config, m.Config.Digest)
+
+			resp, body = fetch(t, client, r, base+"/blobs/"+image.Layer)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, image.Layer, newContent(MediaTypeLayer, body).digest())
+			files := map[string]string{}
+			reader := tar.NewReader(bytes.NewReader(body))
+			for {
+				header, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				content, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				files[header.Name[len("./"):]] = string(content)
+			}
+			assert.Equal(t, pkg.Files, files)
+		}
+	}
+
+	_, body := fetch(t, client, r, "/v2/agent-package/tags/list")
+	assert.JSONEq(t, `{"name": "agent-package", "tags": ["7.99.0-1", "latest"]}`, string(body))
+	resp, _ = fetch(t, client, r, "/v2/agent-package/manifests/7.0.0-1")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = fetch(t, client, r, "/v2/unknown-package/manifests/latest")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	agent := DefaultPackages(DefaultVersion)[1]
+	image, _ := r.Image(agent, "x86_64")
+	assert.Contains(t, r.PulledManifests(), "agent-package:7.99.0-1")
+	assert.Contains(t, r.PulledManifests(), "agent-package@"+image.Manifest)
+	assert.NotContains(t, r.PulledManifests(), "agent-package:7.0.0-1", "failed pulls are not listed")
+	assert.Contains(t, r.PulledBlobs(), image.Layer)
+	assert.Equal(t, "GET unknown-package/manifests/latest 404", r.Requests()[len(r.Requests())-1].String())
+}
+
+func TestLatestTag(t *testing.T) {
+	old, latest := DefaultPackages("7.60.1-1")[1], DefaultPackages("7.61.0-1")[1]
+	r := New(t, WithPackages(old, latest), WithArchitectures("aarch64"))
+	client := httpsClient(t, r)
+
+	image, ok := r.Image(latest, "aarch64")
+	require.True(t, ok)
+	_, ok = r.Image(latest, "x86_64")
+	assert.False(t, ok)
+	resp, _ := fetch(t, client, r, "/v2/agent-package/manifests/latest")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, image.Index, resp.Header.Get("Docker-Content-Digest"))
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"github.com/DataDog/agent-linux-install-script/test/e2e/replay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshot reads the files of the host matching the globs, replay.DefaultPaths when none is given
+func (s *linuxInstallerTestSuite) snapshot(globs ...string) replay.Snapshot {
+	t := s.T()
+	t.Helper()
+	if len(globs) == 0 {
+		globs = replay.DefaultPaths
+	}
+	output := s.runScriptAsRoot(replay.Command(globs...))
+	snapshot, err := replay.Parse(output)
+	require.NoError(t, err)
+	return snapshot
+}
+
+// replayInstall runs the script again with options, and asserts that it only changed the files and entries the
+// allowances cover, besides install.json. It returns the output of the replay and all its changes.
+func (s *linuxInstallerTestSuite) replayInstall(options InstallOptions, allowances ...replay.Allowance) (string, replay.Diff) {
+	t := s.T()
+	t.Helper()
+	before := s.snapshot()
+	output := s.InstallAgent(options)
+	diff := replay.Compare(before, s.snapshot())
+	t.Logf("Replay changes:\n%s", diff)
+	unexpected := diff.Unexpected(append(allowances, replay.InstallIdentity...)...)
+	assert.Empty(t, unexpected.Paths(), "unexpected changes on replay:\n%s", unexpected)
+	return output, diff
+}
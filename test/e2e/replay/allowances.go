@@ -0,0 +1,36 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import "regexp"
+
+// InstallIdentity is install.json, which the script only reuses when its install_type matches at fixed offsets: the
+// installs writing it have an empty install_type, so each replay writes a new install_time, and a new install_id
+// unless the uuid source is stable
+var InstallIdentity = AllowFiles("/etc/datadog-agent/install.json")
+
+// The settings below are applied on every run, even when datadog.yaml is kept, so a replay with different options
+// changes them
+var (
+	// DDOT are the blocks update_ddot prepends to datadog.yaml, with the backup it makes, the configuration of the
+	// collector and its repository
+	DDOT = append([]Allowance{{
+		Path:  "/etc/datadog-agent/datadog.yaml",
+		Lines: regexp.MustCompile(`^(otelcollector|agent_ipc):$|^  (enabled|port|config_refresh_interval): `),
+	}}, AllowFiles(
+		"/etc/datadog-agent/datadog.yaml.orig",
+		"/etc/datadog-agent/otel-config.yaml",
+		"/etc/apt/sources.list.d/datadog-ddot.list",
+	)...)
+	// SBOM are the entries manage_infrastructure_vulnerabilities_config sets in the environment file of the Agent
+	SBOM = []Allowance{
+		AllowEntries("/etc/datadog-agent/environment", "DD_SBOM_ENABLED", "DD_SBOM_CONTAINER_IMAGE_ENABLED", "DD_SBOM_HOST_ENABLED"),
+	}
+	// ErrorTracking are the entries manage_error_tracking_standalone_config sets in /etc/environment
+	ErrorTracking = []Allowance{
+		AllowEntries("/etc/environment", "DD_APM_ERROR_TRACKING_STANDALONE_ENABLED", "DD_CORE_AGENT_ENABLED"),
+	}
+)
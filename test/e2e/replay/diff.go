@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ChangeKind tells how a file changed between two snapshots
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "added"
+	Removed  ChangeKind = "removed"
+	Modified ChangeKind = "modified"
+)
+
+// Change is a file that differs between two snapshots
+type Change struct {
+	Path string
+	Kind ChangeKind
+	// Before and After are the states of the file, the zero File when it is missing
+	Before File
+	After  File
+	// AddedLines and RemovedLines are the lines of text files found on one side only, in file order. Lines only
+	// moved within the file are in neither.
+	AddedLines   []string
+	RemovedLines []string
+}
+
+// Binary tells whether the content can't be compared line by line, such as a GPG keyring
+func (c Change) Binary() bool {
+	return isBinary(c.Before.Content) || isBinary(c.After.Content)
+}
+
+func (c Change) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", c.Kind, c.Path)
+	switch {
+	case c.Before.Link != c.After.Link:
+		fmt.Fprintf(&b, " (link %q -> %q)", c.Before.Link, c.After.Link)
+	case c.Kind == Modified && c.Before.Mode != c.After.Mode:
+		fmt.Fprintf(&b, " (mode %04o -> %04o)", c.Before.Mode, c.After.Mode)
+	}
+	if c.Binary() {
+		b.WriteString(" (binary)")
+		return b.String()
+	}
+	for _, line := range c.RemovedLines {
+		fmt.Fprintf(&b, "\n  - %s", line)
+	}
+	for _, line := range c.AddedLines {
+		fmt.Fprintf(&b, "\n  + %s", line)
+	}
+	if c.Kind == Modified && c.Before.Content != c.After.Content && len(c.AddedLines) == 0 && len(c.RemovedLines) == 0 {
+		b.WriteString("\n  (lines reordered)")
+	}
+	return b.String()
+}
+
+// Diff lists the changes between two snapshots, sorted by path
+type Diff []Change
+
+// Compare returns the files added, removed or modified from before to after
+func Compare(before, after Snapshot) Diff {
+	var diff Diff
+	for path, old := range before {
+		current, ok := after[path]
+		switch {
+		case !ok:
+			diff = append(diff, newChange(path, Removed, old, File{}))
+		case old != current:
+			diff = append(diff, newChange(path, Modified, old, current))
+		}
+	}
+	for path, current := range after {
+		if _, ok := before[path]; !ok {
+			diff = append(diff, newChange(path, Added, File{}, current))
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool { return diff[i].Path < diff[j].Path })
+	return diff
+}
+
+func newChange(path string, kind ChangeKind, before, after File) Change {
+	change := Change{Path: path, Kind: kind, Before: before, After: after}
+	if !change.Binary() {
+		change.RemovedLines = subtractLines(lines(before.Content), lines(after.Content))
+		change.AddedLines = subtractLines(lines(after.Content), lines(before.Content))
+	}
+	return change
+}
+
+// Paths returns the paths of the changes
+func (d Diff) Paths() []string {
+	paths := make([]string, 0, len(d))
+	for _, change := range d {
+		paths = append(paths, change.Path)
+	}
+	return paths
+}
+
+func (d Diff) String() string {
+	changes := make([]string, 0, len(d))
+	for _, change := range d {
+		changes = append(changes, change.String())
+	}
+	return strings.Join(changes, "\n")
+}
+
+// Allowance is a change a replay may make
+type Allowance struct {
+	// Path is a path.Match pattern of the files
+	Path string
+	// Lines matches the lines that may be added, removed or moved. Any change of the files, including their creation,
+	// removal and mode, is allowed when it is nil.
+	Lines *regexp.Regexp
+}
+
+// AllowFiles allows any change of the files matching the patterns
+func AllowFiles(patterns ...string) []Allowance {
+	allowances := make([]Allowance, 0, len(patterns))
+	for _, pattern := range patterns {
+		allowances = append(allowances, Allowance{Path: pattern})
+	}
+	return allowances
+}
+
+// AllowEntries allows the KEY=value or "key:" lines of keys to change in the files matching pattern
+func AllowEntries(pattern string, keys ...string) Allowance {
+	quoted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		quoted = append(quoted, regexp.QuoteMeta(key))
+	}
+	return Allowance{Path: pattern, Lines: regexp.MustCompile(`^(` + strings.Join(quoted, "|") + `)[=:]`)}
+}
+
+// Unexpected returns the changes the allowances don't cover. A change is covered when a file allowance matches its
+// path, or when its file is the same on both sides once the lines matched by the allowances of its path are dropped.
+// The changes returned only list their unmatched lines.
+func (d Diff) Unexpected(allowances ...Allowance) Diff {
+	var unexpected Diff
+	for _, change := range d {
+		var patterns []*regexp.Regexp
+		allowed := false
+		for _, allowance := range allowances {
+			if ok, _ := path.Match(allowance.Path, change.Path); !ok {
+				continue
+			}
+			if allowance.Lines == nil {
+				allowed = true
+				break
+			}
+			patterns = append(patterns, allowance.Lines)
+		}
+		if allowed {
+			continue
+		}
+		if len(patterns) == 0 || change.Binary() || change.Before.Mode != change.After.Mode && change.Kind == Modified ||
+			change.Before.Link != change.After.Link {
+			unexpected = append(unexpected, change)
+			continue
+		}
+		before := dropLines(lines(change.Before.Content), patterns)
+		after := dropLines(lines(change.After.Content), patterns)
+		if slices.Equal(before, after) {
+			continue
+		}
+		change.RemovedLines = dropLines(change.RemovedLines, patterns)
+		change.AddedLines = dropLines(change.AddedLines, patterns)
+		unexpected = append(unexpected, change)
+	}
+	return unexpected
+}
+
+func isBinary(content string) bool {
+	return strings.ContainsRune(content, 0) || !utf8.ValidString(content)
+}
+
+func lines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// subtractLines returns the lines of a missing from b, counting duplicates
+func subtractLines(a, b []string) []string {
+	counts := map[string]int{}
+	for _, line := range b {
+		counts[line]++
+	}
+	var missing []string
+	for _, line := range a {
+		if counts[line] > 0 {
+			counts[line]--
+			continue
+		}
+		missing = append(missing, line)
+	}
+	return missing
+}
+
+func dropLines(lines []string, patterns []*regexp.Regexp) []string {
+	var kept []string
+	for _, line := range lines {
+		matched := false
+		for _, pattern := range patterns {
+			if pattern.MatchString(line) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
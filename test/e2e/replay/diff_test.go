@@ -0,0 +1,191 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	before := Snapshot{
+		"/etc/datadog-agent/datadog.yaml":    {Mode: 0640, Content: "api_key: x\nsite: datadoghq.com\n"},
+		"/etc/datadog-agent/environment":     {Mode: 0644, Content: "A=1\nB=2\n"},
+		"/etc/datadog-agent/removed.yaml":    {Mode: 0644, Content: "a: b\n"},
+		"/etc/datadog-agent/install_info":    {Mode: 0644, Content: "same\n"},
+		"/usr/share/keyrings/datadog.gpg":    {Mode: 0644, Content: "\x99\x01\x00"},
+		"/etc/datadog-agent/conf.d/link.yml": {Link: "/tmp/a"},
+	}
+	after := Snapshot{
+		"/etc/datadog-agent/datadog.yaml":    {Mode: 0600, Content: "api_key: x\nsite: datadoghq.eu\n"},
+		"/etc/datadog-agent/environment":     {Mode: 0644, Content: "B=2\nA=1\n"},
+		"/etc/datadog-agent/added.yaml":      {Mode: 0644, Content: "c: d\n"},
+		"/etc/datadog-agent/install_info":    {Mode: 0644, Content: "same\n"},
+		"/usr/share/keyrings/datadog.gpg":    {Mode: 0644, Content: "\x99\x02\x00"},
+		"/etc/datadog-agent/conf.d/link.yml": {Link: "/tmp/b"},
+	}
+	diff := Compare(before, after)
+
+	assert.Equal(t, []string{
+		"/etc/datadog-agent/added.yaml",
+		"/etc/datadog-agent/conf.d/link.yml",
+		"/etc/datadog-agent/datadog.yaml",
+		"/etc/datadog-agent/environment",
+		"/etc/datadog-agent/removed.yaml",
+		"/usr/share/keyrings/datadog.gpg",
+	}, diff.Paths())
+	assert.Equal(t, `added /etc/datadog-agent/added.yaml
+  + c: d
+modified /etc/datadog-agent/conf.d/link.yml (link "/tmp/a" -> "/tmp/b")
+modified /etc/datadog-agent/datadog.yaml (mode 0640 -> 0600)
+  - site: datadoghq.com
+  + site: datadoghq.eu
+modified /etc/datadog-agent/environment
+  (lines reordered)
+removed /etc/datadog-agent/removed.yaml
+  - a: b
+modified /usr/share/keyrings/datadog.gpg (binary)`, diff.String())
+	assert.Empty(t, Compare(before, before))
+}
+
+func TestUnexpected(t *testing.T) {
+	before := Snapshot{
+		"/etc/environment":                {Mode: 0644, Content: "PATH=/bin\nDD_CORE_AGENT_ENABLED=true\n"},
+		"/etc/datadog-agent/environment":  {Mode: 0644, Content: "DD_SBOM_ENABLED=true\nDD_SBOM_HOST_ENABLED=true\n"},
+		"/etc/datadog-agent/datadog.yaml": {Mode: 0640, Content: "api_key: x\n"},
+	}
+	tests := []struct {
+		name       string
+		after      Snapshot
+		allowances []Allowance
+		// unexpected are the changes left, with their unmatched lines
+		unexpected string
+	}{
+		{
+			name: "allowed entries",
+			after: Snapshot{
+				"/etc/environment":                {Mode: 0644, Content: "PATH=/bin\nDD_CORE_AGENT_ENABLED=false\nDD_APM_ERROR_TRACKING_STANDALONE_ENABLED=true\n"},
+				"/etc/datadog-agent/environment":  {Mode: 0644, Content: "DD_SBOM_HOST_ENABLED=true\nDD_SBOM_ENABLED=true\n"},
+				"/etc/datadog-agent/datadog.yaml": {Mode: 0640, Content: "api_key: x\n"},
+			},
+			allowances: append(ErrorTracking, SBOM...),
+		},
+		{
+			name: "other entries",
+			after: Snapshot{
+				"/etc/environment":                {Mode: 0644, Content: "PATH=/usr/bin\nDD_CORE_AGENT_ENABLED=false\n"},
+				"/etc/datadog-agent/environment":  before["/etc/datadog-agent/environment"],
+				"/etc/datadog-agent/datadog.yaml": {Mode: 0640, Content: "api_key: x\n"},
+			},
+			allowances: ErrorTracking,
+			unexpected: "modified /etc/environment\n  - PATH=/bin\n  + PATH=/usr/bin",
+		},
+		{
+			name: "other entries reordered",
+			after: Snapshot{
+				"/etc/environment":                {Mode: 0644, Content: "DD_CORE_AGENT_ENABLED=true\nPATH=/bin\n"},
+				"/etc/datadog-agent/environment":  before["/etc/datadog-agent/environment"],
+				"/etc/datadog-agent/datadog.yaml": before["/etc/datadog-agent/datadog.yaml"],
+			},
+			allowances: SBOM,
+			unexpected: "modified /etc/environment\n  (lines reordered)",
+		},
+		{
+			name: "DDOT",
+			after: Snapshot{
+				"/etc/environment":                     before["/etc/environment"],
+				"/etc/datadog-agent/environment":       before["/etc/datadog-agent/environment"],
+				"/etc/datadog-agent/datadog.yaml":      {Mode: 0640, Content: "agent_ipc:\n  port: 5009\n  config_refresh_interval: 60\notelcollector:\n  enabled: true\napi_key: x\n"},
+				"/etc/datadog-agent/datadog.yaml.orig": {Mode: 0640, Content: "otelcollector:\n  enabled: true\napi_key: x\n"},
+				"/etc/datadog-agent/otel-config.yaml":  {Mode: 0640, Content: "exporters: {}\n"},
+			},
+			allowances: DDOT,
+		},
+		{
+			name: "mode",
+			after: Snapshot{
+				"/etc/environment":                before["/etc/environment"],
+				"/etc/datadog-agent/environment":  {Mode: 0600, Content: "DD_SBOM_ENABLED=true\nDD_SBOM_HOST_ENABLED=false\n"},
+				"/etc/datadog-agent/datadog.yaml": before["/etc/datadog-agent/datadog.yaml"],
+			},
+			allowances: SBOM,
+			unexpected: "modified /etc/datadog-agent/environment (mode 0644 -> 0600)\n  - DD_SBOM_HOST_ENABLED=true\n  + DD_SBOM_HOST_ENABLED=false",
+		},
+		{
+			name: "file allowance",
+			after: Snapshot{
+				"/etc/environment":               before["/etc/environment"],
+				"/etc/datadog-agent/environment": before["/etc/datadog-agent/environment"],
+			},
+			allowances: AllowFiles("/etc/datadog-agent/*.yaml"),
+		},
+		{
+			name: "removed",
+			after: Snapshot{
+				"/etc/environment":                before["/etc/environment"],
+				"/etc/datadog-agent/datadog.yaml": before["/etc/datadog-agent/datadog.yaml"],
+			},
+			allowances: AllowFiles("/etc/datadog-agent/*.yaml"),
+			unexpected: "removed /etc/datadog-agent/environment\n  - DD_SBOM_ENABLED=true\n  - DD_SBOM_HOST_ENABLED=true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.unexpected, Compare(before, tt.after).Unexpected(tt.allowances...).String())
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	etc := filepath.Join(dir, "etc", "datadog-agent")
+	require.NoError(t, os.MkdirAll(filepath.Join(etc, "conf.d"), 0755))
+	files := map[string]string{
+		"datadog.yaml":           "api_key: x\n",
+		"conf.d/with space.yaml": "",
+		"keyring.gpg":            "\x99\x01\x00\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(etc, name), []byte(content), 0644))
+	}
+	require.NoError(t, os.Chmod(filepath.Join(etc, "datadog.yaml"), 0640))
+	require.NoError(t, os.Symlink("/opt/datadog-agent/run", filepath.Join(etc, "run")))
+
+	globs := []string{"/etc/datadog-agent", "/etc/environment", "/etc/apt/sources.list.d/datadog*.list"}
+	rooted := make([]string, 0, len(globs))
+	for _, glob := range globs {
+		rooted = append(rooted, dir+glob)
+	}
+	output, err := exec.Command("bash", "-c", Command(rooted...)).CombinedOutput()
+	require.NoError(t, err, string(output))
+	parsed, err := Parse(string(output))
+	require.NoError(t, err)
+
+	read, err := Read(dir, globs...)
+	require.NoError(t, err)
+	assert.Equal(t, Snapshot{
+		"/etc/datadog-agent/datadog.yaml":           {Mode: 0640, Content: "api_key: x\n"},
+		"/etc/datadog-agent/conf.d/with space.yaml": {Mode: 0644},
+		"/etc/datadog-agent/keyring.gpg":            {Mode: 0644, Content: "\x99\x01\x00\n"},
+		"/etc/datadog-agent/run":                    {Link: "/opt/datadog-agent/run"},
+	}, read)
+	assert.Len(t, parsed, len(read))
+	for path, file := range read {
+		assert.Equal(t, file, parsed[dir+path], path)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, output := range []string{"x 644 L2V0Yw== YQ==", "f 9z L2V0Yw== YQ==", "f 644 !! YQ==", "f 644"} {
+		_, err := Parse(output)
+		assert.Error(t, err, output)
+	}
+}
@@ -0,0 +1,10 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package replay checks that running the install script again leaves the host as it was. It snapshots the files the
+// script writes, the configuration, /etc/environment, the repository files, install.json and install_info, and
+// compares the snapshots taken before and after the replay. Scenarios declare the paths and entries the replay may
+// change, such as the DDOT, SBOM and Error Tracking settings applied even when datadog.yaml is kept.
+package replay
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiKey = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+// replay installs with env, then runs the script again with the replay variables added, and returns the diff
+func replay(t *testing.T, env map[string]string, replayEnv map[string]string, options ...hermetic.Option) Diff {
+	t.Helper()
+	h := hermetic.New(t, options...)
+	result := h.Run(env)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+	before, err := Read(h.Path("/"), DefaultPaths...)
+	require.NoError(t, err)
+	require.Contains(t, before, "/etc/datadog-agent/datadog.yaml")
+
+	for key, value := range replayEnv {
+		env[key] = value
+	}
+	result = h.Run(env)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+	after, err := Read(h.Path("/"), DefaultPaths...)
+	require.NoError(t, err)
+	return Compare(before, after)
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		replayEnv map[string]string
+		options   []hermetic.Option
+		allowed   []Allowance
+		// changed are the files the replay changes, allowed or not
+		changed []string
+	}{
+		{
+			name: "same options",
+		},
+		{
+			name:      "different configuration options",
+			env:       map[string]string{"DD_SITE": "datadoghq.eu", "DD_HOSTNAME": "kiki", "DD_HOST_TAGS": "foo:bar"},
+			replayEnv: map[string]string{"DD_SITE": "datadoghq.com", "DD_HOSTNAME": "totoro", "DD_HOST_TAGS": "john:doe"},
+		},
+		{
+			name: "redhat",
+			env:  map[string]string{"DD_SITE": "datadoghq.eu"},
+			options: []hermetic.Option{
+				hermetic.WithOS(hermetic.RedHat("9.4")),
+			},
+		},
+		{
+			name:    "sles",
+			options: []hermetic.Option{hermetic.WithOS(hermetic.SLES("15.5"))},
+		},
+		{
+			name:      "DDOT enabled on replay",
+			replayEnv: map[string]string{"DD_OTELCOLLECTOR_ENABLED": "true"},
+			allowed:   DDOT,
+			changed: []string{
+				"/etc/datadog-agent/datadog.yaml",
+				"/etc/datadog-agent/datadog.yaml.orig",
+				"/etc/datadog-agent/otel-config.yaml",
+			},
+		},
+		{
+			name:      "SBOM enabled on replay",
+			env:       map[string]string{"DD_SBOM_CONTAINER_IMAGE_ENABLED": "true"},
+			replayEnv: map[string]string{"DD_SBOM_HOST_ENABLED": "true"},
+			allowed:   SBOM,
+			changed:   []string{"/etc/datadog-agent/environment"},
+		},
+		{
+			name:      "SBOM disabled on replay",
+			env:       map[string]string{"DD_SBOM_CONTAINER_IMAGE_ENABLED": "true", "DD_SBOM_HOST_ENABLED": "true"},
+			replayEnv: map[string]string{"DD_SBOM_CONTAINER_IMAGE_ENABLED": "false", "DD_SBOM_HOST_ENABLED": ""},
+			allowed:   SBOM,
+			changed:   []string{"/etc/datadog-agent/environment"},
+		},
+		{
+			name:      "Error Tracking enabled on replay",
+			replayEnv: map[string]string{"DD_APM_ERROR_TRACKING_STANDALONE": "true"},
+			allowed:   ErrorTracking,
+			changed:   []string{"/etc/environment"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"DD_API_KEY": apiKey}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			diff := replay(t, env, tt.replayEnv, tt.options...)
+
+			assert.Empty(t, diff.Unexpected(append(tt.allowed, InstallIdentity...)...), diff.String())
+			var changed []string
+			for _, path := range diff.Paths() {
+				if path != "/etc/datadog-agent/install.json" {
+					changed = append(changed, path)
+				}
+			}
+			assert.Equal(t, tt.changed, changed, diff.String())
+		})
+	}
+}
+
+func TestReplayReportsUndeclaredChanges(t *testing.T) {
+	diff := replay(t,
+		map[string]string{"DD_API_KEY": apiKey, "DD_SBOM_CONTAINER_IMAGE_ENABLED": "true"},
+		map[string]string{"DD_SBOM_HOST_ENABLED": "true", "DD_APM_ERROR_TRACKING_STANDALONE": "true"},
+	)
+
+	unexpected := diff.Unexpected(append(SBOM, InstallIdentity...)...)
+	require.Len(t, unexpected, 1, diff.String())
+	assert.Equal(t, "/etc/environment", unexpected[0].Path)
+	assert.Equal(t, Modified, unexpected[0].Kind)
+	assert.Equal(t, []string{"DD_APM_ERROR_TRACKING_STANDALONE_ENABLED=true", "DD_CORE_AGENT_ENABLED=false"}, unexpected[0].AddedLines)
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultPaths are the shell globs of the files the script writes and a replay must keep
+var DefaultPaths = []string{
+	"/etc/datadog-agent",
+	"/etc/datadog-fips-proxy",
+	"/etc/environment",
+	"/etc/apt/sources.list.d/datadog*.list",
+	"/usr/share/keyrings/datadog-archive-keyring.gpg",
+	"/etc/apt/trusted.gpg.d/datadog-archive-keyring.gpg",
+	"/etc/yum.repos.d/datadog*.repo",
+	"/etc/zypp/repos.d/datadog*.repo",
+}
+
+// File is a regular file or a symbolic link of a snapshot
+type File struct {
+	// Mode holds the permission bits, it is 0 for links
+	Mode fs.FileMode
+	// Link is the target of a symbolic link, empty for regular files
+	Link    string
+	Content string
+}
+
+// Snapshot maps the absolute paths of the files to their state
+type Snapshot map[string]File
+
+// Read snapshots the files under root matching the globs, such as the root of a hermetic harness. The paths of the
+// snapshot are the ones seen from root.
+func Read(root string, globs ...string) (Snapshot, error) {
+	snapshot := Snapshot{}
+	for _, glob := range globs {
+		matches, err := filepath.Glob(filepath.Join(root, glob))
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %s: %w", glob, err)
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil || entry.IsDir() {
+					return err
+				}
+				file, err := readFile(path, entry)
+				if err != nil {
+					return err
+				}
+				snapshot["/"+strings.TrimPrefix(strings.TrimPrefix(path, root), "/")] = file
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+func readFile(path string, entry fs.DirEntry) (File, error) {
+	if entry.Type()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		return File{Link: target}, err
+	}
+	info, err := entry.Info()
+	if err != nil {
+		return File{}, err
+	}
+	content, err := os.ReadFile(path)
+	return File{Mode: info.Mode().Perm(), Content: string(content)}, err
+}
+
+// Command returns a shell command printing the files matching the globs, for Parse. It needs to run as root to read
+// the configuration files.
+func Command(globs ...string) string {
+	return fmt.Sprintf(`for p in %s; do [ -e "$p" ] || [ -L "$p" ] && find "$p" \( -type f -o -type l \) -print0; done | sort -zu |
+while IFS= read -r -d '' f; do
+  if [ -L "$f" ]; then
+    printf 'l 0 %%s %%s\n' "$(printf '%%s' "$f" | base64 -w0)" "$(readlink "$f" | tr -d '\n' | base64 -w0)"
+  else
+    printf 'f %%s %%s %%s\n' "$(stat -c %%a "$f")" "$(printf '%%s' "$f" | base64 -w0)" "$(base64 -w0 < "$f")"
+  fi
+done`, strings.Join(globs, " "))
+}
+
+// Parse decodes the output of Command, one "<type> <mode> <base64 path> <base64 content>" line per file
+func Parse(output string) (Snapshot, error) {
+	snapshot := Snapshot{}
+	for i, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(strings.TrimRight(line, "\r"), " ")
+		if len(fields) == 3 {
+			// base64 of an empty file
+			fields = append(fields, "")
+		}
+		if len(fields) != 4 || (fields[0] != "f" && fields[0] != "l") {
+			return nil, fmt.Errorf("line %d: invalid record %q", i+1, line)
+		}
+		mode, err := strconv.ParseUint(fields[1], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid mode: %w", i+1, err)
+		}
+		path, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid path: %w", i+1, err)
+		}
+		content, err := base64.StdEncoding.DecodeString(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid content: %w", i+1, err)
+		}
+		if fields[0] == "l" {
+			snapshot[string(path)] = File{Link: string(content)}
+		} else {
+			snapshot[string(path)] = File{Mode: fs.FileMode(mode).Perm(), Content: string(content)}
+		}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,196 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package repository
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maintainer = "Datadog Packages <package@datadoghq.com>"
+
+var debArchitectures = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+}
+
+// buildDeb returns the .deb of pkg for a Debian architecture and its control stanza
+func buildDeb(pkg Package, arch string, buildTime time.Time) ([]byte, string, error) {
+	data, installedSize, err := debData(pkg, buildTime)
+	if err != nil {
+		return nil, "", err
+	}
+	control := fmt.Sprintf(`Package: %s
+Version: %s
+Architecture: %s
+Maintainer: %s
+Installed-Size: %d
+Section: utils
+Priority: extra
+Homepage: https://www.datadoghq.com
+Description: %s
+ Stub package served by the test repository of the install script.
+`, pkg.Name, pkg.epochVersion(), arch, maintainer, (installedSize+1023)/1024, pkg.summary())
+
+	controlFiles := []tarFile{{name: "./control", content: control, mode: 0644}}
+	if pkg.PostInstall != "" {
+		controlFiles = append(controlFiles, tarFile{name: "./postinst", content: "#!/bin/sh\nset -e\n" + pkg.PostInstall + "\n", mode: 0755})
+	}
+	controlTar, err := tarGz(controlFiles, buildTime)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var deb bytes.Buffer
+	deb.WriteString("!<arch>\n")
+	for _, member := range []struct {
+		name    string
+		content []byte
+	}{
+		{"debian-binary", []byte("2.0\n")},
+		{"control.tar.gz", controlTar},
+		{"data.tar.gz", data},
+	} {
+		fmt.Fprintf(&deb, "%-16s%-12d%-6d%-6d%-8s%-10d`\n", member.name, buildTime.Unix(), 0, 0, "100644", len(member.content))
+		deb.Write(member.content)
+		if len(member.content)%2 == 1 {
+			deb.WriteByte('\n')
+		}
+	}
+	return deb.Bytes(), control, nil
+}
+
+// debData returns data.tar.gz, with the parent folders dpkg expects before the files, and the installed size
+func debData(pkg Package, buildTime time.Time) ([]byte, int, error) {
+	dirs := map[string]bool{}
+	var files []tarFile
+	installedSize := 0
+	for _, name := range pkg.paths() {
+		for dir := path.Dir(name); dir != "/"; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+		content := pkg.Files[name]
+		files = append(files, tarFile{name: "." + name, content: content, mode: fileMode(content)})
+		installedSize += len(content)
+	}
+	var entries []tarFile
+	entries = append(entries, tarFile{name: "./", dir: true})
+	sortedDirs := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Strings(sortedDirs)
+	for _, dir := range sortedDirs {
+		entries = append(entries, tarFile{name: "." + dir + "/", dir: true})
+	}
+	data, err := tarGz(append(entries, files...), buildTime)
+	return data, installedSize, err
+}
+
+type tarFile struct {
+	name    string
+	content string
+	mode    int64
+	dir     bool
+}
+
+func tarGz(files []tarFile, buildTime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    file.name,
+			Mode:    file.mode,
+			Size:    int64(len(file.content)),
+			ModTime: buildTime,
+			Uname:   "root",
+			Gname:   "root",
+			Format:  tar.FormatGNU,
+		}
+		if file.dir {
+			header.Typeflag = tar.TypeDir
+			header.Mode = 0755
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write([]byte(file.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// packagesStanza completes the control file of a .deb with the fields of its Packages index entry
+func packagesStanza(control string, filename string, deb []byte) string {
+	return fmt.Sprintf("%sFilename: %s\nSize: %d\nMD5sum: %x\nSHA1: %x\nSHA256: %x\n",
+		control, filename, len(deb), md5.Sum(deb), sha1.Sum(deb), sha256.Sum256(deb))
+}
+
+// releaseFile returns the Release file of a suite, listing the checksums of its indexes relative to dists/<suite>
+func releaseFile(suite string, components []string, archs []string, indexes map[string][]byte, date time.Time) string {
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Origin: Datadog, Inc.\nLabel: Datadog, Inc.\nSuite: %s\nCodename: %s\n", suite, suite)
+	fmt.Fprintf(&b, "Date: %s\n", date.UTC().Format("Mon, 02 Jan 2006 15:04:05 UTC"))
+	fmt.Fprintf(&b, "Architectures: %s\nComponents: %s\n", strings.Join(archs, " "), strings.Join(components, " "))
+	b.WriteString("Description: Datadog test repository\n")
+	for _, sum := range []struct {
+		field string
+		hash  func([]byte) string
+	}{
+		{"MD5Sum", func(b []byte) string { return fmt.Sprintf("%x", md5.Sum(b)) }},
+		{"SHA1", func(b []byte) string { return fmt.Sprintf("%x", sha1.Sum(b)) }},
+		{"SHA256", func(b []byte) string { return fmt.Sprintf("%x", sha256.Sum256(b)) }},
+	} {
+		fmt.Fprintf(&b, "%s:\n", sum.field)
+		for _, name := range names {
+			fmt.Fprintf(&b, " %s %16d %s\n", sum.hash(indexes[name]), len(indexes[name]), name)
+		}
+	}
+	return b.String()
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func fileMode(content string) int64 {
+	if strings.HasPrefix(content, "#!") {
+		return 0755
+	}
+	return 0644
+}
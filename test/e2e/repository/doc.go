@@ -0,0 +1,9 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package repository serves a throwaway APT, YUM and zypper repository of stub Datadog packages, signed with a key
+// generated on the fly. The install script reaches it through the TESTING_*_URL overrides returned by Env, so that
+// install tests run offline against packages whose versions they control.
+package repository
@@ -0,0 +1,195 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultVersion is the version of the default packages, above any released one so that it is always the latest
+const DefaultVersion = "7.99.0-1"
+
+const (
+	// The agent packages create dd-agent on install, the install script chowns the configuration to it
+	ddAgentUser = `getent group dd-agent >/dev/null || groupadd -r dd-agent
+getent passwd dd-agent >/dev/null || useradd -r -M -g dd-agent -d /opt/datadog-agent -s /sbin/nologin dd-agent
+if command -v systemctl >/dev/null 2>&1; then systemctl daemon-reload >/dev/null 2>&1 || true; fi`
+
+	datadogYAMLExample = `## @param api_key - string - required
+api_key:
+
+## @param site - string - optional - default: datadoghq.com
+# site: datadoghq.com
+
+## @param hostname - string - optional - default: auto-detected
+# hostname: <HOSTNAME_NAME>
+
+## @param tags  - list of key:value elements - optional
+# tags:
+#   - <TAG_KEY>:<TAG_VALUE>
+
+## @param env - string - optional
+# env: <environment name>
+`
+
+	systemProbeYAMLExample = `## System Probe configuration
+# system_probe_config:
+  # enabled: false
+# network_config:
+  # enabled: false
+`
+
+	securityAgentYAMLExample = `## Security Agent configuration
+# runtime_security_config:
+  # enabled: false
+# compliance_config:
+  # enabled: false
+`
+
+	otelConfigYAMLExample = `exporters:
+  datadog:
+    api:
+      key: ${env:DD_API_KEY}
+      site: ${env:DD_SITE}
+`
+
+	dogstatsdYAMLExample = `## @param api_key - string - required
+api_key:
+
+## @param site - string - optional - default: datadoghq.com
+# site: datadoghq.com
+`
+
+	fipsProxyCfgExample = `global
+    presetenv DD_FIPS_LOCAL_ADDRESS 127.0.0.1
+`
+)
+
+// Package is a stub package published in every format and architecture of the repository
+type Package struct {
+	Name string
+	// Version is the upstream version and the release, e.g. "7.60.1-1". Packages get epoch 1, as the Datadog ones.
+	Version string
+	// Files are unpacked on install, keyed by absolute path. Contents starting with "#!" are executable.
+	Files map[string]string
+	// PostInstall is a POSIX shell snippet run once the files are unpacked
+	PostInstall string
+}
+
+// DefaultPackages returns stubs of the packages the script installs, at version
+func DefaultPackages(version string) []Package {
+	agent := func(name string) Package {
+		return Package{
+			Name:    name,
+			Version: version,
+			Files: map[string]string{
+				"/etc/datadog-agent/datadog.yaml.example":        datadogYAMLExample,
+				"/etc/datadog-agent/system-probe.yaml.example":   systemProbeYAMLExample,
+				"/etc/datadog-agent/security-agent.yaml.example": securityAgentYAMLExample,
+				"/opt/datadog-agent/bin/agent/agent":             stubBinary("Agent", version),
+				"/usr/lib/systemd/system/datadog-agent.service":  systemdUnit("Datadog Agent", "/opt/datadog-agent/bin/agent/agent"),
+				"/opt/datadog-agent/version-manifest.txt":        fmt.Sprintf("%s %s\n", name, version),
+			},
+			PostInstall: ddAgentUser,
+		}
+	}
+	return []Package{
+		agent("datadog-agent"),
+		agent("datadog-iot-agent"),
+		{
+			Name:    "datadog-dogstatsd",
+			Version: version,
+			Files: map[string]string{
+				"/etc/datadog-dogstatsd/dogstatsd.yaml.example":     dogstatsdYAMLExample,
+				"/opt/datadog-dogstatsd/bin/dogstatsd":              stubBinary("DogStatsD", version),
+				"/usr/lib/systemd/system/datadog-dogstatsd.service": systemdUnit("Datadog DogStatsD", "/opt/datadog-dogstatsd/bin/dogstatsd"),
+			},
+			PostInstall: ddAgentUser,
+		},
+		{
+			Name:    "datadog-fips-proxy",
+			Version: version,
+			Files: map[string]string{
+				"/etc/datadog-fips-proxy/datadog-fips-proxy.cfg.example": fipsProxyCfgExample,
+				"/opt/datadog-fips-proxy/embedded/sbin/haproxy":          stubBinary("FIPS proxy", version),
+				"/usr/lib/systemd/system/datadog-fips-proxy.service":     systemdUnit("Datadog FIPS proxy", "/opt/datadog-fips-proxy/embedded/sbin/haproxy"),
+			},
+		},
+		{
+			Name:    "datadog-agent-ddot",
+			Version: version,
+			Files: map[string]string{
+				"/etc/datadog-agent/otel-config.yaml.example":         otelConfigYAMLExample,
+				"/opt/datadog-agent/ext/ddot/embedded/bin/otel-agent": stubBinary("DDOT", version),
+			},
+		},
+		{
+			// Installed along the agent on Debian based hosts, it only ships the keyring in the real repository
+			Name:    "datadog-signing-keys",
+			Version: "1.4.0-1",
+			Files: map[string]string{
+				"/usr/share/doc/datadog-signing-keys/stub-package-readme": "Stub package served by the test repository of the install script.\n",
+			},
+		},
+	}
+}
+
+// stubBinary answers the version subcommands and otherwise runs until stopped, so that services stay active
+func stubBinary(name string, version string) string {
+	return fmt.Sprintf(`#!/bin/sh
+case "$1" in
+  version|--version) echo "%s %s - Commit: stub";;
+  run|start|"") while :; do sleep 3600; done;;
+esac
+exit 0
+`, name, strings.SplitN(version, "-", 2)[0])
+}
+
+func systemdUnit(description string, binary string) string {
+	return fmt.Sprintf(`[Unit]
+Description=%s (stub)
+After=network.target
+
+[Service]
+Type=simple
+ExecStart=%s run
+
+[Install]
+WantedBy=multi-user.target
+`, description, binary)
+}
+
+func (p Package) upstreamVersion() string {
+	return strings.SplitN(p.Version, "-", 2)[0]
+}
+
+func (p Package) release() string {
+	if _, release, ok := strings.Cut(p.Version, "-"); ok {
+		return release
+	}
+	return "1"
+}
+
+// epochVersion is the version as package managers print it, e.g. "1:7.60.1-1"
+func (p Package) epochVersion() string {
+	return fmt.Sprintf("1:%s-%s", p.upstreamVersion(), p.release())
+}
+
+func (p Package) summary() string {
+	return fmt.Sprintf("Stub %s package", p.Name)
+}
+
+// paths returns the files of the package in lexical order, as rpm wants them
+func (p Package) paths() []string {
+	paths := make([]string, 0, len(p.Files))
+	for name := range p.Files {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,329 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package repository
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/internal/testca"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// APTKeys are the key files the script downloads on Debian based hosts, kept in sync with APT_GPG_KEYS
+	APTKeys = []string{
+		"DATADOG_APT_KEY_CURRENT.public",
+		"DATADOG_APT_KEY_06462314.public",
+		"DATADOG_APT_KEY_C0962C7D.public",
+		"DATADOG_APT_KEY_F14F620E.public",
+		"DATADOG_APT_KEY_382E94DE.public",
+	}
+	// RPMKeys are the key files the script imports on Red Hat and SUSE based hosts, kept in sync with RPM_GPG_KEYS
+	RPMKeys = []string{
+		"DATADOG_RPM_KEY_CURRENT.public",
+		"DATADOG_RPM_KEY_4F09D16B.public",
+		"DATADOG_RPM_KEY_B01082D3.public",
+		"DATADOG_RPM_KEY_FD4BF915.public",
+		"DATADOG_RPM_KEY_E09422B3.public",
+	}
+)
+
+// Option configures a Repository
+type Option func(*Repository)
+
+// WithPackages replaces the packages served, DefaultPackages(DefaultVersion) by default. Several versions of a
+// package can be served at once.
+func WithPackages(packages ...Package) Option {
+	return func(r *Repository) { r.packages = packages }
+}
+
+// WithAptRepoVersion sets the suite and components of the APT repository, as TESTING_APT_REPO_VERSION,
+// "stable 7" by default
+func WithAptRepoVersion(version string) Option {
+	return func(r *Repository) { r.aptRepoVersion = version }
+}
+
+// WithYumVersionPath sets the path of the YUM and zypper repositories, as TESTING_YUM_VERSION_PATH, "stable/7" by
+// default
+func WithYumVersionPath(versionPath string) Option {
+	return func(r *Repository) { r.yumVersionPath = versionPath }
+}
+
+// WithArchitectures sets the architectures packages are built for, as `uname -m` reports them, x86_64 and aarch64
+// by default
+func WithArchitectures(archs ...string) Option {
+	return func(r *Repository) { r.archs = archs }
+}
+
+// WithHosts sets the names and addresses the TLS certificate is valid for, the first one is used in the URLs.
+// 127.0.0.1 by default, a test VM needs the address of the runner instead.
+func WithHosts(hosts ...string) Option {
+	return func(r *Repository) { r.hosts = hosts }
+}
+
+// WithListenAddress sets where the server listens, 127.0.0.1:0 by default
+func WithListenAddress(address string) Option {
+	return func(r *Repository) { r.listenAddress = address }
+}
+
+// Repository is a throwaway package repository, signed with a key generated for the test and served over https
+// with the layout of the Datadog ones:
+//
+//	/DATADOG_{APT,RPM}_KEY_*.public          public key, under every name the script downloads
+//	/dists/<suite>/...                       APT, signed with InRelease and Release.gpg
+//	/pool/d/<name>/<name>_<version>_<arch>.deb
+//	/<yum version path>/<arch>/              YUM, repodata/repomd.xml signed with repomd.xml.asc
+//	/suse/<yum version path>/<arch>/         zypper, same rpm-md layout
+type Repository struct {
+	packages       []Package
+	aptRepoVersion string
+	yumVersionPath string
+	archs          []string
+	hosts          []string
+	listenAddress  string
+
+	dir       string
+	signer    *signer
+	publicKey []byte
+	caFile    string
+	server    *httptest.Server
+	mu        sync.Mutex
+	fetched   []string
+}
+
+// New builds the repository in a temporary folder and serves it until the end of the test
+func New(t testing.TB, options ...Option) *Repository {
+	t.Helper()
+	r := &Repository{
+		packages:       DefaultPackages(DefaultVersion),
+		aptRepoVersion: "stable 7",
+		yumVersionPath: "stable/7",
+		archs:          []string{"x86_64", "aarch64"},
+		hosts:          []string{"127.0.0.1"},
+		listenAddress:  "127.0.0.1:0",
+	}
+	for _, option := range options {
+		option(r)
+	}
+	r.dir = filepath.Join(t.TempDir(), "repository")
+
+	var err error
+	r.signer, err = newSigner()
+	require.NoError(t, err)
+	require.NoError(t, r.build(time.Now()))
+
+	authority, err := testca.New("Datadog Test Repository")
+	require.NoError(t, err)
+	certificate, err := authority.Certificate(r.hosts...)
+	require.NoError(t, err)
+	r.caFile = filepath.Join(filepath.Dir(r.dir), "ca.pem")
+	require.NoError(t, os.WriteFile(r.caFile, authority.PEM(), 0644))
+
+	listener, err := net.Listen("tcp", r.listenAddress)
+	require.NoError(t, err)
+	files := http.FileServer(http.Dir(r.dir))
+	r.server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		r.fetched = append(r.fetched, req.URL.Path)
+		r.mu.Unlock()
+		files.ServeHTTP(w, req)
+	}))
+	r.server.Listener.Close()
+	r.server.Listener = listener
+	r.server.TLS = &tls.Config{Certificates: []tls.Certificate{*certificate}}
+	r.server.StartTLS()
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+// Host returns the host:port the script has to reach, the value of the TESTING_*_URL variables
+func (r *Repository) Host() string {
+	_, port, _ := net.SplitHostPort(r.server.Listener.Addr().String())
+	return net.JoinHostPort(r.hosts[0], port)
+}
+
+// Env returns the variables pointing the install script to the repository
+func (r *Repository) Env() map[string]string {
+	return map[string]string{
+		"TESTING_APT_URL":          r.Host(),
+		"TESTING_YUM_URL":          r.Host(),
+		"TESTING_KEYS_URL":         r.Host(),
+		"TESTING_APT_REPO_VERSION": r.aptRepoVersion,
+		"TESTING_YUM_VERSION_PATH": r.yumVersionPath,
+	}
+}
+
+// CAFile returns the path of the PEM certificate of the CA that signed the server certificate. Hosts have to trust
+// it, e.g. through CURL_CA_BUNDLE, Acquire::https::CAInfo or the system trust store.
+func (r *Repository) CAFile() string {
+	return r.caFile
+}
+
+// Dir returns the folder served
+func (r *Repository) Dir() string {
+	return r.dir
+}
+
+// PublicKey returns the ASCII armored key the repository and the packages are signed with
+func (r *Repository) PublicKey() string {
+	return string(r.publicKey)
+}
+
+// Fingerprint returns the fingerprint of the signing key, in upper case hexadecimal
+func (r *Repository) Fingerprint() string {
+	return r.signer.fingerprint()
+}
+
+// KeyID returns the short id of the signing key, rpm records imported keys as gpg-pubkey-<id>
+func (r *Repository) KeyID() string {
+	return r.signer.keyID()
+}
+
+// Requests returns the paths fetched so far, in order
+func (r *Repository) Requests() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.fetched...)
+}
+
+func (r *Repository) build(now time.Time) error {
+	var err error
+	r.publicKey, err = r.signer.publicKey()
+	if err != nil {
+		return err
+	}
+	for _, name := range append(append([]string{}, APTKeys...), RPMKeys...) {
+		if err := r.writeFile(name, r.publicKey); err != nil {
+			return err
+		}
+	}
+	if err := r.buildAPT(now); err != nil {
+		return err
+	}
+	return r.buildRPMMD(now)
+}
+
+func (r *Repository) buildAPT(now time.Time) error {
+	fields := strings.Fields(r.aptRepoVersion)
+	if len(fields) < 2 {
+		return fmt.Errorf("APT repository version %q is not a suite followed by components", r.aptRepoVersion)
+	}
+	suite, components := fields[0], fields[1:]
+
+	indexes := map[string][]byte{}
+	var debArchs []string
+	for _, arch := range r.archs {
+		debArch, ok := debArchitectures[arch]
+		if !ok {
+			return fmt.Errorf("no Debian architecture for %s", arch)
+		}
+		debArchs = append(debArchs, debArch)
+		var stanzas []string
+		for _, pkg := range r.packages {
+			deb, control, err := buildDeb(pkg, debArch, now)
+			if err != nil {
+				return fmt.Errorf("failed to build the %s package of %s: %w", debArch, pkg.Name, err)
+			}
+			filename := fmt.Sprintf("pool/d/%s/%s_%s_%s.deb", pkg.Name, pkg.Name, pkg.Version, debArch)
+			if err := r.writeFile(filename, deb); err != nil {
+				return err
+			}
+			stanzas = append(stanzas, packagesStanza(control, filename, deb))
+		}
+		packages := []byte(strings.Join(stanzas, "\n"))
+		compressed, err := gzipBytes(packages)
+		if err != nil {
+			return err
+		}
+		for _, component := range components {
+			indexes[path.Join(component, "binary-"+debArch, "Packages")] = packages
+			indexes[path.Join(component, "binary-"+debArch, "Packages.gz")] = compressed
+		}
+	}
+
+	suiteDir := path.Join("dists", suite)
+	for name, content := range indexes {
+		if err := r.writeFile(path.Join(suiteDir, name), content); err != nil {
+			return err
+		}
+	}
+	release := []byte(releaseFile(suite, components, debArchs, indexes, now))
+	inRelease, err := r.signer.clearSign(release)
+	if err != nil {
+		return err
+	}
+	releaseSignature, err := r.signer.armoredDetachSign(release)
+	if err != nil {
+		return err
+	}
+	for name, content := range map[string][]byte{"Release": release, "InRelease": inRelease, "Release.gpg": releaseSignature} {
+		if err := r.writeFile(path.Join(suiteDir, name), content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) buildRPMMD(now time.Time) error {
+	for _, arch := range r.archs {
+		if _, ok := rpmLeadArchitectures[arch]; !ok {
+			return fmt.Errorf("unsupported rpm architecture %s", arch)
+		}
+		var rpms []*rpmPackage
+		for _, pkg := range r.packages {
+			rpm, err := buildRPM(pkg, arch, now, r.signer)
+			if err != nil {
+				return fmt.Errorf("failed to build the %s package of %s: %w", arch, pkg.Name, err)
+			}
+			rpms = append(rpms, rpm)
+		}
+		metadata, err := repodata(rpms, now)
+		if err != nil {
+			return err
+		}
+		signature, err := r.signer.armoredDetachSign(metadata["repodata/repomd.xml"])
+		if err != nil {
+			return err
+		}
+		metadata["repodata/repomd.xml.asc"] = signature
+		// zypper offers to import the key it finds next to repomd.xml
+		metadata["repodata/repomd.xml.key"] = r.publicKey
+
+		for _, base := range []string{r.yumVersionPath, path.Join("suse", r.yumVersionPath)} {
+			repoDir := path.Join(base, arch)
+			for _, rpm := range rpms {
+				if err := r.writeFile(path.Join(repoDir, rpm.filename), rpm.content); err != nil {
+					return err
+				}
+			}
+			for name, content := range metadata {
+				if err := r.writeFile(path.Join(repoDir, name), content); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Repository) writeFile(name string, content []byte) error {
+	target := filepath.Join(r.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, content, 0644)
+}
@@ -0,0 +1,191 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package repository
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func httpsClient(t *testing.T, repo *Repository) *http.Client {
+	t.Helper()
+	ca, err := os.ReadFile(repo.CAFile())
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(ca))
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+}
+
+func fetch(t *testing.T, client *http.Client, repo *Repository, path string) []byte {
+	t.Helper()
+	resp, err := client.Get("https://" + repo.Host() + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, path)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return body
+}
+
+func TestKeyNamesMatchTemplate(t *testing.T) {
+	path, err := hermetic.FindTemplate()
+	require.NoError(t, err)
+	template, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for variable, expected := range map[string][]string{"APT_GPG_KEYS": APTKeys, "RPM_GPG_KEYS": RPMKeys} {
+		match := regexp.MustCompile(`(?m)^` + variable + `=\(([^)]*)\)`).FindSubmatch(template)
+		require.NotNil(t, match, variable)
+		assert.Equal(t, expected, strings.Fields(strings.ReplaceAll(string(match[1]), `"`, "")), variable)
+	}
+}
+
+func TestDebPackage(t *testing.T) {
+	dpkgDeb, err := exec.LookPath("dpkg-deb")
+	if err != nil {
+		t.Skip("dpkg-deb is not available")
+	}
+	pkg := DefaultPackages("7.60.1-1")[0]
+	deb, _, err := buildDeb(pkg, "arm64", time.Now())
+	require.NoError(t, err)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "datadog-agent.deb")
+	require.NoError(t, os.WriteFile(file, deb, 0644))
+
+	output, err := exec.Command(dpkgDeb, "--field", file, "Package", "Version", "Architecture").CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Equal(t, "Package: datadog-agent\nVersion: 1:7.60.1-1\nArchitecture: arm64\n", string(output))
+
+	output, err = exec.Command(dpkgDeb, "--extract", file, filepath.Join(dir, "data")).CombinedOutput()
+	require.NoError(t, err, string(output))
+	for name, content := range pkg.Files {
+		extracted, err := os.ReadFile(filepath.Join(dir, "data", name))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(extracted))
+	}
+	info, err := os.Stat(filepath.Join(dir, "data", "/opt/datadog-agent/bin/agent/agent"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	output, err = exec.Command(dpkgDeb, "--control", file, filepath.Join(dir, "control")).CombinedOutput()
+	require.NoError(t, err, string(output))
+	postinst, err := os.ReadFile(filepath.Join(dir, "control", "postinst"))
+	require.NoError(t, err)
+	assert.Contains(t, string(postinst), "useradd")
+}
+
+// TestAPTRepository has apt check the signatures and resolve the packages, where it is available
+func TestAPTRepository(t *testing.T) {
+	aptGet, err := exec.LookPath("apt-get")
+	if err != nil {
+		t.Skip("apt-get is not available")
+	}
+	repo := New(t, WithArchitectures("x86_64"))
+	dir := t.TempDir()
+	keyring := filepath.Join(dir, "datadog-archive-keyring.asc")
+	require.NoError(t, os.WriteFile(keyring, []byte(repo.PublicKey()), 0644))
+	sources := filepath.Join(dir, "datadog.list")
+	require.NoError(t, os.WriteFile(sources, []byte(fmt.Sprintf("deb [signed-by=%s] https://%s/ stable 7\n", keyring, repo.Host())), 0644))
+	status := filepath.Join(dir, "status")
+	require.NoError(t, os.WriteFile(status, nil, 0644))
+	for _, sub := range []string{"state/lists/partial", "cache/archives/partial"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, sub), 0755))
+	}
+	options := []string{
+		"-o", "Dir::Etc::SourceList=" + sources,
+		"-o", "Dir::Etc::SourceParts=-",
+		"-o", "Dir::State=" + filepath.Join(dir, "state"),
+		"-o", "Dir::State::status=" + status,
+		"-o", "Dir::Cache=" + filepath.Join(dir, "cache"),
+		"-o", "Acquire::https::CAInfo=" + repo.CAFile(),
+		"-o", "APT::Architecture=amd64",
+		"-o", "APT::Architectures=amd64",
+		"-o", "APT::Sandbox::User=root",
+		"-o", "Debug::NoLocking=true",
+		"-o", "APT::Update::Error-Mode=any",
+	}
+	run := func(name string, args ...string) string {
+		cmd := exec.Command(name, append(options, args...)...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return string(output)
+	}
+
+	output := run(aptGet, "update")
+	assert.NotContains(t, output, "NO_PUBKEY")
+	assert.NotContains(t, output, "W:")
+	output = run(filepath.Join(filepath.Dir(aptGet), "apt-cache"), "madison", "datadog-agent")
+	assert.Contains(t, output, "1:7.99.0-1")
+	// download checks the size and hashes listed in the signed indexes
+	run(aptGet, "download", "datadog-agent", "datadog-agent-ddot", "datadog-signing-keys")
+	assert.FileExists(t, filepath.Join(dir, "datadog-agent_1%3a7.99.0-1_amd64.deb"))
+}
+
+func TestScriptUsesRepository(t *testing.T) {
+	repo := New(t)
+	client := httpsClient(t, repo)
+
+	for _, tc := range []struct {
+		name string
+		os   hermetic.OS
+		// repo file the script writes, and the keys it downloads or imports
+		source string
+		keys   []string
+	}{
+		{"ubuntu", hermetic.Ubuntu("22.04"), "/etc/apt/sources.list.d/datadog.list", APTKeys},
+		{"redhat", hermetic.RedHat("9.4"), "/etc/yum.repos.d/datadog.repo", RPMKeys},
+		{"suse", hermetic.SLES("15.5"), "/etc/zypp/repos.d/datadog.repo", RPMKeys},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := hermetic.New(t, hermetic.WithOS(tc.os), hermetic.WithPassthrough(regexp.QuoteMeta(repo.Host())))
+			env := repo.Env()
+			env["DD_API_KEY"] = "0123456789abcdef0123456789abcdef"
+			env["CURL_CA_BUNDLE"] = repo.CAFile()
+			result := h.Run(env)
+			require.Equal(t, 0, result.ExitCode, result.Transcript())
+
+			source := h.ReadFile(tc.source)
+			assert.Contains(t, source, "https://"+repo.Host()+"/")
+			switch tc.os.Family {
+			case hermetic.FamilyDebian:
+				assert.Contains(t, source, " stable 7")
+				// The keys are downloaded with curl, then imported in a single gpg call
+				gpg, ok := result.FindCall("gpg", "--import")
+				require.True(t, ok, result.Transcript())
+				assert.Equal(t, strings.Repeat(repo.PublicKey(), len(APTKeys)), gpg.Stdin)
+				for _, key := range APTKeys {
+					assert.Contains(t, repo.Requests(), "/"+key)
+				}
+			default:
+				// Package managers are shims, so check the URLs the script configured resolve in the repository
+				baseurl := regexp.MustCompile(`baseurl\s*=\s*https://[^/]+(\S+)`).FindStringSubmatch(source)
+				require.NotNil(t, baseurl, source)
+				fetch(t, client, repo, strings.TrimSuffix(baseurl[1], "/")+"/repodata/repomd.xml")
+				for _, key := range tc.keys {
+					assert.Contains(t, source, "https://"+repo.Host()+"/"+key)
+					fetch(t, client, repo, "/"+key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package repository
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+)
+
+// Header tags and types, see https://rpm-software-management.github.io/rpm/manual/format_v4.html
+const (
+	rpmTypeInt16       = 3
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeBin         = 7
+	rpmTypeStringArray = 8
+	rpmTypeI18NString  = 9
+
+	rpmTagHeaderSignatures = 62
+	rpmTagHeaderImmutable  = 63
+	rpmTagHeaderI18NTable  = 100
+
+	rpmSigTagRSA         = 268
+	rpmSigTagSHA1        = 269
+	rpmSigTagSHA256      = 273
+	rpmSigTagSize        = 1000
+	rpmSigTagPGP         = 1002
+	rpmSigTagMD5         = 1004
+	rpmSigTagPayloadSize = 1007
+
+	rpmTagName              = 1000
+	rpmTagVersion           = 1001
+	rpmTagRelease           = 1002
+	rpmTagEpoch             = 1003
+	rpmTagSummary           = 1004
+	rpmTagDescription       = 1005
+	rpmTagBuildTime         = 1006
+	rpmTagBuildHost         = 1007
+	rpmTagSize              = 1009
+	rpmTagVendor            = 1011
+	rpmTagLicense           = 1014
+	rpmTagPackager          = 1015
+	rpmTagGroup             = 1016
+	rpmTagURL               = 1020
+	rpmTagOS                = 1021
+	rpmTagArch              = 1022
+	rpmTagPostIn            = 1024
+	rpmTagFileSizes         = 1028
+	rpmTagFileModes         = 1030
+	rpmTagFileRDevs         = 1033
+	rpmTagFileMTimes        = 1034
+	rpmTagFileDigests       = 1035
+	rpmTagFileLinkTos       = 1036
+	rpmTagFileFlags         = 1037
+	rpmTagFileUserName      = 1039
+	rpmTagFileGroupName     = 1040
+	rpmTagSourceRPM         = 1044
+	rpmTagFileVerifyFlags   = 1045
+	rpmTagProvideName       = 1047
+	rpmTagRequireFlags      = 1048
+	rpmTagRequireName       = 1049
+	rpmTagRequireVersion    = 1050
+	rpmTagRPMVersion        = 1064
+	rpmTagPostInProg        = 1086
+	rpmTagFileDevices       = 1095
+	rpmTagFileInodes        = 1096
+	rpmTagFileLangs         = 1097
+	rpmTagProvideFlags      = 1112
+	rpmTagProvideVersion    = 1113
+	rpmTagDirIndexes        = 1116
+	rpmTagBaseNames         = 1117
+	rpmTagDirNames          = 1118
+	rpmTagPayloadFormat     = 1124
+	rpmTagPayloadCompressor = 1125
+	rpmTagPayloadFlags      = 1126
+	rpmTagFileDigestAlgo    = 5011
+
+	rpmSenseEqual  = 1 << 3
+	rpmSenseLess   = 1 << 1
+	rpmSenseRPMLib = 1 << 24
+
+	rpmDigestAlgoSHA256 = 8
+
+	buildHost = "test-repository.datadoghq.com"
+	vendor    = "Datadog <package@datadoghq.com>"
+	license   = "Apache License Version 2.0"
+)
+
+var (
+	rpmHeaderMagic = []byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0}
+
+	// Lead architecture numbers, from rpmrc
+	rpmLeadArchitectures = map[string]uint16{
+		"x86_64":  1,
+		"aarch64": 19,
+	}
+
+	// Features of rpm the payload relies on, rpm refuses packages requiring features it lacks
+	rpmLibRequires = [][2]string{
+		{"rpmlib(CompressedFileNames)", "3.0.4-1"},
+		{"rpmlib(FileDigests)", "4.6.0-1"},
+		{"rpmlib(PayloadFilesHavePrefix)", "4.0-1"},
+	}
+)
+
+// rpmPackage is a built .rpm and what its repodata entry needs
+type rpmPackage struct {
+	pkg           Package
+	arch          string
+	filename      string
+	content       []byte
+	headerStart   int
+	headerEnd     int
+	archiveSize   int
+	installedSize int
+	buildTime     time.Time
+}
+
+type rpmEntry struct {
+	tag   uint32
+	typ   uint32
+	count uint32
+	data  []byte
+}
+
+// rpmHeader is a header structure, as used for both the signature and the main headers
+type rpmHeader []rpmEntry
+
+func (h *rpmHeader) add(tag uint32, typ uint32, count int, data []byte) {
+	*h = append(*h, rpmEntry{tag: tag, typ: typ, count: uint32(count), data: data})
+}
+
+func (h *rpmHeader) addString(tag uint32, s string) {
+	h.add(tag, rpmTypeString, 1, append([]byte(s), 0))
+}
+
+func (h *rpmHeader) addI18NString(tag uint32, s string) {
+	h.add(tag, rpmTypeI18NString, 1, append([]byte(s), 0))
+}
+
+func (h *rpmHeader) addStringArray(tag uint32, values ...string) {
+	var data []byte
+	for _, value := range values {
+		data = append(append(data, value...), 0)
+	}
+	h.add(tag, rpmTypeStringArray, len(values), data)
+}
+
+func (h *rpmHeader) addInt32(tag uint32, values ...uint32) {
+	data := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint32(data[4*i:], value)
+	}
+	h.add(tag, rpmTypeInt32, len(values), data)
+}
+
+func (h *rpmHeader) addInt16(tag uint32, values ...uint16) {
+	data := make([]byte, 2*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint16(data[2*i:], value)
+	}
+	h.add(tag, rpmTypeInt16, len(values), data)
+}
+
+func (h *rpmHeader) addBin(tag uint32, data []byte) {
+	h.add(tag, rpmTypeBin, len(data), data)
+}
+
+// marshal serializes the header as a single region, the layout rpm verifies the signatures against
+func (h rpmHeader) marshal(regionTag uint32) []byte {
+	entries := append(rpmHeader{}, h...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	var store bytes.Buffer
+	index := make([]byte, 16, 16*(len(entries)+1))
+	for _, entry := range entries {
+		align := map[uint32]int{rpmTypeInt16: 2, rpmTypeInt32: 4}[entry.typ]
+		for align > 0 && store.Len()%align != 0 {
+			store.WriteByte(0)
+		}
+		index = appendIndexEntry(index, entry.tag, entry.typ, uint32(store.Len()), entry.count)
+		store.Write(entry.data)
+	}
+	// The region trailer points back to the start of the index, covering all its entries
+	trailerOffset := store.Len()
+	negativeIndexSize := -int32(16 * (len(entries) + 1))
+	store.Write(appendIndexEntry(nil, regionTag, rpmTypeBin, uint32(negativeIndexSize), 16))
+	copy(index[:16], appendIndexEntry(nil, regionTag, rpmTypeBin, uint32(trailerOffset), 16))
+
+	out := append([]byte{}, rpmHeaderMagic...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(entries)+1))
+	out = binary.BigEndian.AppendUint32(out, uint32(store.Len()))
+	out = append(out, index...)
+	return append(out, store.Bytes()...)
+}
+
+func appendIndexEntry(b []byte, tag uint32, typ uint32, offset uint32, count uint32) []byte {
+	b = binary.BigEndian.AppendUint32(b, tag)
+	b = binary.BigEndian.AppendUint32(b, typ)
+	b = binary.BigEndian.AppendUint32(b, offset)
+	return binary.BigEndian.AppendUint32(b, count)
+}
+
+// buildRPM returns the signed .rpm of pkg for an rpm architecture
+func buildRPM(pkg Package, arch string, buildTime time.Time, s *signer) (*rpmPackage, error) {
+	paths := pkg.paths()
+	mtime := uint32(buildTime.Unix())
+
+	var (
+		sizes, mtimes, flags, verifyFlags, devices, inodes, dirIndexes []uint32
+		modes, rdevs                                                   []uint16
+		digests, linkTos, users, groups, langs, baseNames, dirNames    []string
+		installedSize                                                  int
+	)
+	dirIndex := map[string]int{}
+	for i, name := range paths {
+		content := pkg.Files[name]
+		dir, base := path.Split(name)
+		if _, ok := dirIndex[dir]; !ok {
+			dirIndex[dir] = len(dirNames)
+			dirNames = append(dirNames, dir)
+		}
+		sizes = append(sizes, uint32(len(content)))
+		mtimes = append(mtimes, mtime)
+		flags = append(flags, 0)
+		verifyFlags = append(verifyFlags, 0xffffffff)
+		devices = append(devices, 1)
+		inodes = append(inodes, uint32(i+1))
+		dirIndexes = append(dirIndexes, uint32(dirIndex[dir]))
+		modes = append(modes, uint16(0100000|fileMode(content)))
+		rdevs = append(rdevs, 0)
+		digests = append(digests, fmt.Sprintf("%x", sha256.Sum256([]byte(content))))
+		linkTos = append(linkTos, "")
+		users = append(users, "root")
+		groups = append(groups, "root")
+		langs = append(langs, "")
+		baseNames = append(baseNames, base)
+		installedSize += len(content)
+	}
+
+	var header rpmHeader
+	header.addStringArray(rpmTagHeaderI18NTable, "C")
+	header.addString(rpmTagName, pkg.Name)
+	header.addString(rpmTagVersion, pkg.upstreamVersion())
+	header.addString(rpmTagRelease, pkg.release())
+	header.addInt32(rpmTagEpoch, 1)
+	header.addI18NString(rpmTagSummary, pkg.summary())
+	header.addI18NString(rpmTagDescription, "Stub package served by the test repository of the install script.")
+	header.addInt32(rpmTagBuildTime, mtime)
+	header.addString(rpmTagBuildHost, buildHost)
+	header.addInt32(rpmTagSize, uint32(installedSize))
+	header.addString(rpmTagVendor, vendor)
+	header.addString(rpmTagLicense, license)
+	header.addString(rpmTagPackager, maintainer)
+	header.addI18NString(rpmTagGroup, "System Environment/Daemons")
+	header.addString(rpmTagURL, "https://www.datadoghq.com")
+	header.addString(rpmTagOS, "linux")
+	header.addString(rpmTagArch, arch)
+	header.addString(rpmTagSourceRPM, fmt.Sprintf("%s-%s.src.rpm", pkg.Name, pkg.Version))
+	header.addString(rpmTagRPMVersion, "4.14.3")
+	header.addString(rpmTagPayloadFormat, "cpio")
+	header.addString(rpmTagPayloadCompressor, "gzip")
+	header.addString(rpmTagPayloadFlags, "9")
+	header.addStringArray(rpmTagProvideName, pkg.Name)
+	header.addInt32(rpmTagProvideFlags, rpmSenseEqual)
+	header.addStringArray(rpmTagProvideVersion, pkg.epochVersion())
+	var requireNames, requireVersions []string
+	var requireFlags []uint32
+	for _, require := range rpmLibRequires {
+		requireNames = append(requireNames, require[0])
+		requireVersions = append(requireVersions, require[1])
+		requireFlags = append(requireFlags, rpmSenseRPMLib|rpmSenseLess|rpmSenseEqual)
+	}
+	header.addStringArray(rpmTagRequireName, requireNames...)
+	header.addInt32(rpmTagRequireFlags, requireFlags...)
+	header.addStringArray(rpmTagRequireVersion, requireVersions...)
+	if pkg.PostInstall != "" {
+		header.addString(rpmTagPostIn, pkg.PostInstall)
+		header.addString(rpmTagPostInProg, "/bin/sh")
+	}
+	if len(paths) > 0 {
+		header.addInt32(rpmTagFileSizes, sizes...)
+		header.addInt16(rpmTagFileModes, modes...)
+		header.addInt16(rpmTagFileRDevs, rdevs...)
+		header.addInt32(rpmTagFileMTimes, mtimes...)
+		header.addStringArray(rpmTagFileDigests, digests...)
+		header.addStringArray(rpmTagFileLinkTos, linkTos...)
+		header.addInt32(rpmTagFileFlags, flags...)
+		header.addStringArray(rpmTagFileUserName, users...)
+		header.addStringArray(rpmTagFileGroupName, groups...)
+		header.addInt32(rpmTagFileVerifyFlags, verifyFlags...)
+		header.addInt32(rpmTagFileDevices, devices...)
+		header.addInt32(rpmTagFileInodes, inodes...)
+		header.addStringArray(rpmTagFileLangs, langs...)
+		header.addInt32(rpmTagDirIndexes, dirIndexes...)
+		header.addStringArray(rpmTagBaseNames, baseNames...)
+		header.addStringArray(rpmTagDirNames, dirNames...)
+		header.addInt32(rpmTagFileDigestAlgo, rpmDigestAlgoSHA256)
+	}
+	headerBytes := header.marshal(rpmTagHeaderImmutable)
+
+	archive := cpioArchive(pkg, paths, mtime)
+	payload, err := gzipBytes(archive)
+	if err != nil {
+		return nil, err
+	}
+	signed := append(append([]byte{}, headerBytes...), payload...)
+
+	headerSignature, err := s.detachSign(headerBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the header of %s: %w", pkg.Name, err)
+	}
+	packageSignature, err := s.detachSign(signed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign %s: %w", pkg.Name, err)
+	}
+	md5sum := md5.Sum(signed)
+	var signature rpmHeader
+	signature.addBin(rpmSigTagRSA, headerSignature)
+	signature.addString(rpmSigTagSHA1, fmt.Sprintf("%x", sha1.Sum(headerBytes)))
+	signature.addString(rpmSigTagSHA256, fmt.Sprintf("%x", sha256.Sum256(headerBytes)))
+	signature.addInt32(rpmSigTagSize, uint32(len(signed)))
+	signature.addBin(rpmSigTagPGP, packageSignature)
+	signature.addBin(rpmSigTagMD5, md5sum[:])
+	signature.addInt32(rpmSigTagPayloadSize, uint32(len(archive)))
+	signatureBytes := signature.marshal(rpmTagHeaderSignatures)
+	// The signature header is padded to 8 bytes
+	for len(signatureBytes)%8 != 0 {
+		signatureBytes = append(signatureBytes, 0)
+	}
+
+	content := rpmLead(fmt.Sprintf("%s-%s", pkg.Name, pkg.Version), rpmLeadArchitectures[arch])
+	content = append(content, signatureBytes...)
+	headerStart := len(content)
+	content = append(content, signed...)
+	return &rpmPackage{
+		pkg:           pkg,
+		arch:          arch,
+		filename:      fmt.Sprintf("%s-%s.%s.rpm", pkg.Name, pkg.Version, arch),
+		content:       content,
+		headerStart:   headerStart,
+		headerEnd:     headerStart + len(headerBytes),
+		archiveSize:   len(archive),
+		installedSize: installedSize,
+		buildTime:     buildTime,
+	}, nil
+}
+
+// rpmLead is the obsolete fixed size preamble of rpm files, only its magic is still checked
+func rpmLead(name string, arch uint16) []byte {
+	lead := []byte{0xed, 0xab, 0xee, 0xdb, 3, 0}
+	lead = binary.BigEndian.AppendUint16(lead, 0) // binary package
+	lead = binary.BigEndian.AppendUint16(lead, arch)
+	var nameField [66]byte
+	copy(nameField[:65], name)
+	lead = append(lead, nameField[:]...)
+	lead = binary.BigEndian.AppendUint16(lead, 1) // linux
+	lead = binary.BigEndian.AppendUint16(lead, 5) // header style signature
+	return append(lead, make([]byte, 16)...)
+}
+
+// cpioArchive returns the payload in the "new ASCII" cpio format, with the "./" prefix rpm expects
+func cpioArchive(pkg Package, paths []string, mtime uint32) []byte {
+	var buf bytes.Buffer
+	writeEntry := func(ino int, mode int64, name string, content string) {
+		fmt.Fprintf(&buf, "070701%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X",
+			ino, mode, 0, 0, 1, mtime, len(content), 0, 0, 0, 0, len(name)+1, 0)
+		buf.WriteString(name)
+		buf.WriteByte(0)
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+		buf.WriteString(content)
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+	}
+	for i, name := range paths {
+		content := pkg.Files[name]
+		writeEntry(i+1, 0100000|fileMode(content), "."+name, content)
+	}
+	writeEntry(0, 0, "TRAILER!!!", "")
+	return buf.Bytes()
+}
@@ -0,0 +1,271 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+)
+
+// parsedHeader is an rpm header read back, enough to check what buildRPM wrote
+type parsedHeader struct {
+	raw     []byte
+	entries map[uint32]rpmEntry
+}
+
+func readHeader(t *testing.T, b []byte) parsedHeader {
+	t.Helper()
+	require.GreaterOrEqual(t, len(b), 16)
+	require.Equal(t, rpmHeaderMagic, b[:8], "bad header magic")
+	count := int(binary.BigEndian.Uint32(b[8:]))
+	size := int(binary.BigEndian.Uint32(b[12:]))
+	indexEnd := 16 + 16*count
+	require.GreaterOrEqual(t, len(b), indexEnd+size)
+	store := b[indexEnd : indexEnd+size]
+
+	header := parsedHeader{raw: b[:indexEnd+size], entries: map[uint32]rpmEntry{}}
+	for i := 0; i < count; i++ {
+		e := b[16+16*i:]
+		entry := rpmEntry{
+			tag:   binary.BigEndian.Uint32(e),
+			typ:   binary.BigEndian.Uint32(e[4:]),
+			count: binary.BigEndian.Uint32(e[12:]),
+		}
+		offset := int(binary.BigEndian.Uint32(e[8:]))
+		switch entry.typ {
+		case rpmTypeString, rpmTypeI18NString, rpmTypeStringArray:
+			end := offset
+			for n := uint32(0); n < entry.count; n++ {
+				end += bytes.IndexByte(store[end:], 0) + 1
+			}
+			entry.data = store[offset:end]
+		case rpmTypeInt16:
+			entry.data = store[offset : offset+2*int(entry.count)]
+		case rpmTypeInt32:
+			entry.data = store[offset : offset+4*int(entry.count)]
+		default:
+			entry.data = store[offset : offset+int(entry.count)]
+		}
+		header.entries[entry.tag] = entry
+	}
+	return header
+}
+
+func (h parsedHeader) strings(tag uint32) []string {
+	data := h.entries[tag].data
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+}
+
+func (h parsedHeader) string(tag uint32) string {
+	return h.strings(tag)[0]
+}
+
+func (h parsedHeader) int32(tag uint32) uint32 {
+	return binary.BigEndian.Uint32(h.entries[tag].data)
+}
+
+func TestRPMHeaderRegion(t *testing.T) {
+	var header rpmHeader
+	header.addString(rpmTagName, "datadog-agent")
+	header.addInt16(rpmTagFileModes, 0100644, 0100755)
+	header.addInt32(rpmTagEpoch, 1)
+	raw := header.marshal(rpmTagHeaderImmutable)
+
+	parsed := readHeader(t, raw)
+	assert.Equal(t, "datadog-agent", parsed.string(rpmTagName))
+	assert.EqualValues(t, 1, parsed.int32(rpmTagEpoch))
+
+	// The region tag comes first and its trailer, at the end of the store, points back to the whole index
+	assert.EqualValues(t, rpmTagHeaderImmutable, binary.BigEndian.Uint32(raw[16:]))
+	trailer := parsed.entries[rpmTagHeaderImmutable].data
+	require.Len(t, trailer, 16)
+	assert.EqualValues(t, rpmTagHeaderImmutable, binary.BigEndian.Uint32(trailer))
+	assert.EqualValues(t, -16*4, int32(binary.BigEndian.Uint32(trailer[8:])))
+
+	for i := 0; i < 4; i++ {
+		entry := raw[16+16*i:]
+		offset := binary.BigEndian.Uint32(entry[8:])
+		switch binary.BigEndian.Uint32(entry[4:]) {
+		case rpmTypeInt16:
+			assert.Zero(t, offset%2, "misaligned int16")
+		case rpmTypeInt32:
+			assert.Zero(t, offset%4, "misaligned int32")
+		}
+	}
+}
+
+func TestRPMPackage(t *testing.T) {
+	s, err := newSigner()
+	require.NoError(t, err)
+	pkg := DefaultPackages("7.60.1-1")[0]
+	rpm, err := buildRPM(pkg, "x86_64", time.Unix(1700000000, 0), s)
+	require.NoError(t, err)
+	assert.Equal(t, "datadog-agent-7.60.1-1.x86_64.rpm", rpm.filename)
+
+	content := rpm.content
+	require.Equal(t, []byte{0xed, 0xab, 0xee, 0xdb}, content[:4], "bad lead magic")
+	signature := readHeader(t, content[96:])
+	require.Equal(t, 96+(len(signature.raw)+7)/8*8, rpm.headerStart, "signature header is not padded to 8 bytes")
+	header := readHeader(t, content[rpm.headerStart:])
+	assert.Equal(t, rpm.headerEnd, rpm.headerStart+len(header.raw))
+	payload := content[rpm.headerEnd:]
+
+	assert.Equal(t, "datadog-agent", header.string(rpmTagName))
+	assert.Equal(t, "7.60.1", header.string(rpmTagVersion))
+	assert.Equal(t, "1", header.string(rpmTagRelease))
+	assert.EqualValues(t, 1, header.int32(rpmTagEpoch))
+	assert.Equal(t, "x86_64", header.string(rpmTagArch))
+	assert.Equal(t, []string{"1:7.60.1-1"}, header.strings(rpmTagProvideVersion))
+	assert.Contains(t, header.string(rpmTagPostIn), "useradd")
+	assert.Equal(t, len(pkg.Files), len(header.strings(rpmTagBaseNames)))
+
+	// Digests and signatures over the header, and over the header and the payload
+	signed := content[rpm.headerStart:]
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256(header.raw)), signature.string(rpmSigTagSHA256))
+	md5sum := md5.Sum(signed)
+	assert.Equal(t, md5sum[:], signature.entries[rpmSigTagMD5].data)
+	assert.EqualValues(t, len(signed), signature.int32(rpmSigTagSize))
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(mustPublicKey(t, s)))
+	require.NoError(t, err)
+	_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(header.raw), bytes.NewReader(signature.entries[rpmSigTagRSA].data))
+	assert.NoError(t, err, "header signature")
+	_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(signed), bytes.NewReader(signature.entries[rpmSigTagPGP].data))
+	assert.NoError(t, err, "package signature")
+
+	// The payload is a cpio archive of the files, with the prefix rpm expects
+	gz, err := gzip.NewReader(bytes.NewReader(payload))
+	require.NoError(t, err)
+	archive, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.EqualValues(t, len(archive), signature.int32(rpmSigTagPayloadSize))
+	assert.True(t, bytes.HasPrefix(archive, []byte("070701")))
+	for name, content := range pkg.Files {
+		assert.Contains(t, string(archive), "."+name+"\x00", "missing %s", name)
+		assert.Contains(t, string(archive), content)
+	}
+	assert.Contains(t, string(archive), "TRAILER!!!")
+}
+
+// TestRPMCheckSig has rpm itself check the signatures, where it is available
+func TestRPMCheckSig(t *testing.T) {
+	rpmkeys, err := exec.LookPath("rpmkeys")
+	if err != nil {
+		t.Skip("rpmkeys is not available")
+	}
+	s, err := newSigner()
+	require.NoError(t, err)
+	rpm, err := buildRPM(DefaultPackages(DefaultVersion)[0], "x86_64", time.Now(), s)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	key := filepath.Join(dir, "key.public")
+	require.NoError(t, os.WriteFile(key, []byte(mustPublicKey(t, s)), 0644))
+	file := filepath.Join(dir, rpm.filename)
+	require.NoError(t, os.WriteFile(file, rpm.content, 0644))
+	dbPath := filepath.Join(dir, "rpmdb")
+
+	output, err := exec.Command(rpmkeys, "--dbpath", dbPath, "--import", key).CombinedOutput()
+	require.NoError(t, err, string(output))
+	output, err = exec.Command(rpmkeys, "--dbpath", dbPath, "--checksig", file).CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.NotContains(t, strings.ToLower(string(output)), "not ok")
+}
+
+func TestRepodata(t *testing.T) {
+	repo := New(t, WithArchitectures("aarch64"))
+	client := httpsClient(t, repo)
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(string(fetch(t, client, repo, "/DATADOG_RPM_KEY_CURRENT.public"))))
+	require.NoError(t, err)
+
+	for _, base := range []string{"/stable/7/aarch64/", "/suse/stable/7/aarch64/"} {
+		repomd := fetch(t, client, repo, base+"repodata/repomd.xml")
+		_, err := openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(repomd), bytes.NewReader(fetch(t, client, repo, base+"repodata/repomd.xml.asc")))
+		require.NoError(t, err, "repomd.xml signature")
+
+		var index struct {
+			Data []struct {
+				Type     string `xml:"type,attr"`
+				Checksum string `xml:"checksum"`
+				Location struct {
+					Href string `xml:"href,attr"`
+				} `xml:"location"`
+			} `xml:"data"`
+		}
+		require.NoError(t, xml.Unmarshal(repomd, &index))
+		require.Len(t, index.Data, 3)
+		var primary []byte
+		for _, data := range index.Data {
+			compressed := fetch(t, client, repo, base+data.Location.Href)
+			assert.Equal(t, data.Checksum, fmt.Sprintf("%x", sha256.Sum256(compressed)), data.Type)
+			if data.Type == "primary" {
+				gz, err := gzip.NewReader(bytes.NewReader(compressed))
+				require.NoError(t, err)
+				primary, err = io.ReadAll(gz)
+				require.NoError(t, err)
+			}
+		}
+
+		var metadata struct {
+			Packages []struct {
+				Name    string `xml:"name"`
+				Arch    string `xml:"arch"`
+				Version struct {
+					Epoch string `xml:"epoch,attr"`
+					Ver   string `xml:"ver,attr"`
+					Rel   string `xml:"rel,attr"`
+				} `xml:"version"`
+				Checksum string `xml:"checksum"`
+				Location struct {
+					Href string `xml:"href,attr"`
+				} `xml:"location"`
+				HeaderRange struct {
+					Start int `xml:"start,attr"`
+					End   int `xml:"end,attr"`
+				} `xml:"format>header-range"`
+			} `xml:"package"`
+		}
+		require.NoError(t, xml.Unmarshal(primary, &metadata))
+		var names []string
+		for _, pkg := range metadata.Packages {
+			names = append(names, pkg.Name)
+			assert.Equal(t, "aarch64", pkg.Arch)
+			assert.Equal(t, "1", pkg.Version.Epoch)
+			if pkg.Name != "datadog-signing-keys" {
+				assert.Equal(t, "7.99.0", pkg.Version.Ver)
+				assert.Equal(t, "1", pkg.Version.Rel)
+			}
+			content := fetch(t, client, repo, base+pkg.Location.Href)
+			assert.Equal(t, pkg.Checksum, fmt.Sprintf("%x", sha256.Sum256(content)))
+			header := readHeader(t, content[pkg.HeaderRange.Start:])
+			assert.Equal(t, pkg.HeaderRange.End, pkg.HeaderRange.Start+len(header.raw))
+			assert.Equal(t, pkg.Name, header.string(rpmTagName))
+		}
+		assert.Subset(t, names, []string{"datadog-agent", "datadog-iot-agent", "datadog-dogstatsd", "datadog-fips-proxy", "datadog-agent-ddot"})
+	}
+}
+
+func mustPublicKey(t *testing.T, s *signer) string {
+	t.Helper()
+	key, err := s.publicKey()
+	require.NoError(t, err)
+	return string(key)
+}
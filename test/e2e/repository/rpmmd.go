@@ -0,0 +1,203 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package repository
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	rpmmdFuncs = template.FuncMap{
+		"xml": func(s string) string {
+			var b strings.Builder
+			_ = xml.EscapeText(&b, []byte(s))
+			return b.String()
+		},
+	}
+
+	primaryTemplate = template.Must(template.New("primary").Funcs(rpmmdFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<metadata xmlns="http://linux.duke.edu/metadata/common" xmlns:rpm="http://linux.duke.edu/metadata/rpm" packages="{{len .}}">
+{{- range .}}
+<package type="rpm">
+  <name>{{xml .Name}}</name>
+  <arch>{{.Arch}}</arch>
+  <version epoch="1" ver="{{xml .Upstream}}" rel="{{xml .Release}}"/>
+  <checksum type="sha256" pkgid="YES">{{.Checksum}}</checksum>
+  <summary>{{xml .Summary}}</summary>
+  <description>Stub package served by the test repository of the install script.</description>
+  <packager>{{xml .Packager}}</packager>
+  <url>https://www.datadoghq.com</url>
+  <time file="{{.Time}}" build="{{.Time}}"/>
+  <size package="{{.PackageSize}}" installed="{{.InstalledSize}}" archive="{{.ArchiveSize}}"/>
+  <location href="{{xml .Location}}"/>
+  <format>
+    <rpm:license>{{xml .License}}</rpm:license>
+    <rpm:vendor>{{xml .Vendor}}</rpm:vendor>
+    <rpm:group>System Environment/Daemons</rpm:group>
+    <rpm:buildhost>{{.BuildHost}}</rpm:buildhost>
+    <rpm:sourcerpm>{{xml .SourceRPM}}</rpm:sourcerpm>
+    <rpm:header-range start="{{.HeaderStart}}" end="{{.HeaderEnd}}"/>
+    <rpm:provides>
+      <rpm:entry name="{{xml .Name}}" flags="EQ" epoch="1" ver="{{xml .Upstream}}" rel="{{xml .Release}}"/>
+    </rpm:provides>
+{{- range .Files}}
+    <file>{{xml .}}</file>
+{{- end}}
+  </format>
+</package>
+{{- end}}
+</metadata>
+`))
+
+	filelistsTemplate = template.Must(template.New("filelists").Funcs(rpmmdFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<filelists xmlns="http://linux.duke.edu/metadata/filelists" packages="{{len .}}">
+{{- range .}}
+<package pkgid="{{.Checksum}}" name="{{xml .Name}}" arch="{{.Arch}}">
+  <version epoch="1" ver="{{xml .Upstream}}" rel="{{xml .Release}}"/>
+{{- range .Files}}
+  <file>{{xml .}}</file>
+{{- end}}
+</package>
+{{- end}}
+</filelists>
+`))
+
+	otherTemplate = template.Must(template.New("other").Funcs(rpmmdFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<otherdata xmlns="http://linux.duke.edu/metadata/other" packages="{{len .}}">
+{{- range .}}
+<package pkgid="{{.Checksum}}" name="{{xml .Name}}" arch="{{.Arch}}">
+  <version epoch="1" ver="{{xml .Upstream}}" rel="{{xml .Release}}"/>
+</package>
+{{- end}}
+</otherdata>
+`))
+
+	repomdTemplate = template.Must(template.New("repomd").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<repomd xmlns="http://linux.duke.edu/metadata/repo" xmlns:rpm="http://linux.duke.edu/metadata/rpm">
+  <revision>{{.Revision}}</revision>
+{{- range .Data}}
+  <data type="{{.Type}}">
+    <checksum type="sha256">{{.Checksum}}</checksum>
+    <open-checksum type="sha256">{{.OpenChecksum}}</open-checksum>
+    <location href="{{.Location}}"/>
+    <timestamp>{{.Timestamp}}</timestamp>
+    <size>{{.Size}}</size>
+    <open-size>{{.OpenSize}}</open-size>
+  </data>
+{{- end}}
+</repomd>
+`))
+)
+
+type rpmmdPackage struct {
+	Name          string
+	Arch          string
+	Upstream      string
+	Release       string
+	Checksum      string
+	Summary       string
+	Packager      string
+	Vendor        string
+	License       string
+	BuildHost     string
+	SourceRPM     string
+	Time          int64
+	PackageSize   int
+	InstalledSize int
+	ArchiveSize   int
+	Location      string
+	HeaderStart   int
+	HeaderEnd     int
+	Files         []string
+}
+
+type rpmmdData struct {
+	Type         string
+	Checksum     string
+	OpenChecksum string
+	Location     string
+	Timestamp    int64
+	Size         int
+	OpenSize     int
+}
+
+// repodata returns the files of the repodata folder of an rpm-md repository holding rpms, keyed by their path
+// relative to the repository, repomd.xml included but not signed
+func repodata(rpms []*rpmPackage, timestamp time.Time) (map[string][]byte, error) {
+	packages := make([]rpmmdPackage, 0, len(rpms))
+	for _, rpm := range rpms {
+		packages = append(packages, rpmmdPackage{
+			Name:          rpm.pkg.Name,
+			Arch:          rpm.arch,
+			Upstream:      rpm.pkg.upstreamVersion(),
+			Release:       rpm.pkg.release(),
+			Checksum:      fmt.Sprintf("%x", sha256.Sum256(rpm.content)),
+			Summary:       rpm.pkg.summary(),
+			Packager:      maintainer,
+			Vendor:        vendor,
+			License:       license,
+			BuildHost:     buildHost,
+			SourceRPM:     fmt.Sprintf("%s-%s.src.rpm", rpm.pkg.Name, rpm.pkg.Version),
+			Time:          rpm.buildTime.Unix(),
+			PackageSize:   len(rpm.content),
+			InstalledSize: rpm.installedSize,
+			ArchiveSize:   rpm.archiveSize,
+			Location:      rpm.filename,
+			HeaderStart:   rpm.headerStart,
+			HeaderEnd:     rpm.headerEnd,
+			Files:         rpm.pkg.paths(),
+		})
+	}
+
+	files := map[string][]byte{}
+	var data []rpmmdData
+	for _, metadata := range []struct {
+		kind     string
+		template *template.Template
+	}{
+		{"primary", primaryTemplate},
+		{"filelists", filelistsTemplate},
+		{"other", otherTemplate},
+	} {
+		var open bytes.Buffer
+		if err := metadata.template.Execute(&open, packages); err != nil {
+			return nil, err
+		}
+		compressed, err := gzipBytes(open.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		checksum := fmt.Sprintf("%x", sha256.Sum256(compressed))
+		// Content addressed names, as createrepo does, so that no client ever reads stale metadata
+		location := fmt.Sprintf("repodata/%s-%s.xml.gz", checksum, metadata.kind)
+		files[location] = compressed
+		data = append(data, rpmmdData{
+			Type:         metadata.kind,
+			Checksum:     checksum,
+			OpenChecksum: fmt.Sprintf("%x", sha256.Sum256(open.Bytes())),
+			Location:     location,
+			Timestamp:    timestamp.Unix(),
+			Size:         len(compressed),
+			OpenSize:     open.Len(),
+		})
+	}
+
+	var repomd bytes.Buffer
+	if err := repomdTemplate.Execute(&repomd, struct {
+		Revision int64
+		Data     []rpmmdData
+	}{timestamp.Unix(), data}); err != nil {
+		return nil, err
+	}
+	files["repodata/repomd.xml"] = repomd.Bytes()
+	return files, nil
+}
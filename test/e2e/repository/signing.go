@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package repository
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+
+	// The frozen x/crypto implementation is enough for RSA signatures, which is all apt, rpm and zypper need
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// signer holds the throwaway OpenPGP key signing the repository metadata and the packages
+type signer struct {
+	entity *openpgp.Entity
+	config *packet.Config
+}
+
+func newSigner() (*signer, error) {
+	config := &packet.Config{DefaultHash: crypto.SHA256, RSABits: 2048}
+	entity, err := openpgp.NewEntity("Datadog Test Repository", "throwaway key", "package+test@datadoghq.com", config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the signing key: %w", err)
+	}
+	return &signer{entity: entity, config: config}, nil
+}
+
+// fingerprint returns the hexadecimal fingerprint of the key, in upper case as gpg prints it
+func (s *signer) fingerprint() string {
+	return fmt.Sprintf("%X", s.entity.PrimaryKey.Fingerprint)
+}
+
+// keyID returns the short key id, as used in the gpg-pubkey-<id> package rpm creates on import
+func (s *signer) keyID() string {
+	return fmt.Sprintf("%08x", uint32(s.entity.PrimaryKey.KeyId))
+}
+
+// publicKey returns the ASCII armored public key
+func (s *signer) publicKey() ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.entity.Serialize(w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// detachSign returns a binary detached signature of data, the format rpm stores in its signature header
+func (s *signer) detachSign(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := openpgp.DetachSign(&buf, s.entity, bytes.NewReader(data), s.config); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// armoredDetachSign returns an ASCII armored detached signature of data, as Release.gpg and repomd.xml.asc
+func (s *signer) armoredDetachSign(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&buf, s.entity, bytes.NewReader(data), s.config); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// clearSign returns data wrapped in a cleartext signature, as InRelease
+func (s *signer) clearSign(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, s.entity.PrivateKey, s.config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
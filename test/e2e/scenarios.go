@@ -0,0 +1,225 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/agentconfig"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/installlog"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/replay"
+	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
+	"github.com/stretchr/testify/assert"
+)
+
+// installScenario describes an install with a set of DD_* options, and what the script must leave on the host. The
+// runner installs the latest Agent 7, checks the expectations, replays the install, then uninstalls and purges.
+type installScenario struct {
+	// name is part of the test and stack names, keep it short: stack names are limited in length
+	name        string
+	description string
+	// options of the install script, the description defaults to the one of the scenario
+	options InstallOptions
+	// flavors and platforms the scenario applies to, all of them when empty
+	flavors   []agentFlavor
+	platforms []string
+	// skipInstallAssertions skips the checks of the user, ownership and services, for options changing them
+	skipInstallAssertions bool
+	// services lists the features adding services expected to run, see expectedServices
+	services serviceFeatures
+
+	// files, relative to /etc/<base name>, expected after install and uninstall and removed by purge
+	files []string
+	// absentFiles, relative to /etc/<base name>, must not be created by the install
+	absentFiles []string
+	// logLines are expected in the output of the install script
+	logLines []string
+
+	// Expected values of the configuration files, see configExpectation
+	datadogConfig       configExpectation
+	systemProbeConfig   configExpectation
+	securityAgentConfig configExpectation
+	otelConfig          configExpectation
+	// environment lists variables expected in /etc/environment
+	environment map[string]string
+
+	// replayOptions are the options of the replay following the install checks, the install options when nil.
+	// replayChanges are the files and entries the replay may change, see replay.Allowance.
+	replayOptions *InstallOptions
+	replayChanges []replay.Allowance
+}
+
+// configExpectation lists dotted paths of a YAML configuration and their expected values, as yaml.v2 decodes them:
+// ints are int and lists []any
+type configExpectation struct {
+	values map[string]any
+	absent []string
+}
+
+func (e configExpectation) isEmpty() bool {
+	return len(e.values) == 0 && len(e.absent) == 0
+}
+
+func (s installScenario) appliesTo(flavor agentFlavor, platform string) bool {
+	if flavor == "" {
+		flavor = defaultAgentFlavor
+	}
+	return (len(s.flavors) == 0 || slices.Contains(s.flavors, flavor)) &&
+		(len(s.platforms) == 0 || slices.Contains(s.platforms, platform))
+}
+
+type installScenarioTestSuite struct {
+	linuxInstallerTestSuite
+	scenario installScenario
+}
+
+// runInstallScenarios runs a suite for each scenario applying to the flavor and platform under test
+func runInstallScenarios(t *testing.T, scenarios []installScenario) {
+	for _, scenario := range scenarios {
+		stackName := fmt.Sprintf("install-%s-%s-%s-%s", scenario.name, flavor, platform, getenv("CI_PIPELINE_ID", "dev"))
+		t.Run(stackName, func(t *testing.T) {
+			if !scenario.appliesTo(flavor, platform) {
+				t.Skipf("%s scenario doesn't apply to %s on %s", scenario.name, flavor, platform)
+			}
+			t.Logf("We will install %s with %s with install script on %s", flavor, scenario.description, platform)
+			testSuite := &installScenarioTestSuite{scenario: scenario}
+			testSuite.services = scenario.services
+			e2e.Run(t,
+				testSuite,
+				testSuite.provisionerOption(t),
+				e2e.WithStackName(stackName),
+			)
+		})
+	}
+}
+
+func (s *installScenarioTestSuite) TestInstallScenario() {
+	options := s.scenario.options
+	if options.Description == "" {
+		options.Description = fmt.Sprintf("Install latest Agent 7 with %s", s.scenario.description)
+	}
+	output := s.InstallAgent(options)
+
+	s.assertInstallScenario(output)
+
+	s.assertReplay(options)
+
+	s.addExtraIntegration()
+
+	s.uninstall()
+
+	s.assertUninstall()
+
+	s.purge()
+
+	s.assertPurge()
+}
+
+func (s *installScenarioTestSuite) assertInstallScenario(installCommandOutput string) {
+	if !s.scenario.skipInstallAssertions {
+		s.linuxInstallerTestSuite.assertInstallScript(true)
+	}
+	t := s.T()
+	vm := s.host()
+
+	t.Log("Assert install output contains expected lines")
+	for _, line := range s.scenario.logLines {
+		assert.Contains(t, installCommandOutput, line, "missing installer log line")
+	}
+
+	t.Log("Assert expected files are created")
+	for _, file := range s.scenario.files {
+		assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, file))
+	}
+	for _, file := range s.scenario.absentFiles {
+		assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, file))
+	}
+
+	t.Log("Assert configuration contains expected properties")
+	for file, expectation := range map[string]configExpectation{
+		s.configFile:                s.scenario.datadogConfig,
+		systemProbeConfigFileName:   s.scenario.systemProbeConfig,
+		securityAgentConfigFileName: s.scenario.securityAgentConfig,
+		otelConfigFileName:          s.scenario.otelConfig,
+	} {
+		if !expectation.isEmpty() {
+			s.assertConfigValues(fmt.Sprintf("/etc/%s/%s", s.baseName, file), expectation)
+		}
+	}
+	if len(s.scenario.environment) > 0 {
+		var names []string
+		for name := range s.scenario.environment {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		env := s.loadEnvironment(envFile, names...)
+		for _, name := range names {
+			assert.Equal(t, s.scenario.environment[name], env.Values[name], "unexpected %s in %s", name, envFile)
+		}
+	}
+}
+
+// assertReplay runs the script again, with the replay options of the scenario or the same ones, and checks that it
+// keeps the configuration and only makes the changes the scenario declares
+func (s *installScenarioTestSuite) assertReplay(options InstallOptions) {
+	t := s.T()
+	replayOptions := options
+	replayOptions.Description = fmt.Sprintf("Replay the install of latest Agent 7 with %s", s.scenario.description)
+	if s.scenario.replayOptions != nil {
+		replayOptions = *s.scenario.replayOptions
+		replayOptions.Description = fmt.Sprintf("Replay the install of latest Agent 7 with %s, with other options", s.scenario.description)
+	}
+	output, _ := s.replayInstall(replayOptions, s.scenario.replayChanges...)
+
+	t.Log("Assert the replay kept the configuration")
+	steps := installlog.Parse(output)
+	assert.Contains(t, steps.OfKind(installlog.KindKeepConfig).Files(), fmt.Sprintf("/etc/%s/%s", s.baseName, s.configFile))
+}
+
+func (s *installScenarioTestSuite) assertConfigValues(path string, expectation configExpectation) {
+	t := s.T()
+	t.Helper()
+	var paths []string
+	for key := range expectation.values {
+		paths = append(paths, key)
+	}
+	sort.Strings(paths)
+	var config agentconfig.Document
+	s.loadConfig(path, &config, paths...)
+	for _, key := range paths {
+		value, _ := config.Value(key)
+		assert.Equal(t, expectation.values[key], value, "unexpected %s in %s", key, path)
+	}
+	for _, key := range expectation.absent {
+		assert.False(t, config.Has(key), "%s should not be set in %s", key, path)
+	}
+}
+
+func (s *installScenarioTestSuite) assertUninstall() {
+	s.linuxInstallerTestSuite.assertUninstall()
+	t := s.T()
+	vm := s.host()
+	t.Log("Assert scenario files are there after uninstall")
+	for _, file := range s.scenario.files {
+		assertFileExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, file))
+	}
+}
+
+func (s *installScenarioTestSuite) assertPurge() {
+	if s.shouldSkipPurge() {
+		return
+	}
+	s.linuxInstallerTestSuite.assertPurge()
+	t := s.T()
+	vm := s.host()
+	t.Log("Assert scenario files are removed after purge")
+	for _, file := range s.scenario.files {
+		assertFileNotExists(t, vm, fmt.Sprintf("/etc/%s/%s", s.baseName, file))
+	}
+}
@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// minSecretLength skips the values too short to be secrets, such as the booleans of the PAR options
+const minSecretLength = 8
+
+// secretEnvNames are the variables of the script holding secrets, with secretEnvPrefixes for the Private Action Runner
+// enrollment inputs
+var (
+	secretEnvNames    = []string{"DD_API_KEY", "DD_APP_KEY"}
+	secretEnvPrefixes = []string{"DD_PRIVATE_ACTION_RUNNER_"}
+)
+
+// secretArtifact is a file the script leaves behind, holdsSecrets is set for the configuration files the Agent reads
+// its keys from: they must not be readable by others, the other artifacts must not contain any secret
+type secretArtifact struct {
+	path         string
+	holdsSecrets bool
+}
+
+// secretArtifacts returns the files audited for leaked secrets, relative paths are relative to the home of the test
+// user
+func (s *linuxInstallerTestSuite) secretArtifacts() []secretArtifact {
+	return []secretArtifact{
+		{path: "ddagent-install.log"},
+		{path: "/tmp/datadog-installer-trace.json"},
+		{path: "/tmp/datadog-installer-log.json"},
+		{path: "/tmp/datadog-installer-stdout.log"},
+		{path: "/tmp/datadog-installer-stderr.log"},
+		{path: "/tmp/ddog_install_error_msg"},
+		{path: envFile},
+		{path: "/etc/datadog-agent/environment"},
+		{path: ".bash_history"},
+		{path: "/root/.bash_history"},
+		{path: fmt.Sprintf("/etc/%s/%s", s.baseName, s.configFile), holdsSecrets: true},
+		{path: fmt.Sprintf("/etc/%s/%s", s.baseName, otelConfigFileName), holdsSecrets: true},
+	}
+}
+
+// secretsFromEnv returns the secrets among unquoted NAME=value assignments, such as the environment of the script
+func secretsFromEnv(env ...string) map[string]string {
+	secrets := map[string]string{}
+	for _, assignment := range env {
+		name, value, ok := strings.Cut(assignment, "=")
+		if ok && isSecretEnvName(name) && len(value) >= minSecretLength {
+			secrets[name] = value
+		}
+	}
+	return secrets
+}
+
+func isSecretEnvName(name string) bool {
+	for _, secretName := range secretEnvNames {
+		if name == secretName {
+			return true
+		}
+	}
+	for _, prefix := range secretEnvPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// findSecrets returns the sorted names of the secrets found in content
+func findSecrets(content string, secrets map[string]string) []string {
+	var found []string
+	for name, value := range secrets {
+		if strings.Contains(content, value) {
+			found = append(found, name)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// redactSecrets replaces the values of the secrets in content, e.g. the API key of datadog.yaml in the diagnostics
+func redactSecrets(content []byte, secrets map[string]string) []byte {
+	for name, value := range secrets {
+		content = bytes.ReplaceAll(content, []byte(value), []byte("<"+name+">"))
+	}
+	return content
+}
+
+// trackInstall records the environment and the output of an install of the script, for auditSecrets. InstallAgent
+// calls it.
+func (s *linuxInstallerTestSuite) trackInstall(env []string, output string) {
+	if s.secrets == nil {
+		s.secrets = secretsFromEnv(fmt.Sprintf("DD_API_KEY=%s", apiKey))
+	}
+	for name, value := range secretsFromEnv(env...) {
+		s.secrets[name] = value
+	}
+	s.installOutputs = append(s.installOutputs, output)
+}
+
+// resetInstalls forgets the installs recorded by trackInstall, once the test is over
+func (s *linuxInstallerTestSuite) resetInstalls() {
+	s.secrets, s.installOutputs = nil, nil
+}
+
+// auditSecrets fails the test when a secret given to the script shows in its output or in a file it leaves behind,
+// and when a configuration file holding secrets is readable by others
+func (s *linuxInstallerTestSuite) auditSecrets() {
+	t := s.T()
+	t.Helper()
+	secrets, outputs := s.secrets, s.installOutputs
+	if len(secrets) == 0 {
+		return
+	}
+	vm := s.host()
+
+	t.Log("Audit the install output and artifacts for leaked secrets")
+	for i, output := range outputs {
+		leaked := findSecrets(output, secrets)
+		assert.Empty(t, leaked, "secrets leaked in the output of install %d", i+1)
+	}
+	for _, artifact := range s.secretArtifacts() {
+		// stat fails on missing files, the script doesn't write all of them on every install
+		stat, err := vm.Execute(fmt.Sprintf("sudo stat -c '%%a' %s", artifact.path))
+		if err != nil {
+			continue
+		}
+		mode, err := strconv.ParseUint(strings.TrimSpace(stat), 8, 32)
+		if !assert.NoError(t, err, "unexpected mode of %s", artifact.path) {
+			continue
+		}
+		worldReadable := mode&0o004 != 0
+		if artifact.holdsSecrets {
+			assert.False(t, worldReadable, "%s holds secrets and is readable by others, mode %o", artifact.path, mode)
+			continue
+		}
+		content, err := vm.Execute(fmt.Sprintf("sudo cat %s", artifact.path))
+		if !assert.NoError(t, err, "failed to read %s", artifact.path) {
+			continue
+		}
+		leaked := findSecrets(content, secrets)
+		assert.Empty(t, leaked, "secrets leaked in %s, mode %o", artifact.path, mode)
+	}
+}
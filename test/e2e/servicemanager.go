@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package e2e
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	serviceTrace       = "datadog-agent-trace"
+	serviceProcess     = "datadog-agent-process"
+	serviceSecurity    = "datadog-agent-security"
+	serviceSystemProbe = "datadog-agent-sysprobe"
+	serviceDDOT        = "datadog-agent-ddot"
+	serviceFIPSProxy   = "datadog-fips-proxy"
+)
+
+// serviceFeatures lists the features of an install adding services to the ones of the flavor
+type serviceFeatures struct {
+	// process is off by default: process-agent exits shortly after start when no check needs it
+	process     bool
+	security    bool
+	systemProbe bool
+	ddot        bool
+	fipsProxy   bool
+}
+
+// expectedServices returns the services running after an install of the flavor with the features
+func expectedServices(flavor agentFlavor, features serviceFeatures) []string {
+	services := []string{baseNameByFlavor[flavor]}
+	if flavor == "" || flavor == agentFlavorDatadogAgent {
+		services = append(services, serviceTrace)
+		if features.process {
+			services = append(services, serviceProcess)
+		}
+		if features.security {
+			services = append(services, serviceSecurity)
+		}
+		if features.systemProbe {
+			services = append(services, serviceSystemProbe)
+		}
+		if features.ddot {
+			services = append(services, serviceDDOT)
+		}
+	}
+	if features.fipsProxy {
+		services = append(services, serviceFIPSProxy)
+	}
+	return services
+}
+
+// serviceManager controls the services of the host under test, with systemd, upstart or SysV init scripts like the
+// install script
+type serviceManager interface {
+	name() string
+	isActive(service string) (bool, error)
+	isEnabled(service string) (bool, error)
+	start(service string) error
+	stop(service string) error
+	// status returns the human readable status of the service, whether it runs or not
+	status(service string) (string, error)
+	// logs returns the logs of the services, from journalctl or /var/log
+	logs(services ...string) (string, error)
+}
+
+// detectServiceManager returns the service manager of the host, detected the same way as the install script
+func detectServiceManager(vm installerHost) (serviceManager, error) {
+	if comm, err := vm.Execute("sudo ps --no-headers -o comm 1"); err == nil && strings.TrimSpace(comm) == "systemd" {
+		if _, err = vm.Execute("command -v systemctl"); err == nil {
+			return systemdServiceManager{vm}, nil
+		}
+	}
+	if _, err := vm.Execute("/sbin/init --version 2>&1 | grep -q upstart"); err == nil {
+		return upstartServiceManager{vm}, nil
+	}
+	// /sbin is not in the PATH of a base user on SUSE 11, the script calls the path `which` finds as root
+	if path, err := vm.Execute("sudo which service"); err == nil && strings.TrimSpace(path) != "" {
+		return sysvServiceManager{vm, strings.TrimSpace(path)}, nil
+	}
+	return nil, errors.New("unknown service manager, neither systemd, upstart nor a service command is available")
+}
+
+// succeeds runs a command whose exit code is the answer
+func succeeds(vm installerHost, command string) (bool, error) {
+	output, err := vm.Execute(fmt.Sprintf("(%s) >/dev/null 2>&1 && echo yes || true", command))
+	return strings.TrimSpace(output) == "yes", err
+}
+
+// varLogs returns the end of the Agent logs and of the files of /var/log named after the services
+func varLogs(vm installerHost, services ...string) (string, error) {
+	files := []string{"/var/log/datadog/*.log"}
+	for _, service := range services {
+		files = append(files, fmt.Sprintf("/var/log/upstart/%s.log", service), fmt.Sprintf("/var/log/%s.log", service))
+	}
+	return vm.Execute(fmt.Sprintf("sudo tail -n 200 %s 2>/dev/null || true", strings.Join(files, " ")))
+}
+
+type systemdServiceManager struct {
+	vm installerHost
+}
+
+func (systemdServiceManager) name() string {
+	return "systemd"
+}
+
+func (m systemdServiceManager) isActive(service string) (bool, error) {
+	return succeeds(m.vm, "systemctl is-active --quiet "+service)
+}
+
+func (m systemdServiceManager) isEnabled(service string) (bool, error) {
+	return succeeds(m.vm, "systemctl is-enabled --quiet "+service)
+}
+
+func (m systemdServiceManager) start(service string) error {
+	_, err := m.vm.Execute("sudo systemctl start " + service)
+	return err
+}
+
+func (m systemdServiceManager) stop(service string) error {
+	_, err := m.vm.Execute("sudo systemctl stop " + service)
+	return err
+}
+
+func (m systemdServiceManager) status(service string) (string, error) {
+	// systemctl status exits with 3 when the service isn't running
+	return m.vm.Execute(fmt.Sprintf("sudo systemctl status --no-pager %s || true", service))
+}
+
+func (m systemdServiceManager) logs(services ...string) (string, error) {
+	var units []string
+	for _, service := range services {
+		units = append(units, "-u "+service)
+	}
+	return m.vm.Execute("sudo journalctl --no-pager " + strings.Join(units, " "))
+}
+
+type upstartServiceManager struct {
+	vm installerHost
+}
+
+func (upstartServiceManager) name() string {
+	return "upstart"
+}
+
+func (m upstartServiceManager) isActive(service string) (bool, error) {
+	status, err := m.status(service)
+	return strings.Contains(status, "start/running"), err
+}
+
+func (m upstartServiceManager) isEnabled(service string) (bool, error) {
+	// a job starts on boot unless an override file marks it manual
+	return succeeds(m.vm, fmt.Sprintf("test -f /etc/init/%[1]s.conf && ! grep -qs '^manual' /etc/init/%[1]s.override", service))
+}
+
+func (m upstartServiceManager) start(service string) error {
+	_, err := m.vm.Execute("sudo start " + service)
+	return err
+}
+
+func (m upstartServiceManager) stop(service string) error {
+	_, err := m.vm.Execute("sudo stop " + service)
+	return err
+}
+
+func (m upstartServiceManager) status(service string) (string, error) {
+	return m.vm.Execute(fmt.Sprintf("sudo status %s 2>&1 || true", service))
+}
+
+func (m upstartServiceManager) logs(services ...string) (string, error) {
+	return varLogs(m.vm, services...)
+}
+
+type sysvServiceManager struct {
+	vm installerHost
+	// service is the absolute path of the service command
+	service string
+}
+
+func (sysvServiceManager) name() string {
+	return "sysv"
+}
+
+func (m sysvServiceManager) isActive(service string) (bool, error) {
+	return succeeds(m.vm, fmt.Sprintf("sudo %s %s status", m.service, service))
+}
+
+func (m sysvServiceManager) isEnabled(service string) (bool, error) {
+	return succeeds(m.vm, fmt.Sprintf("ls /etc/rc[2-5].d/S*%[1]s || ls /etc/init.d/rc[2-5].d/S*%[1]s", service))
+}
+
+func (m sysvServiceManager) start(service string) error {
+	_, err := m.vm.Execute(fmt.Sprintf("sudo %s %s start", m.service, service))
+	return err
+}
+
+func (m sysvServiceManager) stop(service string) error {
+	_, err := m.vm.Execute(fmt.Sprintf("sudo %s %s stop", m.service, service))
+	return err
+}
+
+func (m sysvServiceManager) status(service string) (string, error) {
+	return m.vm.Execute(fmt.Sprintf("sudo %s %s status 2>&1 || true", m.service, service))
+}
+
+func (m sysvServiceManager) logs(services ...string) (string, error) {
+	return varLogs(m.vm, services...)
+}
@@ -0,0 +1,8 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package ssi serves scripted install-ssi.sh responses in place of install.datadoghq.com, so that the way
+// install_apm_ssi streams the script into bash, and reports download and script failures, can be tested offline.
+package ssi
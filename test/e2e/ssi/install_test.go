@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package ssi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	apiKey     = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	stdoutLog  = "/tmp/datadog-installer-stdout.log"
+	stderrLog  = "/tmp/datadog-installer-stderr.log"
+	downloadKO = "Error: Unable to download the installer script from "
+)
+
+// installSSI runs install_apm_ssi alone, the script needs curl to download the repository keys before reaching it
+var installSSI = []string{"_install_installer_script", "install_apm_ssi"}
+
+// transport is how the host downloads install-ssi.sh
+type transport struct {
+	name    string
+	options []hermetic.Option
+	// userAgent prefixes the user agent of its requests
+	userAgent string
+}
+
+var transports = []transport{
+	{name: "curl", userAgent: "curl/"},
+	{name: "wget", options: []hermetic.Option{hermetic.WithoutCommands("curl")}, userAgent: "Wget/"},
+}
+
+func TestInstallSSI(t *testing.T) {
+	tests := []struct {
+		name     string
+		response Response
+		env      map[string]string
+		// path is the path requested, ScriptPath by default
+		path string
+		// failure is the error printed, empty when the install succeeds
+		failure string
+		stdout  string
+		stderr  string
+	}{
+		{
+			name:     "success",
+			response: Exiting(0, "installed", "a warning"),
+			stdout:   "installed\n",
+			stderr:   "a warning\n",
+		},
+		{
+			name:     "pipeline",
+			response: Exiting(0, "installed", ""),
+			env:      map[string]string{"DD_APM_INSTRUMENTATION_PIPELINE_ID": "42"},
+			path:     "/pipeline-42" + ScriptPath,
+			stdout:   "installed\n",
+			stderr:   "\n",
+		},
+		{
+			name:     "not found",
+			response: NotFound(),
+			failure:  downloadKO + "https://{host}" + ScriptPath,
+		},
+		{
+			name:     "script failure",
+			response: Exiting(7, "installing", "no space left"),
+			failure:  "Error: The installer script failed with exit code 7",
+			stdout:   "installing\n",
+			stderr:   "no space left\n",
+		},
+		{
+			// bash runs the commands received before the download failed
+			name:     "truncated",
+			response: Truncated("installing"),
+			failure:  downloadKO + "https://{host}" + ScriptPath,
+			stdout:   "installing\n",
+		},
+		{
+			// the download fails with a broken pipe, which is benign once bash succeeded
+			name:     "stdin closed early",
+			response: ClosingStdin(0, "installed"),
+			stdout:   "installed\n",
+		},
+		{
+			// the broken pipe of the download is reported instead of the failure of the script
+			name:     "stdin closed early by a failing script",
+			response: ClosingStdin(5, "installing"),
+			failure:  downloadKO + "https://{host}" + ScriptPath,
+			stdout:   "installing\n",
+		},
+	}
+	for _, transport := range transports {
+		for _, tt := range tests {
+			t.Run(transport.name+"/"+tt.name, func(t *testing.T) {
+				server := New(t, tt.response)
+				h := hermetic.New(t, append(transport.options, hermetic.WithPassthrough(`127\.0\.0\.1`))...)
+				env := server.Env()
+				env["DD_APM_INSTRUMENTATION_ENABLED"] = "host"
+				for key, value := range tt.env {
+					env[key] = value
+				}
+				result := h.RunFunctions(env, `install_apm_ssi ""`, installSSI...)
+				// the failures are reported, not returned
+				require.Equal(t, 0, result.ExitCode, result.Output)
+
+				if tt.failure == "" {
+					assert.NotContains(t, result.Output, "Error:")
+				} else {
+					assert.Contains(t, result.Output, strings.ReplaceAll(tt.failure, "{host}", server.Host()))
+				}
+				assert.NotContains(t, result.Output, "not reached")
+				assert.Equal(t, tt.stdout, h.ReadFile(stdoutLog))
+				assert.Equal(t, tt.stderr, h.ReadFile(stderrLog))
+
+				requests := server.Requests()
+				require.NotEmpty(t, requests)
+				path := tt.path
+				if path == "" {
+					path = ScriptPath
+				}
+				for _, request := range requests {
+					assert.Equal(t, "GET", request.Method)
+					assert.Equal(t, path, request.Path)
+					assert.True(t, strings.HasPrefix(request.UserAgent, transport.userAgent), request.UserAgent)
+				}
+			})
+		}
+	}
+}
+
+func TestInstallSSIURL(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		// url is the URL downloaded, empty when install_apm_ssi does nothing
+		url string
+	}{
+		{
+			name: "default",
+			env:  map[string]string{"DD_APM_INSTRUMENTATION_ENABLED": "host"},
+			url:  "https://install.datadoghq.com/scripts/install-ssi.sh",
+		},
+		{
+			name: "datadoghq.eu",
+			env:  map[string]string{"DD_APM_INSTRUMENTATION_ENABLED": "host", "DD_SITE": "datadoghq.eu"},
+			url:  "https://install.datadoghq.com/scripts/install-ssi.sh",
+		},
+		{
+			name: "datad0g.com",
+			env:  map[string]string{"DD_APM_INSTRUMENTATION_ENABLED": "host", "DD_SITE": "datad0g.com"},
+			url:  "https://install.datad0g.com/scripts/install-ssi.sh",
+		},
+		{
+			name: "pipeline on datad0g.com",
+			env:  map[string]string{"DD_APM_INSTRUMENTATION_ENABLED": "all", "DD_SITE": "datad0g.com", "DD_APM_INSTRUMENTATION_PIPELINE_ID": "42"},
+			url:  "https://install.datad0g.com/pipeline-42/scripts/install-ssi.sh",
+		},
+		{
+			name: "installer domain override",
+			env: map[string]string{
+				"DD_APM_INSTRUMENTATION_ENABLED": "docker",
+				"DD_SITE":                        "datad0g.com",
+				"DD_INSTALLER_REGISTRY_URL_INSTALLER_PACKAGE": "registry.example.com",
+			},
+			url: "https://registry.example.com/scripts/install-ssi.sh",
+		},
+		{
+			name: "instrumentation disabled",
+			env:  map[string]string{"DD_SITE": "datad0g.com"},
+		},
+	}
+	for _, transport := range transports {
+		for _, tt := range tests {
+			t.Run(transport.name+"/"+tt.name, func(t *testing.T) {
+				h := hermetic.New(t, transport.options...)
+				result := h.RunFunctions(tt.env, `install_apm_ssi ""`, installSSI...)
+				require.Equal(t, 0, result.ExitCode, result.Output)
+
+				calls := result.CallsTo(transport.name)
+				if tt.url == "" {
+					assert.Empty(t, calls)
+					return
+				}
+				require.Len(t, calls, 1, result.Transcript())
+				assert.Equal(t, tt.url, calls[0].Args[len(calls[0].Args)-1])
+			})
+		}
+	}
+}
+
+func TestInstallScriptIgnoresSSIFailure(t *testing.T) {
+	server := New(t, Exiting(7, "installing", "no space left"))
+	h := hermetic.New(t, hermetic.WithPassthrough(`127\.0\.0\.1`))
+	env := server.Env()
+	env["DD_API_KEY"] = apiKey
+	env["DD_APM_INSTRUMENTATION_ENABLED"] = "host"
+	env["DD_INSTRUMENTATION_TELEMETRY_ENABLED"] = "false"
+	result := h.Run(env)
+
+	require.Equal(t, 0, result.ExitCode, result.Output)
+	assert.Contains(t, result.Output, "Error: The installer script failed with exit code 7")
+	assert.Contains(t, result.Output, "Your Datadog Agent is running and functioning properly.")
+	assert.Len(t, server.Requests(), 1)
+	assert.Equal(t, "no space left\n", h.ReadFile(stderrLog))
+}
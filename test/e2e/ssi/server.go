@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package ssi
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ScriptPath is the path of install-ssi.sh on the installer domain
+const ScriptPath = "/scripts/install-ssi.sh"
+
+// Response is what the server answers to every request
+type Response struct {
+	// Status is the HTTP status, 200 by default
+	Status int
+	Script string
+	// TruncateAt closes the connection after that many bytes of Script, once Content-Length announced all of it
+	TruncateAt int
+}
+
+// NotFound answers 404, as for an unknown pipeline
+func NotFound() Response {
+	return Response{Status: http.StatusNotFound, Script: "Not Found\n"}
+}
+
+// Exiting is a script printing stdout and stderr, then exiting with code
+func Exiting(code int, stdout string, stderr string) Response {
+	return Response{Script: fmt.Sprintf("echo %s\necho %s >&2\nexit %d\n", shellQuote(stdout), shellQuote(stderr), code)}
+}
+
+// Truncated is a script whose download fails after its first line, which prints stdout. bash runs what it received.
+func Truncated(stdout string) Response {
+	script := fmt.Sprintf("echo %s\necho 'not reached'\n", shellQuote(stdout))
+	return Response{Script: script, TruncateAt: strings.Index(script, "\n") + 1}
+}
+
+// ClosingStdin is a script closing its standard input after its first line then exiting with code. bash reads the
+// script from the pipe, so it stops reading while the download still writes the padding that follows, bigger than
+// any pipe buffer.
+func ClosingStdin(code int, stdout string) Response {
+	return Response{Script: fmt.Sprintf("echo %s; exec 0<&-; exit %d\n", shellQuote(stdout), code) +
+		strings.Repeat("# padding past the pipe buffer\n", 1<<15)}
+}
+
+// Request is a request received by the server
+type Request struct {
+	Method    string
+	Path      string
+	UserAgent string
+}
+
+// Server serves a Response over https until the end of the test
+type Server struct {
+	response Response
+	server   *httptest.Server
+	caFile   string
+	wgetrc   string
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// New starts a server answering response to every request
+func New(t testing.TB, response Response) *Server {
+	t.Helper()
+	if response.Status == 0 {
+		response.Status = http.StatusOK
+	}
+	s := &Server{response: response}
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+
+	// the certificate of httptest is its own CA
+	dir := t.TempDir()
+	s.caFile = filepath.Join(dir, "ssi-ca.pem")
+	require.NoError(t, os.WriteFile(s.caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.server.Certificate().Raw}), 0644))
+	// wget built with GnuTLS ignores SSL_CERT_FILE, it reads the CA from its configuration
+	s.wgetrc = filepath.Join(dir, "wgetrc")
+	require.NoError(t, os.WriteFile(s.wgetrc, []byte("ca_certificate = "+s.caFile+"\n"), 0644))
+	return s
+}
+
+// Host returns the host:port of the server, the installer domain the script has to use
+func (s *Server) Host() string {
+	return strings.TrimPrefix(s.server.URL, "https://")
+}
+
+// Env returns the variables pointing install_apm_ssi to the server, and curl and wget to its CA
+func (s *Server) Env() map[string]string {
+	return map[string]string{
+		"DD_INSTALLER_REGISTRY_URL_INSTALLER_PACKAGE": s.Host(),
+		"CURL_CA_BUNDLE": s.caFile,
+		"WGETRC":         s.wgetrc,
+	}
+}
+
+// Requests returns the requests received so far, in order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, UserAgent: r.UserAgent()})
+	s.mu.Unlock()
+
+	body := s.response.Script
+	w.Header().Set("Content-Type", "text/x-shellscript")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(s.response.Status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if s.response.TruncateAt <= 0 || s.response.TruncateAt >= len(body) {
+		_, _ = w.Write([]byte(body))
+		return
+	}
+	_, _ = w.Write([]byte(body[:s.response.TruncateAt]))
+	// closing the connection before Content-Length bytes were sent makes the download fail
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	panic(http.ErrAbortHandler)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
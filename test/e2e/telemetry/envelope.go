@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	RequestTypeTraces          = "traces"
+	RequestTypeLogs            = "logs"
+	RequestTypeOnboardingEvent = "apm-onboarding-event"
+
+	// ServiceName is the service of the spans and the application reported by the install script
+	ServiceName = "datadog-linux-install-script"
+)
+
+// Envelope is an apmtelemetry request. report_installer_telemetry sends v2 "traces" and "logs" requests,
+// report_telemetry a v1 "apm-onboarding-event".
+type Envelope struct {
+	APIVersion  string       `json:"api_version"`
+	RequestType string       `json:"request_type"`
+	TracerTime  int64        `json:"tracer_time"`
+	RuntimeID   string       `json:"runtime_id"`
+	SeqID       int          `json:"seq_id"`
+	Origin      string       `json:"origin"`
+	Host        *Host        `json:"host,omitempty"`
+	Application *Application `json:"application,omitempty"`
+	Payload     Payload      `json:"payload"`
+}
+
+// Host describes the machine the script ran on
+type Host struct {
+	Hostname      string `json:"hostname"`
+	OS            string `json:"os"`
+	Distribution  string `json:"distribution"`
+	Architecture  string `json:"architecture"`
+	KernelVersion string `json:"kernel_version"`
+	KernelName    string `json:"kernel_name"`
+	KernelRelease string `json:"kernel_release"`
+}
+
+// Application describes the install script
+type Application struct {
+	ServiceName     string `json:"service_name"`
+	ServiceVersion  string `json:"service_version"`
+	LanguageName    string `json:"language_name"`
+	LanguageVersion string `json:"language_version"`
+	TracerVersion   string `json:"tracer_version"`
+}
+
+// Payload holds the content of the request, depending on its type
+type Payload struct {
+	Traces    [][]Span       `json:"traces,omitempty"`
+	Logs      []Log          `json:"logs,omitempty"`
+	EventName string         `json:"event_name,omitempty"`
+	Tags      map[string]any `json:"tags,omitempty"`
+	Error     *EventError    `json:"error,omitempty"`
+}
+
+// EventError is the error reported by an agent.installation.error onboarding event
+type EventError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Span is a span of the install script trace
+type Span struct {
+	Service  string             `json:"service"`
+	Name     string             `json:"name"`
+	Resource string             `json:"resource"`
+	TraceID  uint64             `json:"trace_id"`
+	SpanID   uint64             `json:"span_id"`
+	ParentID uint64             `json:"parent_id"`
+	Start    int64              `json:"start"`
+	Duration int64              `json:"duration"`
+	Error    int                `json:"error"`
+	Meta     map[string]any     `json:"meta"`
+	Metrics  map[string]float64 `json:"metrics"`
+}
+
+// Log is a log entry, the script sends its whole output as a single entry
+type Log struct {
+	Message string `json:"message"`
+	Level   string `json:"level"`
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+}
+
+// ParseEnvelope decodes a request body or the content of /tmp/datadog-installer-trace.json and
+// /tmp/datadog-installer-log.json
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	// json_escape leaves the ESC of the ANSI color codes of the output raw, which JSON doesn't allow in strings
+	data = bytes.ReplaceAll(data, []byte("\x1b"), []byte(`\u001b`))
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Numbers in meta, such as exit_code, are kept as written by the script
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	envelope := &Envelope{}
+	if err := decoder.Decode(envelope); err != nil {
+		return nil, fmt.Errorf("invalid telemetry payload: %w", err)
+	}
+	return envelope, nil
+}
+
+// Spans returns the spans of a traces request
+func (e *Envelope) Spans() []Span {
+	var spans []Span
+	for _, trace := range e.Payload.Traces {
+		spans = append(spans, trace...)
+	}
+	return spans
+}
+
+// MetaString returns a meta value as a string, numbers are formatted as sent
+func (s Span) MetaString(key string) (string, bool) {
+	value, ok := s.Meta[key]
+	if !ok {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ReportFailurePath is the path of agent_stats/report_failure on the Datadog API, where `report` posts the failure
+// reports users accept to send. TESTING_REPORT_URL overrides both the telemetry and the failure report URLs, so the
+// intake accepts them on IntakePath too.
+const ReportFailurePath = "/agent_stats/report_failure"
+
+// The prompts of on_error, shown when the install fails in a terminal
+const (
+	ReportPrompt = "Do you want to send a failure report to Datadog (including ddagent-install.log)? (y/[n]) "
+	EmailPrompt  = "Enter an email address so we can follow up: "
+)
+
+// FormContentType is the content type of the failure reports, curl --data-urlencode posts a form
+const FormContentType = "application/x-www-form-urlencoded"
+
+// FailureReport is the form posted by `report` when a user answers yes to "Do you want to send a failure report"
+type FailureReport struct {
+	OS      string
+	Version string
+	// Log is the content of ddagent-install.log
+	Log     string
+	Email   string
+	APIKey  string
+	Variant string
+}
+
+// ParseFailureReport decodes the form posted by `report`, it rejects missing, repeated and unknown fields
+func ParseFailureReport(body []byte) (*FailureReport, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid failure report: %w", err)
+	}
+	report := &FailureReport{}
+	fields := map[string]*string{
+		"os":      &report.OS,
+		"version": &report.Version,
+		"log":     &report.Log,
+		"email":   &report.Email,
+		"apikey":  &report.APIKey,
+		"variant": &report.Variant,
+	}
+	var unknown []string
+	for name := range form {
+		if _, ok := fields[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("invalid failure report: unknown fields %v", unknown)
+	}
+	for name, field := range fields {
+		values := form[name]
+		if len(values) != 1 {
+			return nil, fmt.Errorf("invalid failure report: expected a single %s field, got %d", name, len(values))
+		}
+		*field = values[0]
+	}
+	return report, nil
+}
+
+// AssertFailureReport decodes the form of a failure report and checks that it was sent with apiKey and email, the
+// answer to EmailPrompt. It returns the report for the checks of the other fields.
+func AssertFailureReport(t *testing.T, body []byte, apiKey, email string) *FailureReport {
+	t.Helper()
+	report, err := ParseFailureReport(body)
+	require.NoError(t, err, string(body))
+	assert.Equal(t, apiKey, report.APIKey)
+	assert.Equal(t, email, report.Email)
+	return report
+}
@@ -0,0 +1,186 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/classifier"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fallbackMsg = "If you are still having problems, please send an email to support@datadoghq.com"
+	reportSent  = "A notification has been sent to Datadog with the contents of ddagent-install.log"
+	readFailed  = "Timed out or input EOF reached, assuming 'No'"
+	// readTimeout is the timeout of the prompt of on_error
+	readTimeout = 60 * time.Second
+	email       = "jane.doe@example.com"
+)
+
+// failingInstall makes the install of the Agent packages fail, which triggers on_error
+var failingInstall = hermetic.WithRule(hermetic.Rule{Command: "apt-get", Args: `(^| )install( |$)`, Exit: 100})
+
+// exchange is a prompt of the script and the answer typed once it shows
+type exchange struct {
+	prompt string
+	answer string
+}
+
+func TestFailureReport(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		exchanges []exchange
+		// interact replaces the exchanges for the answers that aren't lines
+		interact func(t *testing.T, terminal *hermetic.Terminal)
+		// reported is set when the user accepts to send a failure report with a valid email
+		reported bool
+		output   []string
+		slow     bool
+		// aborted is set when on_error stops after report_telemetry: when it skips telemetry, it returns the status
+		// of the failed command, which aborts on_error under set -e before the fallback message and the prompt
+		aborted bool
+	}{
+		{
+			name:      "yes",
+			exchanges: []exchange{{ReportPrompt, "y"}, {EmailPrompt, email}},
+			reported:  true,
+			output:    []string{reportSent, fallbackMsg},
+		},
+		{
+			name:      "yes with invalid emails",
+			exchanges: []exchange{{ReportPrompt, "yes"}, {EmailPrompt, "jane"}, {EmailPrompt, "jane@"}, {EmailPrompt, "@example.com"}},
+			output:    []string{"(1/3) Email address invalid: jane", "(3/3) Email address invalid: @example.com", fallbackMsg},
+		},
+		{
+			name:      "no",
+			exchanges: []exchange{{ReportPrompt, "n"}},
+			output:    []string{fallbackMsg},
+		},
+		{
+			name:      "default answer",
+			exchanges: []exchange{{ReportPrompt, ""}},
+			output:    []string{fallbackMsg},
+		},
+		{
+			name:      "invalid answer",
+			exchanges: []exchange{{ReportPrompt, "maybe"}, {ReportPrompt, "N"}},
+			output:    []string{"Please answer yes or no.", fallbackMsg},
+		},
+		{
+			name: "end of input",
+			interact: func(t *testing.T, terminal *hermetic.Terminal) {
+				require.NoError(t, terminal.Expect(ReportPrompt, waitTimeout), terminal.Output())
+				require.NoError(t, terminal.SendEOF())
+			},
+			output: []string{readFailed, fallbackMsg},
+		},
+		{
+			name: "timeout",
+			interact: func(t *testing.T, terminal *hermetic.Terminal) {
+				require.NoError(t, terminal.Expect(ReportPrompt, waitTimeout), terminal.Output())
+				require.NoError(t, terminal.Expect(readFailed, readTimeout+waitTimeout), terminal.Output())
+			},
+			output: []string{readFailed, fallbackMsg},
+			slow:   true,
+		},
+		{
+			name:    "telemetry disabled",
+			env:     map[string]string{"DD_INSTRUMENTATION_TELEMETRY_ENABLED": "false"},
+			aborted: true,
+		},
+		{
+			name:    "no prompt on ddog-gov.com",
+			env:     map[string]string{"DD_SITE": "ddog-gov.com"},
+			aborted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.slow && testing.Short() {
+				t.Skipf("waits for the %s timeout of the prompt", readTimeout)
+			}
+			intake := NewIntake()
+			t.Cleanup(intake.Close)
+			h := hermetic.New(t, failingInstall, hermetic.WithPassthrough(`127\.0\.0\.1`))
+			env := map[string]string{
+				"DD_API_KEY":         apiKey,
+				"TESTING_REPORT_URL": intake.URL(),
+			}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			result := h.RunInTerminal(env, func(terminal *hermetic.Terminal) {
+				if tt.interact != nil {
+					tt.interact(t, terminal)
+					return
+				}
+				for _, exchange := range tt.exchanges {
+					require.NoError(t, terminal.Expect(exchange.prompt, waitTimeout), terminal.Output())
+					require.NoError(t, terminal.SendLine(exchange.answer))
+				}
+			})
+
+			assert.NotEqual(t, 0, result.ExitCode, result.Output)
+			assert.Contains(t, result.Output, "It looks like you hit an issue when trying to install the Datadog Agent.")
+			for _, output := range tt.output {
+				assert.Contains(t, result.Output, output)
+			}
+			assert.Equal(t, classifier.ClassPackageInstall, classifier.Classify(result.Output).Class)
+			if len(tt.exchanges) == 0 && tt.interact == nil {
+				assert.NotContains(t, result.Output, ReportPrompt)
+			}
+			if tt.aborted {
+				assert.NotContains(t, result.Output, fallbackMsg)
+			}
+
+			reports := intake.FailureReports()
+			if !tt.reported {
+				assert.Empty(t, reports)
+				assert.NotContains(t, result.Output, reportSent)
+				return
+			}
+			require.Len(t, reports, 1)
+			for _, request := range intake.Requests() {
+				if request.FailureReport == nil {
+					continue
+				}
+				assert.Equal(t, FormContentType, request.ContentType)
+				report := AssertFailureReport(t, request.Body, apiKey, email)
+				assert.Equal(t, "Debian", report.OS)
+				assert.Equal(t, "7", report.Version)
+				assert.Equal(t, "install_script_agent7", report.Variant)
+				assert.Contains(t, report.Log, "Installing package(s): datadog-agent datadog-signing-keys")
+				assert.Equal(t, classifier.ClassPackageInstall, classifier.Classify(report.Log).Class)
+			}
+		})
+	}
+}
+
+func TestFailureReportWithoutTerminal(t *testing.T) {
+	intake, result := runWithIntake(t, nil, failingInstall)
+
+	assert.NotEqual(t, 0, result.ExitCode, result.Output)
+	assert.Contains(t, result.Output, fallbackMsg)
+	assert.NotContains(t, result.Output, ReportPrompt)
+	assert.Empty(t, intake.FailureReports())
+	assert.Equal(t, classifier.ClassPackageInstall, classifier.Classify(result.Output).Class)
+}
+
+func TestParseFailureReport(t *testing.T) {
+	report, err := ParseFailureReport([]byte("os=Debian&version=7&log=a%0Ab&email=jane%40example.com&apikey=key&variant=install_script_agent7"))
+	require.NoError(t, err)
+	assert.Equal(t, &FailureReport{OS: "Debian", Version: "7", Log: "a\nb", Email: "jane@example.com", APIKey: "key", Variant: "install_script_agent7"}, report)
+
+	_, err = ParseFailureReport([]byte("os=Debian&version=7&log=&email=&apikey=key&variant=v&flavor=datadog-agent"))
+	assert.ErrorContains(t, err, "unknown fields [flavor]")
+	_, err = ParseFailureReport([]byte("os=Debian&version=7&log=&email=&apikey=key"))
+	assert.ErrorContains(t, err, "expected a single variant field, got 0")
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// IntakePath is the path the script posts telemetry to
+const IntakePath = "/api/v2/apmtelemetry"
+
+// Request is a request received by the intake
+type Request struct {
+	APIKey      string
+	ContentType string
+	Body        []byte
+	// Envelope is nil when the body could not be decoded, see Err
+	Envelope *Envelope
+	// FailureReport is set instead of Envelope for the forms posted by `report`
+	FailureReport *FailureReport
+	Err           error
+}
+
+// Intake is a local instrumentation telemetry intake, which also stands in for agent_stats/report_failure. Point the
+// script to it with TESTING_REPORT_URL=intake.URL().
+type Intake struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	requests []Request
+	received chan struct{}
+}
+
+// NewIntake starts an intake listening on 127.0.0.1
+func NewIntake() *Intake {
+	intake := &Intake{received: make(chan struct{}, 1)}
+	intake.server = httptest.NewServer(http.HandlerFunc(intake.handle))
+	return intake
+}
+
+// URL returns the telemetry URL to give to the script
+func (i *Intake) URL() string {
+	return i.server.URL + IntakePath
+}
+
+// Close stops the intake
+func (i *Intake) Close() {
+	i.server.Close()
+}
+
+func (i *Intake) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || (r.URL.Path != IntakePath && r.URL.Path != ReportFailurePath) {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	request := Request{
+		APIKey:      r.Header.Get("DD-Api-Key"),
+		ContentType: r.Header.Get("Content-Type"),
+		Body:        body,
+	}
+	if request.ContentType == FormContentType {
+		request.FailureReport, request.Err = ParseFailureReport(body)
+	} else {
+		request.Envelope, request.Err = ParseEnvelope(body)
+	}
+
+	i.mu.Lock()
+	i.requests = append(i.requests, request)
+	i.mu.Unlock()
+	select {
+	case i.received <- struct{}{}:
+	default:
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Requests returns the requests received so far
+func (i *Intake) Requests() []Request {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]Request{}, i.requests...)
+}
+
+// Envelopes returns the decoded requests of the given type
+func (i *Intake) Envelopes(requestType string) []*Envelope {
+	var envelopes []*Envelope
+	for _, request := range i.Requests() {
+		if request.Envelope != nil && request.Envelope.RequestType == requestType {
+			envelopes = append(envelopes, request.Envelope)
+		}
+	}
+	return envelopes
+}
+
+// FailureReports returns the decoded failure reports
+func (i *Intake) FailureReports() []*FailureReport {
+	var reports []*FailureReport
+	for _, request := range i.Requests() {
+		if request.FailureReport != nil {
+			reports = append(reports, request.FailureReport)
+		}
+	}
+	return reports
+}
+
+// WaitFor waits until a request of the given type is received and returns the first one
+func (i *Intake) WaitFor(requestType string, timeout time.Duration) (*Envelope, error) {
+	deadline := time.After(timeout)
+	for {
+		if envelopes := i.Envelopes(requestType); len(envelopes) > 0 {
+			return envelopes[0], nil
+		}
+		select {
+		case <-i.received:
+		case <-deadline:
+			return nil, fmt.Errorf("no %s request received after %s", requestType, timeout)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/classifier"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	apiKey      = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	waitTimeout = 10 * time.Second
+)
+
+// runWithIntake runs the script with the API key and the intake as report URL, env adds to or overrides them
+func runWithIntake(t *testing.T, env map[string]string, options ...hermetic.Option) (*Intake, *hermetic.Result) {
+	intake := NewIntake()
+	t.Cleanup(intake.Close)
+	h := hermetic.New(t, append(options, hermetic.WithPassthrough(`127\.0\.0\.1`))...)
+	runEnv := map[string]string{
+		"DD_API_KEY":         apiKey,
+		"TESTING_REPORT_URL": intake.URL(),
+	}
+	for key, value := range env {
+		runEnv[key] = value
+	}
+	return intake, h.Run(runEnv)
+}
+
+func TestInstallTrace(t *testing.T) {
+	intake, result := runWithIntake(t, nil)
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	trace, err := intake.WaitFor(RequestTypeTraces, waitTimeout)
+	require.NoError(t, err)
+	AssertTrace(t, trace, 0, Stages...)
+	AssertStageMeta(t, trace, "initialization", map[string]string{"api_key_provided": "true", "site": "datadoghq.com"})
+	AssertStageMeta(t, trace, "configuration_validation", map[string]string{"agent_major_version": "7", "package_manager": "apt"})
+	AssertStageMeta(t, trace, "package_sources_setup", map[string]string{"apt_version": "2.6.1"})
+	AssertStageMeta(t, trace, "service_management", map[string]string{"no_start_mode": "false"})
+	assert.Equal(t, "Ubuntu", trace.Host.Distribution)
+	assert.Equal(t, "x86_64", trace.Host.Architecture)
+	assert.Equal(t, ServiceName, trace.Application.ServiceName)
+
+	logs, err := intake.WaitFor(RequestTypeLogs, waitTimeout)
+	require.NoError(t, err)
+	require.Len(t, logs.Payload.Logs, 1)
+	assert.Contains(t, logs.Payload.Logs[0].Message, "Your Datadog Agent is running and functioning properly.")
+	assert.Equal(t, classifier.ClassNone, classifier.Classify(logs.Payload.Logs[0].Message).Class)
+	assert.Equal(t, trace.RuntimeID, logs.Payload.Logs[0].TraceID)
+
+	event, err := intake.WaitFor(RequestTypeOnboardingEvent, waitTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, "agent.installation.success", event.Payload.EventName)
+
+	for _, request := range intake.Requests() {
+		require.NoError(t, request.Err, string(request.Body))
+		assert.Equal(t, apiKey, request.APIKey)
+		assert.Equal(t, "application/json", request.ContentType)
+	}
+}
+
+func TestRedHatTraceReportsDNFMode(t *testing.T) {
+	intake, result := runWithIntake(t, nil, hermetic.WithOS(hermetic.RedHat("9.4")))
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	trace, err := intake.WaitFor(RequestTypeTraces, waitTimeout)
+	require.NoError(t, err)
+	AssertTrace(t, trace, 0, Stages...)
+	AssertStageMeta(t, trace, "package_sources_setup", map[string]string{"dnf_mode": "true"})
+	AssertStageMeta(t, trace, "configuration_validation", map[string]string{"package_manager": "yum"})
+}
+
+func TestSUSETraceReportsSUSE11Mode(t *testing.T) {
+	sles11 := hermetic.SLES("11.4")
+	sles11.Files["/etc/SuSE-release"] = "SUSE Linux Enterprise Server 11 (x86_64)\nVERSION = 11\nPATCHLEVEL = 4\n"
+	intake, result := runWithIntake(t, nil, hermetic.WithOS(sles11), hermetic.WithAvailableVersions("7.32.4-1"))
+	require.Equal(t, 0, result.ExitCode, result.Output)
+
+	trace, err := intake.WaitFor(RequestTypeTraces, waitTimeout)
+	require.NoError(t, err)
+	AssertTrace(t, trace, 0, Stages...)
+	AssertStageMeta(t, trace, "package_sources_setup", map[string]string{"suse11_mode": "true"})
+	AssertStageMeta(t, trace, "service_management", map[string]string{"suse11_mode": "true"})
+}
+
+func TestFailedInstallTrace(t *testing.T) {
+	intake, result := runWithIntake(t, nil, hermetic.WithArch("armv7l"))
+	require.Equal(t, 1, result.ExitCode, result.Output)
+
+	trace, err := intake.WaitFor(RequestTypeTraces, waitTimeout)
+	require.NoError(t, err)
+	AssertTrace(t, trace, 1, "initialization", "configuration_validation")
+	stage, ok := trace.Stage("configuration_validation")
+	require.True(t, ok)
+	assert.Equal(t, 1, stage.Error)
+	message, _ := stage.MetaString("error")
+	assert.True(t, strings.HasPrefix(message, "The full Datadog Agent isn't available for your architecture (armv7l)."), message)
+
+	event, err := intake.WaitFor(RequestTypeOnboardingEvent, waitTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, "agent.installation.error", event.Payload.EventName)
+	require.NotNil(t, event.Payload.Error)
+	assert.Equal(t, 5, event.Payload.Error.Code)
+}
+
+func TestParseEnvelopeRejectsUnknownFields(t *testing.T) {
+	_, err := ParseEnvelope([]byte(`{"api_version": "v2", "request_type": "traces", "unexpected": true}`))
+	assert.Error(t, err)
+}
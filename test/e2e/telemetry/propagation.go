@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Variables the script exports for the spans of its subprocesses, such as the installer or the package hooks
+const (
+	EnvTraceID  = "DATADOG_TRACE_ID"
+	EnvParentID = "DATADOG_PARENT_ID"
+)
+
+// ContextFromEnv returns the trace and parent span ids a subprocess inherited from the script
+func ContextFromEnv(env map[string]string) (traceID uint64, parentID uint64, err error) {
+	if traceID, err = parseID(env, EnvTraceID); err != nil {
+		return 0, 0, err
+	}
+	if parentID, err = parseID(env, EnvParentID); err != nil {
+		return 0, 0, err
+	}
+	return traceID, parentID, nil
+}
+
+func parseID(env map[string]string, name string) (uint64, error) {
+	value, ok := env[name]
+	if !ok {
+		return 0, fmt.Errorf("%s is not set", name)
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return id, nil
+}
+
+// Span returns the span with the given id
+func (e *Envelope) Span(id uint64) (Span, bool) {
+	for _, span := range e.Spans() {
+		if span.SpanID == id {
+			return span, true
+		}
+	}
+	return Span{}, false
+}
+
+// AssertParentStage checks that a subprocess of the script ran within stage: it inherited the trace id of the script,
+// and the span id of the stage as parent. process names the subprocess in the failures.
+func AssertParentStage(t *testing.T, envelope *Envelope, env map[string]string, stage string, process string) {
+	t.Helper()
+	root, err := envelope.RootSpan()
+	require.NoError(t, err)
+	span, ok := envelope.Stage(stage)
+	require.True(t, ok, "stage %s not found", stage)
+
+	traceID, parentID, err := ContextFromEnv(env)
+	if !assert.NoError(t, err, process) {
+		return
+	}
+	assert.Equal(t, root.TraceID, traceID, "%s trace id", process)
+	if parentID != span.SpanID {
+		parent := "no span of the trace"
+		if actual, ok := envelope.Span(parentID); ok {
+			parent = "the span of " + actual.Name
+		}
+		assert.Fail(t, fmt.Sprintf("%s parent id %d is %s, not the span of %s %d", process, parentID, parent, stage, span.SpanID))
+	}
+}
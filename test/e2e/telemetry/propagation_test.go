@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"os"
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/mockinstaller"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/ssi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// installSSI stands for install-ssi.sh, which delegates the injector to datadog-installer
+const installSSI = `datadog-installer is-installed datadog-apm-inject ||
+  datadog-installer install oci://install.datadoghq.com/apm-inject-package:latest
+`
+
+func TestTraceContextPropagation(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []hermetic.Option
+		command string
+		// refresh is the command line refreshing the Datadog repository
+		refresh []string
+	}{
+		{name: "ubuntu", command: "apt-get", refresh: []string{"apt-get", "update"}},
+		{name: "redhat", options: []hermetic.Option{hermetic.WithOS(hermetic.RedHat("9.4"))}, command: "yum", refresh: []string{"yum", "clean", "metadata"}},
+		{name: "sles", options: []hermetic.Option{hermetic.WithOS(hermetic.SLES("15"))}, command: "zypper", refresh: []string{"zypper", "refresh"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installer := mockinstaller.Installer{Dir: t.TempDir()}
+			server := ssi.New(t, ssi.Response{Script: installSSI})
+			env := server.Env()
+			env["DD_APM_INSTRUMENTATION_ENABLED"] = "host"
+			intake, result := runWithIntake(t, env, append(tt.options,
+				hermetic.WithExecutable("/usr/bin/datadog-installer", installer.Script()),
+			)...)
+			require.Equal(t, 0, result.ExitCode, result.Output)
+			trace, err := intake.WaitFor(RequestTypeTraces, waitTimeout)
+			require.NoError(t, err)
+			AssertTrace(t, trace, 0, Stages...)
+
+			// each subprocess is a child of the stage running at the time, or of the root span outside of the stages
+			for _, call := range result.Calls {
+				if _, ok := call.Env[EnvParentID]; !ok {
+					continue
+				}
+				_, parentID, err := ContextFromEnv(call.Env)
+				require.NoError(t, err, call.String())
+				_, ok := trace.Span(parentID)
+				assert.True(t, ok, "%s parent id %d is not a span of the trace", call, parentID)
+			}
+
+			refresh, ok := result.FindCall(tt.refresh[0], tt.refresh[1:]...)
+			require.True(t, ok, result.Transcript())
+			AssertParentStage(t, trace, refresh.Env, "package_sources_setup", refresh.String())
+			install, ok := result.FindCall(tt.command, "install", "datadog-agent")
+			require.True(t, ok, result.Transcript())
+			AssertParentStage(t, trace, install.Env, "install_agent_packages", install.String())
+			hook, ok := result.HookOf("datadog-agent")
+			require.True(t, ok)
+			AssertParentStage(t, trace, hook.Env, "install_agent_packages", "datadog-agent maintainer scripts")
+
+			download, ok := result.FindCall("curl", "https://"+server.Host()+ssi.ScriptPath)
+			require.True(t, ok, result.Transcript())
+			AssertParentStage(t, trace, download.Env, "configuration_setup", download.String())
+			log, err := os.ReadFile(installer.LogPath())
+			require.NoError(t, err)
+			calls, err := mockinstaller.ParseLog(log)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"datadog-apm-inject"}, mockinstaller.Delegated(calls))
+			for _, call := range calls {
+				AssertParentStage(t, trace, call.Env, "configuration_setup", "datadog-installer "+call.Subcommand())
+			}
+		})
+	}
+}
+
+func TestContextFromEnv(t *testing.T) {
+	traceID, parentID, err := ContextFromEnv(map[string]string{EnvTraceID: "17", EnvParentID: "1700000000001"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(17), traceID)
+	assert.Equal(t, uint64(1700000000001), parentID)
+
+	_, _, err = ContextFromEnv(map[string]string{EnvTraceID: "17"})
+	assert.EqualError(t, err, "DATADOG_PARENT_ID is not set")
+	_, _, err = ContextFromEnv(map[string]string{EnvTraceID: "", EnvParentID: "1"})
+	assert.ErrorContains(t, err, "DATADOG_TRACE_ID: ")
+}
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Stages of the install script, in execution order
+var Stages = []string{
+	"initialization",
+	"configuration_validation",
+	"package_sources_setup",
+	"install_agent_packages",
+	"agent5_upgrade",
+	"configuration_setup",
+	"service_management",
+}
+
+// RootSpan returns the install_script span, the one without parent
+func (e *Envelope) RootSpan() (Span, error) {
+	var roots []Span
+	for _, span := range e.Spans() {
+		if span.ParentID == 0 {
+			roots = append(roots, span)
+		}
+	}
+	if len(roots) != 1 {
+		return Span{}, fmt.Errorf("expected a single root span, got %d", len(roots))
+	}
+	return roots[0], nil
+}
+
+// StageSpans returns the spans created by start_stage and end_stage, in the order the stages ended
+func (e *Envelope) StageSpans() []Span {
+	var stages []Span
+	for _, span := range e.Spans() {
+		if _, ok := span.Meta["stage"]; ok {
+			stages = append(stages, span)
+		}
+	}
+	return stages
+}
+
+// Stage returns the span of the named stage
+func (e *Envelope) Stage(name string) (Span, bool) {
+	for _, span := range e.StageSpans() {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return Span{}, false
+}
+
+// AssertTrace checks the trace sent by report_installer_telemetry: a root span carrying the exit code of the
+// script, and one child span per stage, in the given order
+func AssertTrace(t *testing.T, envelope *Envelope, exitCode int, stages ...string) {
+	t.Helper()
+	require.Equal(t, "v2", envelope.APIVersion)
+	require.Equal(t, RequestTypeTraces, envelope.RequestType)
+	require.Len(t, envelope.Payload.Traces, 1, "the script sends a single trace")
+
+	root, err := envelope.RootSpan()
+	require.NoError(t, err)
+	assert.Equal(t, ServiceName, root.Service)
+	assert.Equal(t, "install_script", root.Name)
+	assert.Equal(t, root.TraceID, root.SpanID, "the root span id is the trace id")
+	assert.Equal(t, strconv.FormatUint(root.TraceID, 10), envelope.RuntimeID)
+	assert.Equal(t, exitCode, root.Error)
+	code, _ := root.MetaString("exit_code")
+	assert.Equal(t, strconv.Itoa(exitCode), code, "exit_code meta")
+	assert.Equal(t, float64(1), root.Metrics["_trace_root"])
+
+	var names []string
+	for _, span := range envelope.StageSpans() {
+		names = append(names, span.Name)
+		assert.Equal(t, ServiceName, span.Service, "stage %s", span.Name)
+		assert.Equal(t, span.Name, span.Resource, "stage %s", span.Name)
+		assert.Equal(t, root.TraceID, span.TraceID, "stage %s trace_id", span.Name)
+		assert.Equal(t, root.SpanID, span.ParentID, "stage %s parent_id", span.Name)
+		assert.NotEqual(t, root.SpanID, span.SpanID, "stage %s span_id", span.Name)
+		stage, _ := span.MetaString("stage")
+		assert.Equal(t, span.Name, stage, "stage %s meta", span.Name)
+		assert.GreaterOrEqual(t, span.Start, root.Start, "stage %s starts before the script", span.Name)
+		assert.LessOrEqual(t, span.Start+span.Duration, root.Start+root.Duration, "stage %s ends after the script", span.Name)
+	}
+	assert.Equal(t, stages, names, "stage spans")
+}
+
+// AssertStageMeta checks metadata reported by end_stage, such as dnf_mode, apt_version or suse11_mode
+func AssertStageMeta(t *testing.T, envelope *Envelope, stage string, expected map[string]string) {
+	t.Helper()
+	span, ok := envelope.Stage(stage)
+	require.True(t, ok, "stage %s not found", stage)
+	for key, value := range expected {
+		actual, ok := span.MetaString(key)
+		if assert.True(t, ok, "stage %s has no %s meta", stage, key) {
+			assert.Equal(t, value, actual, "stage %s %s meta", stage, key)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/agent-linux-install-script/test/e2e/classifier"
+	"github.com/DataDog/agent-linux-install-script/test/e2e/hermetic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Error codes of the install script
+const (
+	unsupportedPlatformCode       = 5
+	invalidParametersCode         = 6
+	unableToInstallDependencyCode = 7
+)
+
+// validationCase is an early exit of the script, on invalid parameters, an unsupported platform or a package the
+// package manager did not install
+type validationCase struct {
+	name    string
+	env     map[string]string
+	options []hermetic.Option
+	// message is the start of ERROR_MESSAGE, as printed by the script
+	message string
+	// stage is the stage the script exits in
+	stage     string
+	exitCode  int
+	errorCode int
+	// class is how the classifier sorts the log
+	class classifier.Class
+	// installs is set when the script runs the package manager before exiting, the dependency checks follow it
+	installs bool
+}
+
+var validationCases = []validationCase{
+	{
+		name:      "unknown flavor",
+		env:       map[string]string{"DD_AGENT_FLAVOR": "datadog-unknown-agent"},
+		message:   `Unknown DD_AGENT_FLAVOR "datadog-unknown-agent"`,
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name:      "major version",
+		env:       map[string]string{"DD_AGENT_MAJOR_VERSION": "8"},
+		message:   "DD_AGENT_MAJOR_VERSION must be either 6 or 7. Current value: 8",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name:      "dist channel",
+		env:       map[string]string{"DD_AGENT_DIST_CHANNEL": "nightly"},
+		message:   "DD_AGENT_DIST_CHANNEL must be either 'stable' or 'beta'. Current value: nightly",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name:      "dist channel on custom repo",
+		env:       map[string]string{"DD_REPO_URL": "datad0g.com", "DD_AGENT_DIST_CHANNEL": "unstable"},
+		message:   "DD_AGENT_DIST_CHANNEL must be either 'stable', 'beta' or 'nightly' on custom repos. Current value: unstable",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name:      "DDOT dist channel",
+		env:       map[string]string{"DD_OTELCOLLECTOR_ENABLED": "true", "DD_DDOT_DIST_CHANNEL": "stable"},
+		message:   "DD_DDOT_DIST_CHANNEL must be 'beta' while only available in preview. Current value: stable",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name:      "DDOT dist channel on custom repo",
+		env:       map[string]string{"DD_OTELCOLLECTOR_ENABLED": "true", "DD_REPO_URL": "datad0g.com", "DD_DDOT_DIST_CHANNEL": "unstable"},
+		message:   "DD_DDOT_DIST_CHANNEL must be either 'stable', 'beta' or 'nightly' on custom repos. Current value: unstable",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name:      "full Agent on armv7l",
+		options:   []hermetic.Option{hermetic.WithArch("armv7l")},
+		message:   "The full Datadog Agent isn't available for your architecture (armv7l).",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: unsupportedPlatformCode,
+		class:     classifier.ClassUnsupportedPlatform,
+	},
+	{
+		name:      "APM with Agent 6",
+		env:       map[string]string{"DD_APM_INSTRUMENTATION_ENABLED": "host", "DD_AGENT_MAJOR_VERSION": "6"},
+		message:   "APM library injection is not supported with Agent version 6",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name: "macOS",
+		options: []hermetic.Option{
+			hermetic.WithOS(hermetic.OS{}),
+			hermetic.WithRule(hermetic.Rule{Command: "uname", Args: "^-s$", Stdout: "Darwin"}),
+		},
+		message:   "This script does not support installing on the Mac.",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: unsupportedPlatformCode,
+		class:     classifier.ClassUnsupportedPlatform,
+	},
+	{
+		name:      "dogstatsd on aarch64 Red Hat",
+		env:       map[string]string{"DD_AGENT_FLAVOR": "datadog-dogstatsd"},
+		options:   []hermetic.Option{hermetic.WithOS(hermetic.RedHat("9.4")), hermetic.WithArch("aarch64")},
+		message:   "The Datadog Dogstatsd isn't available for your architecture.",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: unsupportedPlatformCode,
+		class:     classifier.ClassUnsupportedPlatform,
+	},
+	{
+		name:      "dogstatsd below 7.35 on aarch64 Debian",
+		env:       map[string]string{"DD_AGENT_FLAVOR": "datadog-dogstatsd", "DD_AGENT_MINOR_VERSION": "34"},
+		options:   []hermetic.Option{hermetic.WithOS(hermetic.Debian("12")), hermetic.WithArch("aarch64")},
+		message:   "The Datadog Dogstatsd is only available since version 7.35.0 for your architecture.",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: unsupportedPlatformCode,
+		class:     classifier.ClassUnsupportedPlatform,
+	},
+	{
+		name:      "FIPS mode on i686",
+		env:       map[string]string{"DD_FIPS_MODE": "true"},
+		options:   []hermetic.Option{hermetic.WithArch("i686")},
+		message:   "FIPS mode isn't available for your architecture",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: unsupportedPlatformCode,
+		class:     classifier.ClassUnsupportedPlatform,
+	},
+	{
+		name:      "FIPS mode below 7.41",
+		env:       map[string]string{"DD_FIPS_MODE": "true", "DD_AGENT_MINOR_VERSION": "40.1"},
+		message:   "FIPS mode is only available since version 7.41.0 and requested minor version is 40.1",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name:      "FIPS Agent with FIPS mode",
+		env:       map[string]string{"DD_AGENT_FLAVOR": "datadog-fips-agent", "DD_FIPS_MODE": "true"},
+		message:   "The datadog-fips-agent cannot be used with the fips-proxy installed. Please install without DD_FIPS_MODE set",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name:      "FIPS Agent below 7.64",
+		env:       map[string]string{"DD_AGENT_FLAVOR": "datadog-fips-agent", "DD_AGENT_MINOR_VERSION": "63"},
+		message:   "The datadog-fips-agent is only available since version 7.64.x and requested minor version is 63",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name:      "DDOT below 7.69",
+		env:       map[string]string{"DD_OTELCOLLECTOR_ENABLED": "true", "DD_AGENT_MINOR_VERSION": "68.2"},
+		message:   "The datadog-agent-ddot is only available since version 7.69.3 and requested minor version is 68.2",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	{
+		name:      "DDOT with Agent 6",
+		env:       map[string]string{"DD_OTELCOLLECTOR_ENABLED": "true", "DD_AGENT_MAJOR_VERSION": "6"},
+		message:   "The datadog-agent-ddot is only available since version 7.69.3",
+		stage:     "configuration_validation",
+		exitCode:  1,
+		errorCode: invalidParametersCode,
+		class:     classifier.ClassInvalidParameters,
+	},
+	// zypper does not fail when a repository fails to refresh, the script checks with rpm that the packages are there
+	{
+		name: "curl on SUSE",
+		options: []hermetic.Option{
+			hermetic.WithOS(hermetic.SLES("15.5")),
+			hermetic.WithRule(hermetic.Rule{Command: "rpm", Args: "^-q curl$", Exit: 1}),
+		},
+		message:   "Failed to install curl.",
+		stage:     "package_sources_setup",
+		exitCode:  1,
+		errorCode: unableToInstallDependencyCode,
+		class:     classifier.ClassPackageInstall,
+		installs:  true,
+	},
+	{
+		name: "Agent on SUSE",
+		options: []hermetic.Option{
+			hermetic.WithOS(hermetic.SLES("15.5")),
+			hermetic.WithRule(hermetic.Rule{Command: "rpm", Args: "^-q datadog-agent$", Exit: 1}),
+		},
+		message:   "Failed to install datadog-agent.",
+		stage:     "install_agent_packages",
+		exitCode:  1,
+		errorCode: unableToInstallDependencyCode,
+		class:     classifier.ClassPackageInstall,
+		installs:  true,
+	},
+	// The platform checks of the package sources setup end with a bare `exit`, the script then exits with the status
+	// of report_telemetry
+	{
+		name:      "Debian 7 above 7.35",
+		env:       map[string]string{"DD_AGENT_MINOR_VERSION": "36"},
+		options:   []hermetic.Option{hermetic.WithOS(hermetic.Debian("7"))},
+		message:   "Debian < 8 only supports Datadog Agent 7 up to 7.35.",
+		stage:     "package_sources_setup",
+		exitCode:  0,
+		errorCode: unsupportedPlatformCode,
+		class:     classifier.ClassUnsupportedPlatform,
+	},
+	{
+		name:      "CentOS 6 above 7.51",
+		env:       map[string]string{"DD_AGENT_MINOR_VERSION": "52"},
+		options:   []hermetic.Option{hermetic.WithOS(hermetic.CentOS("6.10"))},
+		message:   "CentOS < 7 only supports Datadog Agent 7 up to 7.51.",
+		stage:     "package_sources_setup",
+		exitCode:  0,
+		errorCode: unsupportedPlatformCode,
+		class:     classifier.ClassUnsupportedPlatform,
+	},
+	{
+		name:      "openSUSE 13 above 7.32",
+		env:       map[string]string{"DD_AGENT_MINOR_VERSION": "33"},
+		options:   []hermetic.Option{hermetic.WithOS(hermetic.OpenSUSE("13.2"))},
+		message:   "openSUSE < 15 only supports Datadog Agent 7 up to 7.32.",
+		stage:     "package_sources_setup",
+		exitCode:  0,
+		errorCode: unsupportedPlatformCode,
+		class:     classifier.ClassUnsupportedPlatform,
+	},
+	{
+		name:      "SUSE on i686",
+		options:   []hermetic.Option{hermetic.WithOS(hermetic.SLES("15.5")), hermetic.WithArch("i686")},
+		message:   "The Datadog Agent installer is only available for 64 bit SUSE Enterprise machines.",
+		stage:     "package_sources_setup",
+		exitCode:  0,
+		errorCode: unsupportedPlatformCode,
+		class:     classifier.ClassUnsupportedPlatform,
+	},
+	{
+		name:      "unknown distribution",
+		options:   []hermetic.Option{hermetic.WithOS(hermetic.OS{})},
+		message:   "Your OS or distribution are not supported by this install script.",
+		stage:     "package_sources_setup",
+		exitCode:  0,
+		errorCode: unsupportedPlatformCode,
+		class:     classifier.ClassUnsupportedPlatform,
+	},
+}
+
+// stagesUntil returns the stages of the script up to the given one
+func stagesUntil(stage string) []string {
+	return Stages[:slices.Index(Stages, stage)+1]
+}
+
+func TestParameterValidation(t *testing.T) {
+	for _, c := range validationCases {
+		t.Run(c.name, func(t *testing.T) {
+			intake, result := runWithIntake(t, c.env, c.options...)
+			assert.Equal(t, c.exitCode, result.ExitCode, result.Output)
+			assert.Contains(t, result.Output, c.message)
+			if !c.installs {
+				for _, command := range []string{"apt-get", "yum", "zypper"} {
+					_, ok := result.FindCall(command, "install")
+					assert.False(t, ok, "the script must not install packages\n%s", result.Transcript())
+				}
+			}
+
+			trace, err := intake.WaitFor(RequestTypeTraces, waitTimeout)
+			require.NoError(t, err)
+			AssertTrace(t, trace, c.exitCode, stagesUntil(c.stage)...)
+			stage, ok := trace.Stage(c.stage)
+			require.True(t, ok)
+			assert.Equal(t, c.exitCode, stage.Error)
+			if c.exitCode != 0 {
+				// end_stage replaces the double quotes of the message
+				AssertStageMeta(t, trace, c.stage, map[string]string{"error_code": strconv.Itoa(c.errorCode)})
+				message, _ := stage.MetaString("error")
+				expected := strings.ReplaceAll(c.message, `"`, "'")
+				assert.True(t, strings.HasPrefix(message, expected), "stage error %q", message)
+			}
+
+			event, err := intake.WaitFor(RequestTypeOnboardingEvent, waitTimeout)
+			require.NoError(t, err)
+			assert.Equal(t, "agent.installation.error", event.Payload.EventName)
+			require.NotNil(t, event.Payload.Error)
+			assert.Equal(t, c.errorCode, event.Payload.Error.Code)
+			// report_telemetry replaces the double quotes of the message
+			expected := strings.ReplaceAll(c.message, `"`, "_")
+			assert.True(t, strings.HasPrefix(event.Payload.Error.Message, expected), "event error %q", event.Payload.Error.Message)
+
+			assert.Equal(t, c.class, classifier.Classify(result.Output).Class)
+			logs, err := intake.WaitFor(RequestTypeLogs, waitTimeout)
+			require.NoError(t, err)
+			require.Len(t, logs.Payload.Logs, 1)
+			assert.Equal(t, c.class, classifier.Classify(logs.Payload.Logs[0].Message).Class)
+		})
+	}
+}
+
+// TestMissingAPIKey covers the only early exit without report_telemetry: without an API key the script can't reach
+// the intake, so it only prints the error
+func TestMissingAPIKey(t *testing.T) {
+	intake, result := runWithIntake(t, map[string]string{"DD_API_KEY": ""})
+	assert.Equal(t, 1, result.ExitCode, result.Output)
+	assert.Contains(t, result.Output, "API key not available in DD_API_KEY environment variable.")
+	_, ok := result.FindCall("apt-get", "install")
+	assert.False(t, ok, "the script must not install packages\n%s", result.Transcript())
+	assert.Equal(t, classifier.ClassMissingAPIKey, classifier.Classify(result.Output).Class)
+	assert.Empty(t, intake.Requests())
+}
@@ -10,7 +10,6 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 )
 
 type upgrade5TestSuite struct {
@@ -27,7 +26,7 @@ func TestUpgrade5Suite(t *testing.T) {
 		testSuite := &upgrade5TestSuite{}
 		e2e.Run(t,
 			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
+			testSuite.provisionerOption(t),
 			e2e.WithStackName(stackName),
 		)
 	})
@@ -36,8 +35,8 @@ func TestUpgrade5Suite(t *testing.T) {
 func (s *upgrade5TestSuite) TestUpgrade5() {
 
 	// Installation
-	s.InstallAgent(5)
-	s.InstallAgent(7)
+	s.InstallAgent(InstallOptions{AgentMajorVersion: 5})
+	s.InstallAgent(InstallOptions{}.withCIRepository())
 
 	s.assertInstallScript(true)
 
@@ -10,7 +10,6 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 )
 
 type upgrade6TestSuite struct {
@@ -27,7 +26,7 @@ func TestUpgrade6Suite(t *testing.T) {
 		testSuite := &upgrade6TestSuite{}
 		e2e.Run(t,
 			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
+			testSuite.provisionerOption(t),
 			e2e.WithStackName(stackName),
 		)
 	})
@@ -35,8 +34,8 @@ func TestUpgrade6Suite(t *testing.T) {
 
 func (s *upgrade6TestSuite) TestUpgrade6() {
 	// Installation
-	s.InstallAgent(6)
-	s.InstallAgent(7)
+	s.InstallAgent(InstallOptions{AgentMajorVersion: 6})
+	s.InstallAgent(InstallOptions{}.withCIRepository())
 
 	s.assertInstallScript(true)
 
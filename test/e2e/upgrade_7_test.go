@@ -10,7 +10,6 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/e2e"
-	awshost "github.com/DataDog/datadog-agent/test/new-e2e/pkg/provisioners/aws/host"
 )
 
 type upgrade7TestSuite struct {
@@ -24,7 +23,7 @@ func TestUpgrade7Suite(t *testing.T) {
 		testSuite := &upgrade7TestSuite{}
 		e2e.Run(t,
 			testSuite,
-			e2e.WithProvisioner(awshost.ProvisionerNoAgentNoFakeIntake(awshost.WithEC2InstanceOptions(getEC2Options(t)...))),
+			testSuite.provisionerOption(t),
 			e2e.WithStackName(stackName),
 		)
 	})
@@ -32,8 +31,8 @@ func TestUpgrade7Suite(t *testing.T) {
 
 func (s *upgrade7TestSuite) TestUpgrade7() {
 	// Installation
-	s.InstallAgent(7, "DD_AGENT_MINOR_VERSION=42.0", "Install Old Agent 7 version : 7.42.0")
-	s.InstallAgent(7)
+	s.InstallAgent(InstallOptions{Description: "Install Old Agent 7 version : 7.42.0", AgentMinorVersion: "42.0"})
+	s.InstallAgent(InstallOptions{}.withCIRepository())
 
 	s.assertInstallScript(true)
 